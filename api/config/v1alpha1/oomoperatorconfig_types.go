@@ -0,0 +1,129 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+// BudgetConfig limits how much disruption the operator causes at once.
+type BudgetConfig struct {
+	// MaxInjecting is the most Oomers which inject at the same time, further
+	// Oomers wait until another stops. There is no limit when this is zero.
+	MaxInjecting int32 `json:"maxInjecting,omitempty"`
+
+	// MaxReplicas caps the replicas created by an Oomer in the Deployment mode,
+	// including those set by a pattern. There is no limit when this is zero.
+	MaxReplicas int32 `json:"maxReplicas,omitempty"`
+}
+
+// WebhooksConfig enables the webhooks served by the operator, these are all
+// disabled when the ENABLE_WEBHOOKS environment variable is "false".
+type WebhooksConfig struct {
+	// Conversion serves the conversion webhook between Oomer versions, defaults
+	// to true.
+	Conversion *bool `json:"conversion,omitempty"`
+
+	// PodInjector serves the webhook which injects allocator sidecars in the
+	// Sidecar mode, defaults to true.
+	PodInjector *bool `json:"podInjector,omitempty"`
+}
+
+// ClientConfig limits the requests which the operator makes to the API server.
+type ClientConfig struct {
+	// QPS is the number of requests which may be made each second, defaults to 20.
+	QPS int32 `json:"qps,omitempty"`
+
+	// Burst is the number of requests which may be made at once above the QPS,
+	// defaults to 30.
+	Burst int32 `json:"burst,omitempty"`
+}
+
+// RateLimiterConfig limits how quickly Oomers are queued for reconciling. The
+// exponential backoff applies to each failing Oomer and the overall rate limit to
+// failures and rate limited requeues, neither limits the requests to the API
+// server which are configured by ClientConfig.
+type RateLimiterConfig struct {
+	// BaseDelay is the delay before a failed reconcile is retried, this doubles
+	// with each consecutive failure. Defaults to 5ms.
+	BaseDelay *metav1.Duration `json:"baseDelay,omitempty"`
+
+	// MaxDelay is the longest delay before a failed reconcile is retried,
+	// defaults to 1000s.
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+
+	// QPS is the number of failed or rate limited Oomers which may be requeued
+	// each second, defaults to 10.
+	QPS int32 `json:"qps,omitempty"`
+
+	// Burst is the number of failed or rate limited Oomers which may be requeued
+	// at once above the QPS, defaults to 100.
+	Burst int32 `json:"burst,omitempty"`
+}
+
+// OomerControllerConfig tunes the controller which reconciles Oomers.
+type OomerControllerConfig struct {
+	// MaxConcurrentReconciles is the number of Oomers which are reconciled in
+	// parallel. When unset, controller.groupKindConcurrency is used, which
+	// defaults to 1.
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// RateLimiter limits how quickly Oomers are reconciled.
+	RateLimiter RateLimiterConfig `json:"rateLimiter,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OomOperatorConfig is the Schema for the configuration file of the operator.
+// The embedded manager configuration, Client, Namespaces, Webhooks and
+// OomerController are read on start, the remaining fields are reloaded when the
+// file changes.
+type OomOperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ControllerManagerConfigurationSpec configures the manager, such as leader
+	// election, the webhook server and the concurrency of each controller.
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+
+	// DefaultImage is the allocator image used by Oomers which do not specify one.
+	DefaultImage string `json:"defaultImage,omitempty"`
+
+	// RequeueInterval is how often an Oomer is reconciled when nothing else
+	// triggers a reconcile, defaults to 5m.
+	RequeueInterval *metav1.Duration `json:"requeueInterval,omitempty"`
+
+	// Client limits the requests which the operator makes to the API server.
+	Client ClientConfig `json:"client,omitempty"`
+
+	// Namespaces limits the operator to watching the given namespaces, every
+	// namespace is watched when this is empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Budget limits how much disruption the operator causes at once.
+	Budget BudgetConfig `json:"budget,omitempty"`
+
+	// Webhooks enables the webhooks served by the operator.
+	Webhooks WebhooksConfig `json:"webhooks,omitempty"`
+
+	// OomerController tunes the controller which reconciles Oomers.
+	OomerController OomerControllerConfig `json:"oomerController,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OomOperatorConfig{})
+}
@@ -0,0 +1,310 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// conversionDataAnnotation holds the fields which cannot be represented in the
+// version an Oomer has been converted to, so that they are restored when it is
+// converted back. It is removed once those fields have been restored.
+const conversionDataAnnotation = "oomer.jdocklabs.co.uk/conversion-data"
+
+// conversionData contains the fields which are lost when converting between versions.
+type conversionData struct {
+	// Fields from v1beta1 which do not exist in v1alpha1.
+	Selector          *metav1.LabelSelector        `json:"selector,omitempty"`
+	MultiContainer    *v1beta1.MultiContainerSpec  `json:"multiContainer,omitempty"`
+	Profile           *v1beta1.ProfileSpec         `json:"profile,omitempty"`
+	Squeeze           *v1beta1.SqueezeSpec         `json:"squeeze,omitempty"`
+	Schedule          *v1beta1.ScheduleSpec        `json:"schedule,omitempty"`
+	Paused            bool                         `json:"paused,omitempty"`
+	Verify            *v1beta1.VerifySpec          `json:"verify,omitempty"`
+	Notify            *v1beta1.NotifySpec          `json:"notify,omitempty"`
+	AbortWhen         []v1beta1.AbortCriterion     `json:"abortWhen,omitempty"`
+	Conditions        []metav1.Condition           `json:"conditions,omitempty"`
+	Notifications     []v1beta1.NotificationRecord `json:"notifications,omitempty"`
+	Phase             v1beta1.OomerPhase           `json:"phase,omitempty"`
+	History           []v1beta1.PhaseTransition    `json:"history,omitempty"`
+	SqueezeStatus     *v1beta1.SqueezeStatus       `json:"squeezeStatus,omitempty"`
+	ContainerOOMKills []v1beta1.ContainerOOMKills  `json:"containerOOMKills,omitempty"`
+	MemoryRequest     *resource.Quantity           `json:"memoryRequest,omitempty"`
+
+	// Mode is a v1beta1 mode which v1alpha1 does not have, v1alpha1 is given
+	// the closest mode which it does have instead.
+	Mode v1beta1.OomerMode `json:"mode,omitempty"`
+
+	// Pointers in v1alpha1 which are values in v1beta1, these record when the
+	// pointer differs from what would be assumed from the value.
+	EmptyImage           bool `json:"emptyImage,omitempty"`
+	NilReplicas          bool `json:"nilReplicas,omitempty"`
+	ZeroObservedReplicas bool `json:"zeroObservedReplicas,omitempty"`
+}
+
+// ConvertTo converts this Oomer to the Hub version (v1beta1).
+func (src *Oomer) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.Oomer)
+
+	dst.ObjectMeta = src.ObjectMeta
+	data, err := popConversionData(&dst.ObjectMeta)
+	if err != nil {
+		return err
+	}
+
+	if src.Spec.Image != nil {
+		dst.Spec.Image = *src.Spec.Image
+	}
+	if src.Spec.Replicas != nil {
+		dst.Spec.Replicas = *src.Spec.Replicas
+	}
+	if src.Spec.Labels != nil || data.Selector != nil {
+		dst.Spec.Selector = &metav1.LabelSelector{}
+		if data.Selector != nil {
+			dst.Spec.Selector = data.Selector
+		}
+		dst.Spec.Selector.MatchLabels = src.Spec.Labels
+	}
+	dst.Spec.Mode = v1beta1.OomerMode(src.Spec.Mode)
+	if data.Mode != "" && src.Spec.Mode == closestMode(data.Mode) {
+		dst.Spec.Mode = data.Mode
+	}
+	dst.Spec.Timing = (*v1beta1.TimingSpec)(src.Spec.Timing)
+	dst.Spec.MultiContainer = data.MultiContainer
+	dst.Spec.Profile = data.Profile
+	dst.Spec.Squeeze = data.Squeeze
+	dst.Spec.Schedule = data.Schedule
+	dst.Spec.Paused = data.Paused
+	dst.Spec.Verify = data.Verify
+	dst.Spec.Notify = data.Notify
+	dst.Spec.AbortWhen = data.AbortWhen
+
+	if p := src.Spec.Pattern; p != nil {
+		dst.Spec.Pattern = &v1beta1.PatternSpec{
+			Type:          v1beta1.PatternType(p.Type),
+			From:          p.From,
+			To:            p.To,
+			Duration:      p.Duration,
+			Steps:         p.Steps,
+			Period:        p.Period,
+			BurstDuration: p.BurstDuration,
+		}
+	}
+
+	if np := src.Spec.NodePressure; np != nil {
+		dst.Spec.NodePressure = &v1beta1.NodePressureSpec{
+			NodeSelector:       np.NodeSelector,
+			NodeNames:          np.NodeNames,
+			AllocatablePercent: np.AllocatablePercent,
+			PriorityClassName:  np.PriorityClassName,
+			MemoryRequest:      data.MemoryRequest,
+		}
+	}
+
+	if t := src.Spec.Target; t != nil {
+		dst.Spec.Target = &v1beta1.TargetSpec{
+			Deployment:        t.Deployment,
+			Selector:          t.Selector,
+			ContainerName:     t.ContainerName,
+			ContainerSelector: (*v1beta1.ContainerSelector)(t.ContainerSelector),
+			MemoryLimit:       t.MemoryLimit,
+		}
+	}
+
+	if src.Status.ObservedReplicas != nil {
+		dst.Status.ObservedReplicas = *src.Status.ObservedReplicas
+	}
+	dst.Status.StartTime = src.Status.StartTime
+	dst.Status.Conditions = data.Conditions
+	dst.Status.Notifications = data.Notifications
+	dst.Status.Phase = data.Phase
+	dst.Status.History = data.History
+	dst.Status.Squeeze = data.SqueezeStatus
+	dst.Status.ContainerOOMKills = data.ContainerOOMKills
+
+	// Record the pointers which cannot be recovered from the values.
+	return pushConversionData(&dst.ObjectMeta, &conversionData{
+		EmptyImage:           src.Spec.Image != nil && *src.Spec.Image == "",
+		NilReplicas:          src.Spec.Replicas == nil,
+		ZeroObservedReplicas: src.Status.ObservedReplicas != nil && *src.Status.ObservedReplicas == 0,
+	})
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *Oomer) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.Oomer)
+
+	dst.ObjectMeta = src.ObjectMeta
+	data, err := popConversionData(&dst.ObjectMeta)
+	if err != nil {
+		return err
+	}
+
+	if src.Spec.Image != "" || data.EmptyImage {
+		image := src.Spec.Image
+		dst.Spec.Image = &image
+	}
+	if src.Spec.Replicas != 0 || !data.NilReplicas {
+		replicas := src.Spec.Replicas
+		dst.Spec.Replicas = &replicas
+	}
+	dst.Spec.Mode = closestMode(src.Spec.Mode)
+	dst.Spec.Timing = (*TimingSpec)(src.Spec.Timing)
+
+	if p := src.Spec.Pattern; p != nil {
+		dst.Spec.Pattern = &PatternSpec{
+			Type:          PatternType(p.Type),
+			From:          p.From,
+			To:            p.To,
+			Duration:      p.Duration,
+			Steps:         p.Steps,
+			Period:        p.Period,
+			BurstDuration: p.BurstDuration,
+		}
+	}
+
+	if np := src.Spec.NodePressure; np != nil {
+		dst.Spec.NodePressure = &NodePressureSpec{
+			NodeSelector:       np.NodeSelector,
+			NodeNames:          np.NodeNames,
+			AllocatablePercent: np.AllocatablePercent,
+			PriorityClassName:  np.PriorityClassName,
+		}
+	}
+
+	if t := src.Spec.Target; t != nil {
+		dst.Spec.Target = &TargetSpec{
+			Deployment:        t.Deployment,
+			Selector:          t.Selector,
+			ContainerName:     t.ContainerName,
+			ContainerSelector: (*ContainerSelector)(t.ContainerSelector),
+			MemoryLimit:       t.MemoryLimit,
+		}
+	}
+
+	if src.Status.ObservedReplicas != 0 || data.ZeroObservedReplicas {
+		observed := src.Status.ObservedReplicas
+		dst.Status.ObservedReplicas = &observed
+	}
+	dst.Status.StartTime = src.Status.StartTime
+
+	// v1alpha1 only has labels, anything else in the selector is preserved.
+	lost := &conversionData{
+		MultiContainer:    src.Spec.MultiContainer,
+		Profile:           src.Spec.Profile,
+		Squeeze:           src.Spec.Squeeze,
+		Schedule:          src.Spec.Schedule,
+		Paused:            src.Spec.Paused,
+		Verify:            src.Spec.Verify,
+		Notify:            src.Spec.Notify,
+		AbortWhen:         src.Spec.AbortWhen,
+		Conditions:        src.Status.Conditions,
+		Notifications:     src.Status.Notifications,
+		Phase:             src.Status.Phase,
+		History:           src.Status.History,
+		SqueezeStatus:     src.Status.Squeeze,
+		ContainerOOMKills: src.Status.ContainerOOMKills,
+	}
+	if dst.Spec.Mode != OomerMode(src.Spec.Mode) {
+		lost.Mode = src.Spec.Mode
+	}
+	if np := src.Spec.NodePressure; np != nil {
+		lost.MemoryRequest = np.MemoryRequest
+	}
+	if s := src.Spec.Selector; s != nil {
+		dst.Spec.Labels = s.MatchLabels
+		if s.MatchLabels == nil || len(s.MatchExpressions) > 0 {
+			lost.Selector = &metav1.LabelSelector{MatchExpressions: s.MatchExpressions}
+		}
+	}
+
+	return pushConversionData(&dst.ObjectMeta, lost)
+}
+
+// closestMode returns the v1alpha1 mode for a v1beta1 mode. Modes which v1alpha1
+// does not have are given the mode which behaves most like them, so that the
+// object is valid in v1alpha1.
+func closestMode(mode v1beta1.OomerMode) OomerMode {
+	switch mode {
+	case "", v1beta1.DeploymentMode, v1beta1.NodePressureMode, v1beta1.TargetMode, v1beta1.EphemeralMode, v1beta1.SidecarMode:
+		return OomerMode(mode)
+	case v1beta1.SqueezeMode:
+		return TargetMode
+	default:
+		return DeploymentMode
+	}
+}
+
+// popConversionData removes the conversion data annotation from an object, returning
+// its contents. An empty conversionData is returned when the annotation is not set.
+func popConversionData(meta *metav1.ObjectMeta) (*conversionData, error) {
+	data := &conversionData{}
+
+	raw, ok := meta.Annotations[conversionDataAnnotation]
+	if !ok {
+		return data, nil
+	}
+
+	if err := json.Unmarshal([]byte(raw), data); err != nil {
+		return nil, err
+	}
+
+	// The annotations are shared with the object being converted, so they are
+	// copied rather than modified in place.
+	annotations := make(map[string]string, len(meta.Annotations)-1)
+	for k, v := range meta.Annotations {
+		if k != conversionDataAnnotation {
+			annotations[k] = v
+		}
+	}
+	meta.Annotations = annotations
+	if len(annotations) == 0 {
+		meta.Annotations = nil
+	}
+
+	return data, nil
+}
+
+// pushConversionData sets the conversion data annotation on an object, this is
+// skipped when there is nothing to preserve.
+func pushConversionData(meta *metav1.ObjectMeta, data *conversionData) error {
+	if data.Selector == nil && data.MultiContainer == nil && data.Profile == nil && data.Squeeze == nil && data.Schedule == nil && !data.Paused && data.Verify == nil && data.Notify == nil &&
+		len(data.AbortWhen) == 0 && len(data.Conditions) == 0 && len(data.Notifications) == 0 && data.Phase == "" && len(data.History) == 0 && data.SqueezeStatus == nil && len(data.ContainerOOMKills) == 0 && data.MemoryRequest == nil && data.Mode == "" &&
+		!data.EmptyImage && !data.NilReplicas && !data.ZeroObservedReplicas {
+		return nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	annotations := make(map[string]string, len(meta.Annotations)+1)
+	for k, v := range meta.Annotations {
+		annotations[k] = v
+	}
+	annotations[conversionDataAnnotation] = string(raw)
+	meta.Annotations = annotations
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package v1alpha1
+
+import (
+	"math/rand"
+	"os"
+
+	fuzz "github.com/google/gofuzz"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/yaml"
+
+	"github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// modeEnum returns the modes which the Oomer CRD allows in a version.
+func modeEnum(version string) []string {
+	raw, err := os.ReadFile("../../config/crd/bases/jdocklabs.co.uk_oomers.yaml")
+	Expect(err).NotTo(HaveOccurred())
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	Expect(yaml.Unmarshal(raw, crd)).To(Succeed())
+
+	for _, v := range crd.Spec.Versions {
+		if v.Name != version {
+			continue
+		}
+
+		var modes []string
+		for _, e := range v.Schema.OpenAPIV3Schema.Properties["spec"].Properties["mode"].Enum {
+			var mode string
+			Expect(yaml.Unmarshal(e.Raw, &mode)).To(Succeed())
+			modes = append(modes, mode)
+		}
+		return modes
+	}
+
+	Fail("the CRD has no version " + version)
+	return nil
+}
+
+var _ = Describe("Oomer conversion", func() {
+
+	const iterations = 1000
+
+	alphaModes := modeEnum("v1alpha1")
+	betaModes := modeEnum("v1beta1")
+
+	var f *fuzz.Fuzzer
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(AddToScheme(scheme)).To(Succeed())
+		Expect(v1beta1.AddToScheme(scheme)).To(Succeed())
+
+		funcs := fuzzer.MergeFuzzerFuncs(metafuzzer.Funcs, func(serializer.CodecFactory) []interface{} {
+			return []interface{}{
+				func(q *resource.Quantity, c fuzz.Continue) {
+					*q = *resource.NewQuantity(c.Int63n(1<<40), resource.BinarySI)
+				},
+				// Modes are limited to those which the CRD of each version allows.
+				func(m *OomerMode, c fuzz.Continue) {
+					*m = OomerMode(alphaModes[c.Intn(len(alphaModes))])
+				},
+				func(m *v1beta1.OomerMode, c fuzz.Continue) {
+					*m = v1beta1.OomerMode(betaModes[c.Intn(len(betaModes))])
+				},
+			}
+		})
+		f = fuzzer.FuzzerFor(funcs, rand.NewSource(GinkgoRandomSeed()), serializer.NewCodecFactory(scheme))
+	})
+
+	It("Should round-trip v1alpha1 through the hub", func() {
+		for i := 0; i < iterations; i++ {
+			original := &Oomer{}
+			f.Fuzz(original)
+
+			hub := &v1beta1.Oomer{}
+			Expect(original.DeepCopy().ConvertTo(hub)).To(Succeed())
+
+			converted := &Oomer{}
+			Expect(converted.ConvertFrom(hub)).To(Succeed())
+
+			Expect(apiequality.Semantic.DeepEqual(original, converted)).To(BeTrue(), "%#v\n%#v", original, converted)
+		}
+	})
+
+	It("Should round-trip the hub through v1alpha1", func() {
+		for i := 0; i < iterations; i++ {
+			original := &v1beta1.Oomer{}
+			f.Fuzz(original)
+
+			spoke := &Oomer{}
+			Expect(spoke.ConvertFrom(original.DeepCopy())).To(Succeed())
+
+			converted := &v1beta1.Oomer{}
+			Expect(spoke.ConvertTo(converted)).To(Succeed())
+
+			Expect(apiequality.Semantic.DeepEqual(original, converted)).To(BeTrue(), "%#v\n%#v", original, converted)
+		}
+	})
+
+	It("Should convert every v1beta1 mode to one which v1alpha1 allows", func() {
+		Expect(alphaModes).NotTo(BeEmpty())
+		Expect(betaModes).To(ContainElement(string(v1beta1.SqueezeMode)))
+
+		for _, mode := range betaModes {
+			hub := &v1beta1.Oomer{Spec: v1beta1.OomerSpec{Mode: v1beta1.OomerMode(mode)}}
+
+			spoke := &Oomer{}
+			Expect(spoke.ConvertFrom(hub.DeepCopy())).To(Succeed())
+			Expect(alphaModes).To(ContainElement(string(spoke.Spec.Mode)), "mode %s", mode)
+
+			converted := &v1beta1.Oomer{}
+			Expect(spoke.ConvertTo(converted)).To(Succeed())
+			Expect(converted.Spec.Mode).To(Equal(hub.Spec.Mode))
+		}
+	})
+
+	It("Should keep a mode which is changed in v1alpha1", func() {
+		hub := &v1beta1.Oomer{Spec: v1beta1.OomerSpec{Mode: v1beta1.SqueezeMode}}
+
+		spoke := &Oomer{}
+		Expect(spoke.ConvertFrom(hub)).To(Succeed())
+		Expect(spoke.Spec.Mode).To(Equal(TargetMode))
+		spoke.Spec.Mode = EphemeralMode
+
+		converted := &v1beta1.Oomer{}
+		Expect(spoke.ConvertTo(converted)).To(Succeed())
+		Expect(converted.Spec.Mode).To(Equal(v1beta1.EphemeralMode))
+	})
+
+	It("Should not annotate an object which converts without loss", func() {
+		replicas := int32(3)
+		o := &Oomer{Spec: OomerSpec{Replicas: &replicas, Labels: map[string]string{"app": "oomer"}}}
+
+		hub := &v1beta1.Oomer{}
+		Expect(o.ConvertTo(hub)).To(Succeed())
+		Expect(hub.ObjectMeta.Annotations).Should(BeEmpty())
+		Expect(hub.Spec.Replicas).Should(Equal(replicas))
+		Expect(hub.Spec.Selector.MatchLabels).Should(Equal(o.Spec.Labels))
+	})
+})
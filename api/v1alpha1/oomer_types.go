@@ -17,12 +17,197 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// InjectLabel opts a pod into having an allocator sidecar injected at creation
+// time, when set to "true", by an Oomer in the Sidecar mode. Only pods with this
+// label are sent to the pod webhook.
+const InjectLabel = "oomer.jdocklabs.co.uk/inject"
+
+// OomerMode determines how OOM conditions are injected into the cluster.
+// +kubebuilder:validation:Enum=Deployment;NodePressure;Target;Ephemeral;Sidecar
+type OomerMode string
+
+const (
+	// DeploymentMode places a number of OOMKilled pods into the cluster
+	// through a Deployment object.
+	DeploymentMode OomerMode = "Deployment"
+
+	// NodePressureMode places allocators onto selected nodes through a DaemonSet,
+	// consuming node allocatable memory until the kubelet reports MemoryPressure
+	// and begins evicting pods.
+	NodePressureMode OomerMode = "NodePressure"
+
+	// TargetMode drives the containers of an existing workload to OOM by lowering
+	// their memory limit, the original limits are restored when the Oomer is deleted.
+	TargetMode OomerMode = "Target"
+
+	// EphemeralMode attaches an ephemeral container running the allocator to
+	// the running pods of an existing workload, sharing the pod cgroup. The owning
+	// workload is not modified, so no rollout is triggered.
+	EphemeralMode OomerMode = "Ephemeral"
+
+	// SidecarMode injects an allocator sidecar into pods, matching the target
+	// selector, which are created with the InjectLabel. Existing workloads
+	// are not modified by the operator.
+	SidecarMode OomerMode = "Sidecar"
+)
+
+// NodePressureSpec configures the allocators used in the NodePressure mode.
+type NodePressureSpec struct {
+	// NodeSelector selects the nodes which allocators are placed onto.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// NodeNames explicitly lists the nodes which allocators are placed onto.
+	// When used alongside NodeSelector, a node must satisfy both.
+	NodeNames []string `json:"nodeNames,omitempty"`
+
+	// AllocatablePercent is the percentage of node allocatable memory which each
+	// allocator will consume. When nodes differ in size, the smallest selected
+	// node is used to calculate the amount.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=95
+	// +optional
+	AllocatablePercent int32 `json:"allocatablePercent,omitempty"`
+
+	// PriorityClassName is set on the allocator pods, this can be used to alter
+	// where the allocators fall within the kubelet eviction ordering.
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
+
+// ContainerSelector selects containers within a pod by name or image, a container
+// is selected when it matches any of the provided fields.
+type ContainerSelector struct {
+	// Names lists the containers to select.
+	Names []string `json:"names,omitempty"`
+
+	// NamePattern is a shell pattern, such as "istio-*", matched against container names.
+	NamePattern string `json:"namePattern,omitempty"`
+
+	// ImagePattern is a shell pattern, such as "*/fluent/fluent-bit:*", matched against
+	// container images. As with file paths, "*" does not match "/".
+	ImagePattern string `json:"imagePattern,omitempty"`
+}
+
+// TargetSpec references the existing workload, and its containers, which are
+// driven to OOM in the Target, Ephemeral and Sidecar modes.
+type TargetSpec struct {
+	// Deployment is the name of an existing Deployment in the same namespace as
+	// the Oomer, this is required in the Target mode.
+	Deployment string `json:"deployment,omitempty"`
+
+	// Selector selects pods in the same namespace as the Oomer, this can be used
+	// instead of Deployment in the Ephemeral mode and is required in the Sidecar mode.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// ContainerName is the single container to target. When neither this nor
+	// ContainerSelector are provided, the default container is targeted, this is
+	// the container named by the kubectl.kubernetes.io/default-container annotation
+	// or otherwise the first container.
+	ContainerName string `json:"containerName,omitempty"`
+
+	// ContainerSelector targets every container which it matches. In the Ephemeral
+	// mode, the first matching container is used as the target of the ephemeral container.
+	ContainerSelector *ContainerSelector `json:"containerSelector,omitempty"`
+
+	// MemoryLimit is applied to the targeted containers in the Target mode, this
+	// should be lower than their usage for an OOM to occur. In the Sidecar mode,
+	// it is the memory limit of the injected sidecar.
+	MemoryLimit *resource.Quantity `json:"memoryLimit,omitempty"`
+}
+
+// PatternType is the shape of a pattern used to vary replicas over time.
+// +kubebuilder:validation:Enum=constant;linearRamp;step;sine;burst
+type PatternType string
+
+const (
+	// ConstantPattern keeps the configured number of replicas.
+	ConstantPattern PatternType = "constant"
+
+	// LinearRampPattern increases, or decreases, replicas by one at a time
+	// from From to To over Duration.
+	LinearRampPattern PatternType = "linearRamp"
+
+	// StepPattern moves replicas from From to To over Duration in a number of
+	// evenly sized Steps.
+	StepPattern PatternType = "step"
+
+	// SinePattern oscillates replicas between From and To every Period, sampled
+	// Steps times per Period.
+	SinePattern PatternType = "sine"
+
+	// BurstPattern runs To replicas for BurstDuration at the start of every Period,
+	// falling back to From replicas for the remainder.
+	BurstPattern PatternType = "burst"
+)
+
+// PatternSpec describes how the number of replicas varies from the time the
+// Oomer started.
+type PatternSpec struct {
+	// Type is the shape of the pattern.
+	Type PatternType `json:"type"`
+
+	// From is the number of replicas at the start of a linearRamp or step, the
+	// trough of a sine and the replicas between bursts.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	From int32 `json:"from,omitempty"`
+
+	// To is the number of replicas at the end of a linearRamp or step, the peak
+	// of a sine and the replicas during a burst.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	To int32 `json:"to,omitempty"`
+
+	// Duration is the time taken for a linearRamp or step to reach To.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Steps is the number of steps taken in a step pattern, or the number of
+	// samples taken per Period in a sine pattern, defaults to 1 and 8 respectively.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Steps int32 `json:"steps,omitempty"`
+
+	// Period is the length of a single sine wave or burst cycle.
+	Period *metav1.Duration `json:"period,omitempty"`
+
+	// BurstDuration is how long each burst lasts, this must be shorter than Period.
+	BurstDuration *metav1.Duration `json:"burstDuration,omitempty"`
+}
+
+// TimingSpec randomises when each allocator triggers an OOM, so that pods do not
+// crash in lockstep.
+type TimingSpec struct {
+	// MinDelay is the minimum time an allocator waits before it begins allocating.
+	MinDelay *metav1.Duration `json:"minDelay,omitempty"`
+
+	// MaxDelay is the maximum time an allocator waits before it begins allocating,
+	// the delay is chosen uniformly between MinDelay and MaxDelay.
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+
+	// Interval is how often an allocator decides whether to OOM once the delay
+	// has passed, defaults to allocating immediately after the delay.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// ProbabilityPercent is the chance of an allocator triggering an OOM on each
+	// Interval, defaults to 100.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	ProbabilityPercent *int32 `json:"probabilityPercent,omitempty"`
+
+	// Seed is used to seed the random number generator of each allocator, this
+	// is combined with the pod name so that pods do not share the same sequence.
+	// When unset, allocators are seeded randomly.
+	Seed *int64 `json:"seed,omitempty"`
+}
+
 // OomerSpec defines the desired state of Oomer
 type OomerSpec struct {
 	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
@@ -37,6 +222,28 @@ type OomerSpec struct {
 
 	// Labels are passed directly to the oomer application.
 	Labels map[string]string `json:"labels,omitempty"`
+
+	// Mode is how OOM conditions are injected, defaults to Deployment.
+	// +kubebuilder:default=Deployment
+	// +optional
+	Mode OomerMode `json:"mode,omitempty"`
+
+	// NodePressure configures the allocators when using the NodePressure mode,
+	// Replicas is ignored in this mode as a single allocator runs on each selected node.
+	NodePressure *NodePressureSpec `json:"nodePressure,omitempty"`
+
+	// Pattern varies the number of replicas over time, rather than using the
+	// static Replicas value. This does not apply in the NodePressure mode.
+	Pattern *PatternSpec `json:"pattern,omitempty"`
+
+	// Timing randomises when each allocator triggers an OOM, when unset allocators
+	// OOM immediately.
+	Timing *TimingSpec `json:"timing,omitempty"`
+
+	// Target references the existing workload used in the Target, Ephemeral and Sidecar modes.
+	// In the Ephemeral mode, a non-zero Replicas limits the number of pods which
+	// have an allocator attached.
+	Target *TargetSpec `json:"target,omitempty"`
 }
 
 // OomerStatus defines the observed state of Oomer
@@ -47,6 +254,10 @@ type OomerStatus struct {
 	// ObservedReplicas are number of observed OOMKilled pods, this should
 	// match the number of configured replicas.
 	ObservedReplicas *int32 `json:"observedReplicas,omitempty"`
+
+	// StartTime is when the Oomer was first reconciled, patterns are calculated
+	// from this point.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,770 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InjectLabel opts a pod into having an allocator sidecar injected at creation
+// time, when set to "true", by an Oomer in the Sidecar mode. Only pods with this
+// label are sent to the pod webhook.
+const InjectLabel = "oomer.jdocklabs.co.uk/inject"
+
+// OomerMode determines how OOM conditions are injected into the cluster.
+// +kubebuilder:validation:Enum=Deployment;MultiContainer;NodePressure;Target;Squeeze;Ephemeral;Sidecar
+type OomerMode string
+
+const (
+	// DeploymentMode places a number of OOMKilled pods into the cluster
+	// through a Deployment object.
+	DeploymentMode OomerMode = "Deployment"
+
+	// MultiContainerMode places pods with several allocator containers, which
+	// together allocate more than the memory limit of the pod, through a Deployment.
+	// The OOM kills are attributed to each container in the status.
+	MultiContainerMode OomerMode = "MultiContainer"
+
+	// NodePressureMode places allocators onto selected nodes through a DaemonSet,
+	// consuming node allocatable memory until the kubelet reports MemoryPressure
+	// and begins evicting pods.
+	NodePressureMode OomerMode = "NodePressure"
+
+	// TargetMode drives the containers of an existing workload to OOM by lowering
+	// their memory limit, the original limits are restored when the Oomer is deleted.
+	TargetMode OomerMode = "Target"
+
+	// SqueezeMode drives the containers of an existing workload to OOM by lowering
+	// their memory limit in steps until they are OOMKilled, the original limits are
+	// restored when the Oomer completes or is deleted.
+	SqueezeMode OomerMode = "Squeeze"
+
+	// EphemeralMode attaches an ephemeral container running the allocator to
+	// the running pods of an existing workload, sharing the pod cgroup. The owning
+	// workload is not modified, so no rollout is triggered.
+	EphemeralMode OomerMode = "Ephemeral"
+
+	// SidecarMode injects an allocator sidecar into pods, matching the target
+	// selector, which are created with the InjectLabel. Existing workloads
+	// are not modified by the operator.
+	SidecarMode OomerMode = "Sidecar"
+)
+
+// NodePressureSpec configures the allocators used in the NodePressure mode.
+type NodePressureSpec struct {
+	// NodeSelector selects the nodes which allocators are placed onto.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// NodeNames explicitly lists the nodes which allocators are placed onto.
+	// When used alongside NodeSelector, a node must satisfy both.
+	NodeNames []string `json:"nodeNames,omitempty"`
+
+	// AllocatablePercent is the percentage of node allocatable memory which each
+	// allocator will consume. When nodes differ in size, the smallest selected
+	// node is used to calculate the amount.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=95
+	// +optional
+	AllocatablePercent int32 `json:"allocatablePercent,omitempty"`
+
+	// PriorityClassName is set on the allocator pods, this can be used to alter
+	// where the allocators fall within the kubelet eviction ordering.
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// MemoryRequest is the memory request of each allocator. By default none is
+	// set, so the allocators are BestEffort pods and the first to be evicted under
+	// MemoryPressure. With a request they are Burstable, a request above the
+	// memory which is free on a node stops the allocator being scheduled there.
+	// +optional
+	MemoryRequest *resource.Quantity `json:"memoryRequest,omitempty"`
+}
+
+// AllocatorContainer is one of the allocators within each pod in the MultiContainer mode.
+type AllocatorContainer struct {
+	// Name of the container, this must be unique within the pod.
+	Name string `json:"name"`
+
+	// Weight is the share of the allocation of the pod which this container makes.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	Weight int32 `json:"weight,omitempty"`
+}
+
+// MultiContainerSpec configures the pods placed in the MultiContainer mode.
+type MultiContainerSpec struct {
+	// MemoryLimit is the memory limit of each pod, set through pod-level resources
+	// which require Kubernetes 1.34, or the PodLevelResources feature gate. Each
+	// container is also limited to this, so a container is only OOMKilled alone
+	// when its own allocation exceeds it, otherwise the pod is OOMKilled once the
+	// combined allocation of its containers does.
+	MemoryLimit resource.Quantity `json:"memoryLimit"`
+
+	// OvercommitPercent is the amount which the containers of a pod allocate in
+	// total, as a percentage of its memory limit. This is divided between the
+	// containers in proportion to their Weight.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=150
+	// +optional
+	OvercommitPercent int32 `json:"overcommitPercent,omitempty"`
+
+	// Containers are the allocators placed in each pod.
+	// +kubebuilder:validation:MinItems=2
+	Containers []AllocatorContainer `json:"containers"`
+}
+
+// ContainerSelector selects containers within a pod by name or image, a container
+// is selected when it matches any of the provided fields.
+type ContainerSelector struct {
+	// Names lists the containers to select.
+	Names []string `json:"names,omitempty"`
+
+	// NamePattern is a shell pattern, such as "istio-*", matched against container names.
+	NamePattern string `json:"namePattern,omitempty"`
+
+	// ImagePattern is a shell pattern, such as "*/fluent/fluent-bit:*", matched against
+	// container images. As with file paths, "*" does not match "/".
+	ImagePattern string `json:"imagePattern,omitempty"`
+}
+
+// TargetSpec references the existing workload, and its containers, which are
+// driven to OOM in the Target, Squeeze, Ephemeral and Sidecar modes.
+type TargetSpec struct {
+	// Deployment is the name of an existing Deployment in the same namespace as
+	// the Oomer, this is required in the Target and Squeeze modes.
+	Deployment string `json:"deployment,omitempty"`
+
+	// Selector selects pods in the same namespace as the Oomer, this can be used
+	// instead of Deployment in the Ephemeral mode and is required in the Sidecar mode.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// ContainerName is the single container to target. When neither this nor
+	// ContainerSelector are provided, the default container is targeted, this is
+	// the container named by the kubectl.kubernetes.io/default-container annotation
+	// or otherwise the first container.
+	ContainerName string `json:"containerName,omitempty"`
+
+	// ContainerSelector targets every container which it matches. In the Ephemeral
+	// mode, the first matching container is used as the target of the ephemeral container.
+	ContainerSelector *ContainerSelector `json:"containerSelector,omitempty"`
+
+	// MemoryLimit is applied to the targeted containers in the Target mode, this
+	// should be lower than their usage for an OOM to occur. In the Squeeze mode,
+	// it is the limit which squeezing starts from for containers without one. In
+	// the Sidecar mode, it is the memory limit of the injected sidecar.
+	MemoryLimit *resource.Quantity `json:"memoryLimit,omitempty"`
+}
+
+// SqueezeSpec configures how the memory limit of the targeted containers is
+// lowered in the Squeeze mode.
+type SqueezeSpec struct {
+	// Step is the amount which the memory limit is lowered by at each interval.
+	Step resource.Quantity `json:"step"`
+
+	// Floor is the lowest memory limit which is applied, the limit is held here
+	// once it has been reached.
+	Floor resource.Quantity `json:"floor"`
+
+	// Interval is the time between each step, defaults to 1m.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+}
+
+// PatternType is the shape of a pattern used to vary replicas over time.
+// +kubebuilder:validation:Enum=constant;linearRamp;step;sine;burst
+type PatternType string
+
+const (
+	// ConstantPattern keeps the configured number of replicas.
+	ConstantPattern PatternType = "constant"
+
+	// LinearRampPattern increases, or decreases, replicas by one at a time
+	// from From to To over Duration.
+	LinearRampPattern PatternType = "linearRamp"
+
+	// StepPattern moves replicas from From to To over Duration in a number of
+	// evenly sized Steps.
+	StepPattern PatternType = "step"
+
+	// SinePattern oscillates replicas between From and To every Period, sampled
+	// Steps times per Period.
+	SinePattern PatternType = "sine"
+
+	// BurstPattern runs To replicas for BurstDuration at the start of every Period,
+	// falling back to From replicas for the remainder.
+	BurstPattern PatternType = "burst"
+)
+
+// PatternSpec describes how the number of replicas varies from the time the
+// Oomer started.
+type PatternSpec struct {
+	// Type is the shape of the pattern.
+	Type PatternType `json:"type"`
+
+	// From is the number of replicas at the start of a linearRamp or step, the
+	// trough of a sine and the replicas between bursts.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	From int32 `json:"from,omitempty"`
+
+	// To is the number of replicas at the end of a linearRamp or step, the peak
+	// of a sine and the replicas during a burst.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	To int32 `json:"to,omitempty"`
+
+	// Duration is the time taken for a linearRamp or step to reach To.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// Steps is the number of steps taken in a step pattern, or the number of
+	// samples taken per Period in a sine pattern, defaults to 1 and 8 respectively.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Steps int32 `json:"steps,omitempty"`
+
+	// Period is the length of a single sine wave or burst cycle.
+	Period *metav1.Duration `json:"period,omitempty"`
+
+	// BurstDuration is how long each burst lasts, this must be shorter than Period.
+	BurstDuration *metav1.Duration `json:"burstDuration,omitempty"`
+}
+
+// TimingSpec randomises when each allocator triggers an OOM, so that pods do not
+// crash in lockstep.
+type TimingSpec struct {
+	// MinDelay is the minimum time an allocator waits before it begins allocating.
+	MinDelay *metav1.Duration `json:"minDelay,omitempty"`
+
+	// MaxDelay is the maximum time an allocator waits before it begins allocating,
+	// the delay is chosen uniformly between MinDelay and MaxDelay.
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+
+	// Interval is how often an allocator decides whether to OOM once the delay
+	// has passed, defaults to allocating immediately after the delay.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// ProbabilityPercent is the chance of an allocator triggering an OOM on each
+	// Interval, defaults to 100.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	ProbabilityPercent *int32 `json:"probabilityPercent,omitempty"`
+
+	// Seed is used to seed the random number generator of each allocator, this
+	// is combined with the pod name so that pods do not share the same sequence.
+	// When unset, allocators are seeded randomly.
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+// ProfileType is how an allocator consumes memory over time.
+// +kubebuilder:validation:Enum=instant;leak;sawtooth
+type ProfileType string
+
+const (
+	// InstantProfile allocates all of its memory at once, causing an immediate OOM.
+	InstantProfile ProfileType = "instant"
+
+	// LeakProfile allocates memory at a steady Rate until it is OOMKilled.
+	LeakProfile ProfileType = "leak"
+
+	// SawtoothProfile leaks memory at Rate and frees FreePercent of what it holds
+	// every FreeInterval, as a garbage collector would, so memory grows in a sawtooth.
+	SawtoothProfile ProfileType = "sawtooth"
+)
+
+// ProfileSpec describes how an allocator consumes memory, the leak and sawtooth
+// profiles expose the memory in use on /metrics so that slope based alerts can be tested.
+type ProfileSpec struct {
+	// Type is how memory is consumed, defaults to instant.
+	// +kubebuilder:default=instant
+	// +optional
+	Type ProfileType `json:"type,omitempty"`
+
+	// Rate is how much memory is leaked each second in the leak and sawtooth
+	// profiles, defaults to 1Mi.
+	// +optional
+	Rate *resource.Quantity `json:"rate,omitempty"`
+
+	// FreeInterval is how often memory is freed in the sawtooth profile, defaults to 1m.
+	// +optional
+	FreeInterval *metav1.Duration `json:"freeInterval,omitempty"`
+
+	// FreePercent is the percentage of the leaked memory which is freed every
+	// FreeInterval in the sawtooth profile, defaults to 50.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	FreePercent int32 `json:"freePercent,omitempty"`
+
+	// MetricsPort is the port which the allocator serves /metrics on, defaults to 8080.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	MetricsPort int32 `json:"metricsPort,omitempty"`
+}
+
+// PrometheusCheck passes when a Prometheus query returns a sample greater than a threshold.
+type PrometheusCheck struct {
+	// URL is the base URL of the Prometheus server, such as http://prometheus.monitoring:9090.
+	URL string `json:"url"`
+
+	// Query is the PromQL instant query to evaluate.
+	Query string `json:"query"`
+
+	// GreaterThan is the value which a sample must exceed for the check to pass,
+	// defaults to 0. This may be fractional, such as 0.95 or 950m.
+	// +optional
+	GreaterThan *resource.Quantity `json:"greaterThan,omitempty"`
+}
+
+// HTTPCheck passes when an HTTP endpoint responds with the expected status, such
+// as a receiver which reports whether it received an alert webhook.
+type HTTPCheck struct {
+	// URL is requested with a GET request.
+	URL string `json:"url"`
+
+	// ExpectedStatus is the status code which must be returned, defaults to 200.
+	// +kubebuilder:validation:Minimum=100
+	// +kubebuilder:validation:Maximum=599
+	// +optional
+	ExpectedStatus int32 `json:"expectedStatus,omitempty"`
+
+	// BodyContains must be contained in the response body, when provided.
+	BodyContains string `json:"bodyContains,omitempty"`
+}
+
+// EventCheck passes when a Kubernetes Event with the given reason appears in the
+// namespace of the Oomer after injection started.
+type EventCheck struct {
+	// Reason is the reason of the Event, such as OOMKilling or BackOff.
+	Reason string `json:"reason"`
+
+	// InvolvedObjectKind limits the check to Events about objects of the given kind.
+	InvolvedObjectKind string `json:"involvedObjectKind,omitempty"`
+}
+
+// VerifyCheck is a single check, exactly one of its checks should be provided.
+type VerifyCheck struct {
+	// Name identifies the check in the conditions of the Oomer.
+	Name string `json:"name"`
+
+	// Prometheus checks the result of a Prometheus query.
+	Prometheus *PrometheusCheck `json:"prometheus,omitempty"`
+
+	// HTTP checks the response of an HTTP endpoint.
+	HTTP *HTTPCheck `json:"http,omitempty"`
+
+	// Event checks that a Kubernetes Event appeared.
+	Event *EventCheck `json:"event,omitempty"`
+}
+
+// VerifySpec asserts that the signals expected from an OOM, such as alerts, fired
+// during a run.
+type VerifySpec struct {
+	// Within is how long after injection starts every check must have passed,
+	// defaults to 2m.
+	Within *metav1.Duration `json:"within,omitempty"`
+
+	// Checks must all pass for the Oomer to be verified.
+	// +kubebuilder:validation:MinItems=1
+	Checks []VerifyCheck `json:"checks"`
+}
+
+// NotifyEvent is a point in the run of an Oomer which notifications are sent for.
+// +kubebuilder:validation:Enum=Started;OOMKilled;Completed;Failed
+type NotifyEvent string
+
+const (
+	// StartedNotifyEvent is sent when injection starts.
+	StartedNotifyEvent NotifyEvent = "Started"
+
+	// OOMKilledNotifyEvent is sent when the first OOMKilled container is observed.
+	OOMKilledNotifyEvent NotifyEvent = "OOMKilled"
+
+	// CompletedNotifyEvent is sent when the scheduled duration has passed.
+	CompletedNotifyEvent NotifyEvent = "Completed"
+
+	// FailedNotifyEvent is sent when verification of the run fails, or the run is aborted.
+	FailedNotifyEvent NotifyEvent = "Failed"
+)
+
+// SecretKeyReference refers to a key of a Secret in the namespace of the Oomer.
+type SecretKeyReference struct {
+	// Name of the Secret.
+	Name string `json:"name"`
+
+	// Key within the Secret.
+	Key string `json:"key"`
+}
+
+// WebhookTarget is an HTTP endpoint which notifications are sent to as a POST request.
+type WebhookTarget struct {
+	// URL which notifications are sent to.
+	URL string `json:"url"`
+
+	// Events limits the notifications which are sent to this target, defaults to
+	// every event.
+	// +optional
+	Events []NotifyEvent `json:"events,omitempty"`
+
+	// Template is a Go template used to render the body of the request, such as
+	// a message in the format expected by a chat service. The fields of the
+	// notification are available, for example {{ .Oomer }} and {{ .Event }}. When
+	// unset, the notification is sent as JSON.
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// SigningSecret refers to a key used to sign the body of each request with
+	// HMAC-SHA256, the signature is sent in the X-Oomer-Signature header.
+	// +optional
+	SigningSecret *SecretKeyReference `json:"signingSecret,omitempty"`
+}
+
+// NotifySpec configures the notifications sent during the run of an Oomer.
+type NotifySpec struct {
+	// Webhooks which notifications are sent to.
+	// +kubebuilder:validation:MinItems=1
+	Webhooks []WebhookTarget `json:"webhooks"`
+
+	// Retries is the number of times a failed notification is retried, defaults to 3.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+}
+
+// DeploymentAbortCriterion aborts an Oomer when a Deployment in its namespace no
+// longer has enough available replicas.
+type DeploymentAbortCriterion struct {
+	// Name of the Deployment.
+	Name string `json:"name"`
+
+	// MinAvailableReplicas is the fewest available replicas the Deployment may
+	// have before the Oomer is aborted.
+	// +kubebuilder:validation:Minimum=0
+	MinAvailableReplicas int32 `json:"minAvailableReplicas"`
+}
+
+// AbortCriterion is a condition under which injection must stop immediately,
+// exactly one of its criteria should be provided.
+type AbortCriterion struct {
+	// Name identifies the criterion in the Aborted condition of the Oomer.
+	Name string `json:"name"`
+
+	// Deployment aborts when the available replicas of a Deployment drop below
+	// a threshold.
+	Deployment *DeploymentAbortCriterion `json:"deployment,omitempty"`
+
+	// Prometheus aborts when a Prometheus query returns a sample greater than
+	// its threshold.
+	Prometheus *PrometheusCheck `json:"prometheus,omitempty"`
+}
+
+// ScheduleSpec determines when an Oomer injects OOM conditions.
+type ScheduleSpec struct {
+	// StartAt delays injection until the given time, when unset injection
+	// starts immediately.
+	StartAt *metav1.Time `json:"startAt,omitempty"`
+
+	// Duration is how long injection runs for before the Oomer completes, when
+	// unset injection continues until the Oomer is deleted.
+	Duration *metav1.Duration `json:"duration,omitempty"`
+}
+
+// OomerSpec defines the desired state of Oomer
+// +kubebuilder:validation:XValidation:rule="self.mode != 'Ephemeral' || ((!has(self.schedule) || !has(self.schedule.duration)) && (!has(self.paused) || !self.paused) && (!has(self.abortWhen) || size(self.abortWhen) == 0))",message="ephemeral containers cannot be removed, so the Ephemeral mode does not support schedule.duration, paused or abortWhen"
+type OomerSpec struct {
+	// Image is the allocator image, defaulting to the image configured for the operator.
+	// The timing, profile and allocation size are passed through OOMER_* environment
+	// variables which only the allocator built from cmd/allocator honours.
+	Image string `json:"image,omitempty"`
+
+	// Replicas is the number of desired OOMKilled pods to deploy. Zero is a valid
+	// value, so this is always serialised.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=1
+	// +optional
+	Replicas int32 `json:"replicas"`
+
+	// Selector selects the pods created by the Oomer, its match labels are applied
+	// to those pods. When unset, pods are labelled with app=oomer and the name of
+	// the Oomer in the oomer.jdocklabs.co.uk/oomer label.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Mode is how OOM conditions are injected, defaults to Deployment.
+	// +kubebuilder:default=Deployment
+	// +optional
+	Mode OomerMode `json:"mode,omitempty"`
+
+	// NodePressure configures the allocators when using the NodePressure mode,
+	// Replicas is ignored in this mode as a single allocator runs on each selected node.
+	NodePressure *NodePressureSpec `json:"nodePressure,omitempty"`
+
+	// MultiContainer configures the pods when using the MultiContainer mode, this
+	// is required in that mode.
+	MultiContainer *MultiContainerSpec `json:"multiContainer,omitempty"`
+
+	// Pattern varies the number of replicas over time, rather than using the
+	// static Replicas value. This does not apply in the NodePressure mode.
+	Pattern *PatternSpec `json:"pattern,omitempty"`
+
+	// Timing randomises when each allocator triggers an OOM, when unset allocators
+	// OOM immediately. This requires an Image which honours the OOMER_* variables.
+	Timing *TimingSpec `json:"timing,omitempty"`
+
+	// Profile determines how each allocator consumes memory, when unset allocators
+	// allocate all of their memory at once.
+	Profile *ProfileSpec `json:"profile,omitempty"`
+
+	// Target references the existing workload used in the Target, Squeeze, Ephemeral and Sidecar modes.
+	// In the Ephemeral mode, a non-zero Replicas limits the number of pods which
+	// have an allocator attached.
+	Target *TargetSpec `json:"target,omitempty"`
+
+	// Squeeze configures the steps taken in the Squeeze mode, this is required
+	// in that mode.
+	Squeeze *SqueezeSpec `json:"squeeze,omitempty"`
+
+	// Schedule determines when injection starts and how long it runs for.
+	Schedule *ScheduleSpec `json:"schedule,omitempty"`
+
+	// Paused stops injection until it is unset, the resources which the Oomer
+	// created or modified are removed or restored while it is paused. The
+	// scheduled duration continues to elapse while paused.
+	Paused bool `json:"paused,omitempty"`
+
+	// Verify asserts that the expected signals fired during the run, the results
+	// are reported through the Verified and VerificationFailed conditions.
+	Verify *VerifySpec `json:"verify,omitempty"`
+
+	// Notify sends notifications when the Oomer starts, observes its first OOM,
+	// completes or fails.
+	Notify *NotifySpec `json:"notify,omitempty"`
+
+	// AbortWhen lists the criteria which are evaluated on each reconcile while
+	// injecting, when any is met the created resources are removed and the Oomer
+	// is marked as Aborted.
+	// +optional
+	AbortWhen []AbortCriterion `json:"abortWhen,omitempty"`
+}
+
+// Condition types reported on an Oomer.
+const (
+	// ReadyCondition is false when an Oomer cannot be injected as specified, the
+	// reason and message say why. It is not retried until the Oomer changes.
+	ReadyCondition = "Ready"
+
+	// InjectingCondition is true while OOM conditions are being injected.
+	InjectingCondition = "Injecting"
+
+	// CompletedCondition is true once the scheduled duration of an Oomer has
+	// passed and injection has stopped.
+	CompletedCondition = "Completed"
+
+	// VerifiedCondition is true once every verification check has passed.
+	VerifiedCondition = "Verified"
+
+	// VerificationFailedCondition is true when a verification check had not
+	// passed within the allowed time.
+	VerificationFailedCondition = "VerificationFailed"
+
+	// AbortedCondition is true once an abort criterion has been met and injection
+	// has stopped.
+	AbortedCondition = "Aborted"
+)
+
+// OomerPhase summarises what an Oomer is doing.
+// +kubebuilder:validation:Enum=Pending;Injecting;OOMing;Paused;Completed;Aborted;Failed
+type OomerPhase string
+
+const (
+	// PendingPhase is the phase of an Oomer which is waiting to inject, either for
+	// its scheduled start time or for the injection budget.
+	PendingPhase OomerPhase = "Pending"
+
+	// InjectingPhase is the phase of an Oomer which is injecting OOM conditions
+	// but has not yet observed an OOMKilled container.
+	InjectingPhase OomerPhase = "Injecting"
+
+	// OOMingPhase is the phase of an Oomer which has observed an OOMKilled
+	// container while injecting.
+	OOMingPhase OomerPhase = "OOMing"
+
+	// PausedPhase is the phase of an Oomer which has been paused.
+	PausedPhase OomerPhase = "Paused"
+
+	// CompletedPhase is the phase of an Oomer whose scheduled duration has passed.
+	CompletedPhase OomerPhase = "Completed"
+
+	// AbortedPhase is the phase of an Oomer which met an abort criterion.
+	AbortedPhase OomerPhase = "Aborted"
+
+	// FailedPhase is the phase of an Oomer which finished without its
+	// verification checks passing.
+	FailedPhase OomerPhase = "Failed"
+)
+
+// PhaseTransition records an Oomer entering a phase.
+type PhaseTransition struct {
+	// Phase which was entered.
+	Phase OomerPhase `json:"phase"`
+
+	// Time the phase was entered.
+	Time metav1.Time `json:"time"`
+
+	// Reason the phase was entered.
+	Reason string `json:"reason"`
+}
+
+// NotificationRecord records a notification and its delivery to webhooks.
+type NotificationRecord struct {
+	// Event which the notification was sent for.
+	Event NotifyEvent `json:"event"`
+
+	// Time the event occurred, this is the time sent in the notification.
+	Time metav1.Time `json:"time"`
+
+	// Message sent in the notification.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Pending lists the URLs of the webhooks which the notification has not been
+	// delivered to yet, these are retried on later reconciles.
+	// +optional
+	Pending []string `json:"pending,omitempty"`
+
+	// Attempts is the number of times delivery has been attempted.
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// LastAttemptTime is when delivery was last attempted.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+
+	// DeliveredTime is when the notification had been delivered to every webhook,
+	// it is unset while any are pending or once the retries have been exhausted.
+	// +optional
+	DeliveredTime *metav1.Time `json:"deliveredTime,omitempty"`
+
+	// Error from the webhooks which the notification could not be delivered to on
+	// the last attempt.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// SqueezeStatus records the progress of an Oomer in the Squeeze mode.
+type SqueezeStatus struct {
+	// Steps is the number of times the memory limit has been lowered.
+	Steps int32 `json:"steps,omitempty"`
+
+	// LastStepTime is when the memory limit was last lowered.
+	// +optional
+	LastStepTime *metav1.Time `json:"lastStepTime,omitempty"`
+
+	// Limits are the memory limits currently applied to each targeted container,
+	// once the Oomer is OOMing these are the limits at which containers were OOMKilled.
+	// +optional
+	Limits map[string]resource.Quantity `json:"limits,omitempty"`
+}
+
+// ContainerOOMKills attributes OOM kills to a container of the pods in the
+// MultiContainer mode.
+type ContainerOOMKills struct {
+	// Name of the container.
+	Name string `json:"name"`
+
+	// OOMKilled is the number of pods in which this container was last terminated
+	// by the OOM killer.
+	OOMKilled int32 `json:"oomKilled"`
+
+	// Restarts is the sum of the restart counts of this container across the pods.
+	Restarts int32 `json:"restarts"`
+}
+
+// OomerStatus defines the observed state of Oomer
+type OomerStatus struct {
+	// ObservedReplicas are number of observed OOMKilled pods, this should
+	// match the number of configured replicas.
+	ObservedReplicas int32 `json:"observedReplicas,omitempty"`
+
+	// StartTime is when injection started, patterns are calculated from this point.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Phase summarises what the Oomer is doing.
+	// +optional
+	Phase OomerPhase `json:"phase,omitempty"`
+
+	// History lists the most recent phase transitions, oldest first.
+	// +optional
+	History []PhaseTransition `json:"history,omitempty"`
+
+	// Conditions represent the latest observations of the Oomer.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Notifications are those which have been sent, or are being retried, each
+	// event is only sent once.
+	// +listType=map
+	// +listMapKey=event
+	// +optional
+	Notifications []NotificationRecord `json:"notifications,omitempty"`
+
+	// Squeeze records the progress of squeezing in the Squeeze mode.
+	// +optional
+	Squeeze *SqueezeStatus `json:"squeeze,omitempty"`
+
+	// ContainerOOMKills attributes the OOM kills observed in the MultiContainer
+	// mode to each container of the pods.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ContainerOOMKills []ContainerOOMKills `json:"containerOOMKills,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Mode",type=string,JSONPath=`.spec.mode`
+//+kubebuilder:printcolumn:name="Replicas",type=integer,JSONPath=`.status.observedReplicas`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Oomer is the Schema for the oomers API
+type Oomer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OomerSpec   `json:"spec,omitempty"`
+	Status OomerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OomerList contains a list of Oomer
+type OomerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Oomer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Oomer{}, &OomerList{})
+}
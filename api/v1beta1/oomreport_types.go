@@ -0,0 +1,148 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OomerLabel is set on the resources generated for an Oomer, such as its
+// reports, with the name of the Oomer as its value.
+const OomerLabel = "oomer.jdocklabs.co.uk/oomer"
+
+// ReportReason is why an OomReport was generated.
+// +kubebuilder:validation:Enum=Completed;Deleted;Aborted
+type ReportReason string
+
+const (
+	// CompletedReason is used when the scheduled duration of the Oomer passed.
+	CompletedReason ReportReason = "Completed"
+
+	// DeletedReason is used when the Oomer was deleted.
+	DeletedReason ReportReason = "Deleted"
+
+	// AbortedReason is used when an abort criterion of the Oomer was met.
+	AbortedReason ReportReason = "Aborted"
+)
+
+// ContainerReport records the outcome of a single container which was affected by an Oomer.
+type ContainerReport struct {
+	// Pod is the name of the pod which the container belongs to.
+	Pod string `json:"pod"`
+
+	// Container is the name of the container.
+	Container string `json:"container"`
+
+	// Node is the name of the node which the pod was scheduled onto.
+	Node string `json:"node,omitempty"`
+
+	// RestartCount is the number of times the container restarted.
+	RestartCount int32 `json:"restartCount"`
+
+	// OOMKilled is true when the last termination of the container was an OOM kill.
+	OOMKilled bool `json:"oomKilled"`
+
+	// OOMKilledAt is when the container was last OOMKilled.
+	OOMKilledAt *metav1.Time `json:"oomKilledAt,omitempty"`
+}
+
+// EventReport records an event observed for an Oomer, or the pods which it affected.
+type EventReport struct {
+	// Object is the kind and name of the object which the event is about.
+	Object string `json:"object"`
+
+	// Type is the type of the event, such as Normal or Warning.
+	Type string `json:"type,omitempty"`
+
+	// Reason is a short, machine understandable, reason for the event.
+	Reason string `json:"reason,omitempty"`
+
+	// Message is a human readable description of the event.
+	Message string `json:"message,omitempty"`
+
+	// Count is the number of times the event occurred.
+	Count int32 `json:"count,omitempty"`
+
+	// LastTimestamp is when the event was most recently observed.
+	LastTimestamp *metav1.Time `json:"lastTimestamp,omitempty"`
+}
+
+// OomReportSpec defines the results of an Oomer run.
+type OomReportSpec struct {
+	// Oomer is the name of the Oomer which the report was generated for.
+	Oomer string `json:"oomer"`
+
+	// Reason is why the report was generated.
+	Reason ReportReason `json:"reason"`
+
+	// StartTime is when the Oomer started injecting.
+	StartTime metav1.Time `json:"startTime"`
+
+	// EndTime is when the Oomer stopped injecting.
+	EndTime metav1.Time `json:"endTime"`
+
+	// OomerSpec is a snapshot of the spec of the Oomer when the report was generated.
+	OomerSpec OomerSpec `json:"oomerSpec"`
+
+	// OOMKilledContainers is the number of containers which were OOMKilled.
+	OOMKilledContainers int32 `json:"oomKilledContainers"`
+
+	// TotalRestarts is the sum of the restart counts of the affected containers.
+	TotalRestarts int32 `json:"totalRestarts"`
+
+	// TimeToFirstOOM is how long after the start time the first container was
+	// OOMKilled, this is unset when no OOM kill was observed.
+	TimeToFirstOOM *metav1.Duration `json:"timeToFirstOOM,omitempty"`
+
+	// Nodes lists the nodes which the affected pods were scheduled onto.
+	Nodes []string `json:"nodes,omitempty"`
+
+	// Containers lists the containers which were affected.
+	Containers []ContainerReport `json:"containers,omitempty"`
+
+	// Events lists the events observed for the Oomer and the affected pods.
+	Events []EventReport `json:"events,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:printcolumn:name="Oomer",type=string,JSONPath=`.spec.oomer`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.spec.reason`
+//+kubebuilder:printcolumn:name="OOMKilled",type=integer,JSONPath=`.spec.oomKilledContainers`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// OomReport is the Schema for the oomreports API, it is a durable record of the
+// results of an Oomer which is generated when the Oomer completes or is deleted.
+type OomReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="OomReport is immutable"
+	Spec OomReportSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OomReportList contains a list of OomReport
+type OomReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OomReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OomReport{}, &OomReportList{})
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OomWatcherSpec defines which pods an OomWatcher observes.
+type OomWatcherSpec struct {
+	// Namespaces limits the watcher to pods in the given namespaces, when empty
+	// pods in every namespace are observed.
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// Selector limits the watcher to pods matching the selector, when unset every
+	// pod is observed.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// MaxRecords is the number of OOMKilled containers retained in the status, the
+	// oldest records are removed first.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=100
+	// +optional
+	MaxRecords int32 `json:"maxRecords,omitempty"`
+}
+
+// OOMKilledRecord records a container which was OOMKilled.
+type OOMKilledRecord struct {
+	// Namespace is the namespace of the pod.
+	Namespace string `json:"namespace"`
+
+	// Pod is the name of the pod which the container belongs to.
+	Pod string `json:"pod"`
+
+	// Container is the name of the container.
+	Container string `json:"container"`
+
+	// Node is the name of the node which the pod was scheduled onto.
+	Node string `json:"node,omitempty"`
+
+	// MemoryLimit is the memory limit of the container, when it has one.
+	MemoryLimit *resource.Quantity `json:"memoryLimit,omitempty"`
+
+	// MemoryRequest is the memory request of the container, when it has one.
+	MemoryRequest *resource.Quantity `json:"memoryRequest,omitempty"`
+
+	// RestartCount is the number of times the container had restarted when it
+	// was observed.
+	RestartCount int32 `json:"restartCount"`
+
+	// FinishedAt is when the container was OOMKilled.
+	FinishedAt metav1.Time `json:"finishedAt"`
+}
+
+// OomWatcherStatus defines the OOMKilled containers observed by an OomWatcher.
+type OomWatcherStatus struct {
+	// TotalOOMKilled is the number of OOMKilled containers observed since the
+	// watcher was created, this includes records which are no longer retained.
+	TotalOOMKilled int64 `json:"totalOOMKilled,omitempty"`
+
+	// LastOOMKilledTime is when the most recently observed container was OOMKilled.
+	LastOOMKilledTime *metav1.Time `json:"lastOOMKilledTime,omitempty"`
+
+	// Records are the most recently OOMKilled containers, oldest first.
+	Records []OOMKilledRecord `json:"records,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="OOMKilled",type=integer,JSONPath=`.status.totalOOMKilled`
+//+kubebuilder:printcolumn:name="Last",type=date,JSONPath=`.status.lastOOMKilledTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// OomWatcher is the Schema for the oomwatchers API, it records OOMKilled containers
+// across the cluster, including those which were not created by an Oomer.
+type OomWatcher struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OomWatcherSpec   `json:"spec,omitempty"`
+	Status OomWatcherStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OomWatcherList contains a list of OomWatcher
+type OomWatcherList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []OomWatcher `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&OomWatcher{}, &OomWatcherList{})
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// chunkSize is the size of each allocation, so that memory can be partially freed.
+	chunkSize = 1 << 20
+
+	// pageSize is the stride at which allocated memory is written to, so that it is
+	// resident rather than only reserved.
+	pageSize = 4096
+)
+
+// allocator holds the memory which it has allocated, in chunks which are freed
+// oldest first.
+type allocator struct {
+	mu        sync.Mutex
+	chunks    [][]byte
+	allocated int64
+	frees     int64
+}
+
+// allocate allocates, and writes to, n bytes of memory.
+func (a *allocator) allocate(n int64) {
+	for n > 0 {
+		size := int64(chunkSize)
+		if n < size {
+			size = n
+		}
+
+		chunk := make([]byte, size)
+		for i := 0; i < len(chunk); i += pageSize {
+			chunk[i] = 1
+		}
+
+		a.mu.Lock()
+		a.chunks = append(a.chunks, chunk)
+		a.allocated += size
+		a.mu.Unlock()
+
+		n -= size
+	}
+}
+
+// free releases percent of the memory held, returning it to the operating system
+// as a garbage collector would. It returns the number of bytes freed.
+func (a *allocator) free(percent int) int64 {
+	a.mu.Lock()
+	n := len(a.chunks) * percent / 100
+	var freed int64
+	for _, chunk := range a.chunks[:n] {
+		freed += int64(len(chunk))
+	}
+	a.chunks = append([][]byte(nil), a.chunks[n:]...)
+	a.allocated -= freed
+	a.frees++
+	a.mu.Unlock()
+
+	debug.FreeOSMemory()
+	return freed
+}
+
+// Allocated returns the number of bytes currently held.
+func (a *allocator) Allocated() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.allocated
+}
+
+// remaining returns how many more bytes may be allocated under the bound of the
+// configuration, with at most n bytes being requested.
+func (a *allocator) remaining(c *config, n int64) int64 {
+	if c.allocateBytes == 0 {
+		return n
+	}
+	if left := c.allocateBytes - a.Allocated(); left < n {
+		return left
+	}
+	return n
+}
+
+// run consumes memory following the profile of the configuration until the
+// context is cancelled, or the allocator is OOMKilled. Once the bound on the
+// allocated memory is reached, the memory is held.
+func (a *allocator) run(ctx context.Context, c *config) {
+	if c.profile == instantProfile {
+		if c.allocateBytes > 0 {
+			a.allocate(c.allocateBytes)
+		} else {
+			for ctx.Err() == nil {
+				a.allocate(chunkSize)
+			}
+		}
+		<-ctx.Done()
+		return
+	}
+
+	leak := time.NewTicker(c.tick)
+	defer leak.Stop()
+
+	// Only the sawtooth profile frees memory, a nil channel never receives.
+	var free <-chan time.Time
+	if c.profile == sawtoothProfile {
+		t := time.NewTicker(c.freeInterval)
+		defer t.Stop()
+		free = t.C
+	}
+
+	perTick := c.leakRate * int64(c.tick) / int64(time.Second)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-leak.C:
+			if n := a.remaining(c, perTick); n > 0 {
+				a.allocate(n)
+			}
+		case <-free:
+			a.free(c.freePercent)
+		}
+	}
+}
+
+// collector exposes the memory held by the allocator, alongside the resident
+// memory of the process which the process collector exposes.
+func (a *allocator) collector(c *config) prometheus.Collector {
+	return &allocatorCollector{a: a, c: c}
+}
+
+var (
+	allocatedDesc = prometheus.NewDesc("oomer_allocator_allocated_bytes",
+		"Memory currently held by the allocator in bytes.", nil, nil)
+	rssDesc = prometheus.NewDesc("oomer_allocator_rss_bytes",
+		"Resident memory of the allocator in bytes.", nil, nil)
+	freesDesc = prometheus.NewDesc("oomer_allocator_frees_total",
+		"Number of times memory has been freed by the sawtooth profile.", nil, nil)
+	leakRateDesc = prometheus.NewDesc("oomer_allocator_leak_rate_bytes",
+		"Configured rate at which memory is leaked in bytes per second.", []string{"profile"}, nil)
+)
+
+type allocatorCollector struct {
+	a *allocator
+	c *config
+}
+
+func (ac *allocatorCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- allocatedDesc
+	ch <- rssDesc
+	ch <- freesDesc
+	ch <- leakRateDesc
+}
+
+func (ac *allocatorCollector) Collect(ch chan<- prometheus.Metric) {
+	ac.a.mu.Lock()
+	allocated, frees := ac.a.allocated, ac.a.frees
+	ac.a.mu.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(allocatedDesc, prometheus.GaugeValue, float64(allocated))
+	ch <- prometheus.MustNewConstMetric(freesDesc, prometheus.CounterValue, float64(frees))
+	ch <- prometheus.MustNewConstMetric(leakRateDesc, prometheus.GaugeValue, float64(ac.c.leakRate), ac.c.profile)
+	if rss, err := residentBytes(); err == nil {
+		ch <- prometheus.MustNewConstMetric(rssDesc, prometheus.GaugeValue, float64(rss))
+	}
+}
+
+// residentBytes reads the resident memory of the process from /proc, which is
+// only available on Linux.
+func residentBytes() (int64, error) {
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0, err
+	}
+
+	var size, resident int64
+	if _, err := fmt.Sscan(string(statm), &size, &resident); err != nil {
+		return 0, err
+	}
+	return resident * int64(os.Getpagesize()), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// environment returns a getenv which reads from the given variables.
+func environment(vars map[string]string) func(string) string {
+	return func(name string) string { return vars[name] }
+}
+
+var _ = Describe("Allocator", func() {
+
+	It("Should default to the instant profile", func() {
+		c, err := loadConfig(environment(nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.profile).To(Equal(instantProfile))
+		Expect(c.leakRate).To(Equal(int64(defaultLeakRate)))
+		Expect(c.freeInterval).To(Equal(defaultFreeInterval))
+		Expect(c.metricsPort).To(BeZero())
+	})
+
+	It("Should read the configuration passed by the operator", func() {
+		c, err := loadConfig(environment(map[string]string{
+			"OOMER_ALLOCATE_BYTES": "1048576",
+			"OOMER_PROFILE":        "sawtooth",
+			"OOMER_LEAK_RATE":      "4096",
+			"OOMER_FREE_INTERVAL":  "30s",
+			"OOMER_FREE_PERCENT":   "25",
+			"OOMER_METRICS_PORT":   "9090",
+			"OOMER_MIN_DELAY":      "1s",
+			"OOMER_MAX_DELAY":      "2s",
+			"OOMER_SEED":           "42",
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.allocateBytes).To(Equal(int64(1 << 20)))
+		Expect(c.profile).To(Equal(sawtoothProfile))
+		Expect(c.leakRate).To(Equal(int64(4096)))
+		Expect(c.freeInterval).To(Equal(30 * time.Second))
+		Expect(c.freePercent).To(Equal(25))
+		Expect(c.metricsPort).To(Equal(9090))
+		Expect(*c.seed).To(Equal(int64(42)))
+	})
+
+	It("Should report every invalid variable", func() {
+		_, err := loadConfig(environment(map[string]string{
+			"OOMER_PROFILE":      "spike",
+			"OOMER_FREE_PERCENT": "0",
+			"OOMER_MIN_DELAY":    "soon",
+		}))
+		Expect(err).To(MatchError(ContainSubstring("OOMER_PROFILE")))
+		Expect(err).To(MatchError(ContainSubstring("OOMER_FREE_PERCENT")))
+		Expect(err).To(MatchError(ContainSubstring("OOMER_MIN_DELAY")))
+	})
+
+	It("Should choose the same delay for the same seed and pod", func() {
+		seed := int64(7)
+		c := &config{minDelay: time.Second, maxDelay: time.Minute, seed: &seed, podName: "oomer-abc"}
+		delay := c.delay(c.random())
+		Expect(delay).To(BeNumerically(">=", time.Second))
+		Expect(delay).To(BeNumerically("<=", time.Minute))
+		Expect(c.delay(c.random())).To(Equal(delay))
+
+		other := &config{minDelay: time.Second, maxDelay: time.Minute, seed: &seed, podName: "oomer-def"}
+		Expect(other.delay(other.random())).NotTo(Equal(delay))
+	})
+
+	It("Should free the oldest memory as a garbage collector would", func() {
+		a := &allocator{}
+		a.allocate(4*chunkSize + 10)
+		Expect(a.Allocated()).To(Equal(int64(4*chunkSize + 10)))
+
+		Expect(a.free(50)).To(Equal(int64(2 * chunkSize)))
+		Expect(a.Allocated()).To(Equal(int64(2*chunkSize + 10)))
+		Expect(a.frees).To(Equal(int64(1)))
+	})
+
+	It("Should leak up to the bound and expose the memory held", func() {
+		c := &config{
+			allocateBytes: 3 * chunkSize,
+			profile:       leakProfile,
+			leakRate:      chunkSize * 100,
+			freeInterval:  time.Hour,
+			freePercent:   50,
+			tick:          10 * time.Millisecond,
+		}
+		a := &allocator{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			a.run(ctx, c)
+		}()
+
+		Eventually(a.Allocated).Should(Equal(int64(3 * chunkSize)))
+		Consistently(a.Allocated, 100*time.Millisecond).Should(Equal(int64(3 * chunkSize)))
+		cancel()
+		Eventually(done).Should(BeClosed())
+
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(a.collector(c))
+		families, err := registry.Gather()
+		Expect(err).NotTo(HaveOccurred())
+
+		values := map[string]float64{}
+		for _, f := range families {
+			m := f.GetMetric()[0]
+			values[f.GetName()] = m.GetGauge().GetValue() + m.GetCounter().GetValue()
+		}
+		Expect(values).To(HaveKeyWithValue("oomer_allocator_allocated_bytes", float64(3*chunkSize)))
+		Expect(values).To(HaveKeyWithValue("oomer_allocator_leak_rate_bytes", float64(100*chunkSize)))
+		Expect(values).To(HaveKey("oomer_allocator_rss_bytes"))
+	})
+})
@@ -0,0 +1,266 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// allocator consumes memory until it is OOMKilled, it is the container which an
+// Oomer runs and is configured through the OOMER_* environment variables.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// Profiles which determine how memory is consumed over time.
+const (
+	instantProfile  = "instant"
+	leakProfile     = "leak"
+	sawtoothProfile = "sawtooth"
+)
+
+// Defaults of the settings which are not set through the environment.
+const (
+	defaultLeakRate     = 1 << 20
+	defaultFreeInterval = time.Minute
+	defaultFreePercent  = 50
+	defaultProbability  = 100
+
+	// leakTick is how often memory is leaked, the leak rate is per second.
+	leakTick = time.Second
+)
+
+// config is the configuration of the allocator read from the environment.
+type config struct {
+	// allocateBytes bounds the memory which is allocated, allocation continues
+	// until the allocator is OOMKilled when this is 0.
+	allocateBytes int64
+
+	profile      string
+	leakRate     int64
+	freeInterval time.Duration
+	freePercent  int
+	metricsPort  int
+
+	minDelay    time.Duration
+	maxDelay    time.Duration
+	interval    time.Duration
+	probability int
+	seed        *int64
+	podName     string
+
+	// tick is how often memory is leaked, it is only changed in tests.
+	tick time.Duration
+}
+
+// loadConfig reads the configuration from the environment through getenv.
+func loadConfig(getenv func(string) string) (*config, error) {
+	c := &config{
+		profile:      instantProfile,
+		leakRate:     defaultLeakRate,
+		freeInterval: defaultFreeInterval,
+		freePercent:  defaultFreePercent,
+		probability:  defaultProbability,
+		podName:      getenv("OOMER_POD_NAME"),
+		tick:         leakTick,
+	}
+
+	var errs []error
+	parseInt := func(name string, into *int64) {
+		if v := getenv(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				errs = append(errs, fmt.Errorf("%s must be a non-negative integer, got %q", name, v))
+				return
+			}
+			*into = n
+		}
+	}
+	parseDuration := func(name string, into *time.Duration) {
+		if v := getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d < 0 {
+				errs = append(errs, fmt.Errorf("%s must be a non-negative duration, got %q", name, v))
+				return
+			}
+			*into = d
+		}
+	}
+
+	var freePercent, probability, metricsPort int64 = defaultFreePercent, defaultProbability, 0
+	parseInt("OOMER_ALLOCATE_BYTES", &c.allocateBytes)
+	parseInt("OOMER_LEAK_RATE", &c.leakRate)
+	parseInt("OOMER_FREE_PERCENT", &freePercent)
+	parseInt("OOMER_PROBABILITY_PERCENT", &probability)
+	parseInt("OOMER_METRICS_PORT", &metricsPort)
+	parseDuration("OOMER_FREE_INTERVAL", &c.freeInterval)
+	parseDuration("OOMER_MIN_DELAY", &c.minDelay)
+	parseDuration("OOMER_MAX_DELAY", &c.maxDelay)
+	parseDuration("OOMER_INTERVAL", &c.interval)
+
+	if v := getenv("OOMER_SEED"); v != "" {
+		seed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("OOMER_SEED must be an integer, got %q", v))
+		}
+		c.seed = &seed
+	}
+
+	if v := getenv("OOMER_PROFILE"); v != "" {
+		c.profile = v
+	}
+
+	c.freePercent, c.probability, c.metricsPort = int(freePercent), int(probability), int(metricsPort)
+
+	switch c.profile {
+	case instantProfile, leakProfile, sawtoothProfile:
+	default:
+		errs = append(errs, fmt.Errorf("OOMER_PROFILE must be one of instant, leak or sawtooth, got %q", c.profile))
+	}
+	if c.leakRate == 0 {
+		errs = append(errs, errors.New("OOMER_LEAK_RATE must be positive"))
+	}
+	if c.freePercent < 1 || c.freePercent > 100 {
+		errs = append(errs, fmt.Errorf("OOMER_FREE_PERCENT must be between 1 and 100, got %d", c.freePercent))
+	}
+	if c.probability > 100 {
+		errs = append(errs, fmt.Errorf("OOMER_PROBABILITY_PERCENT must be at most 100, got %d", c.probability))
+	}
+	if c.metricsPort > 65535 {
+		errs = append(errs, fmt.Errorf("OOMER_METRICS_PORT must be at most 65535, got %d", c.metricsPort))
+	}
+	if c.maxDelay != 0 && c.minDelay > c.maxDelay {
+		errs = append(errs, fmt.Errorf("OOMER_MIN_DELAY %s is greater than OOMER_MAX_DELAY %s", c.minDelay, c.maxDelay))
+	}
+
+	return c, utilerrors.NewAggregate(errs)
+}
+
+// random returns the random number generator of the allocator, a seed is combined
+// with the pod name so that pods do not share the same sequence.
+func (c *config) random() *rand.Rand {
+	if c.seed == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(c.podName))
+	return rand.New(rand.NewSource(*c.seed ^ int64(h.Sum64())))
+}
+
+// delay returns how long to wait before allocating, chosen uniformly between the
+// minimum and maximum delay.
+func (c *config) delay(rng *rand.Rand) time.Duration {
+	if c.maxDelay <= c.minDelay {
+		return c.minDelay
+	}
+	return c.minDelay + time.Duration(rng.Int63n(int64(c.maxDelay-c.minDelay)+1))
+}
+
+// waitToAllocate blocks until the allocator should begin allocating, it returns
+// false when the context is cancelled first.
+func (c *config) waitToAllocate(ctx context.Context, rng *rand.Rand) bool {
+	if !sleep(ctx, c.delay(rng)) {
+		return false
+	}
+
+	if c.interval == 0 {
+		return true
+	}
+
+	for rng.Intn(100) >= c.probability {
+		if !sleep(ctx, c.interval) {
+			return false
+		}
+	}
+	return true
+}
+
+// sleep waits for d, returning false if the context is cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func main() {
+	var terminationLog string
+	flag.StringVar(&terminationLog, "termination-log", "/tmp/oomed-pod.log", "File which the configuration is written to before allocating.")
+	flag.Parse()
+
+	c, err := loadConfig(os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a := &allocator{}
+	if c.metricsPort > 0 {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			collectors.NewGoCollector(),
+			a.collector(c),
+		)
+
+		server := &http.Server{
+			Addr:              fmt.Sprintf(":%d", c.metricsPort),
+			Handler:           promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fmt.Fprintln(os.Stderr, "serving metrics:", err)
+			}
+		}()
+		defer server.Close()
+	}
+
+	if !c.waitToAllocate(ctx, c.random()) {
+		return
+	}
+
+	// The kubelet reports this as the termination message of the container, as
+	// an OOMKilled allocator has no chance to write one when it terminates.
+	message := fmt.Sprintf("allocating with the %s profile, up to %d bytes\n", c.profile, c.allocateBytes)
+	if err := os.WriteFile(terminationLog, []byte(message), 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "writing termination log:", err)
+	}
+
+	a.run(ctx, c)
+}
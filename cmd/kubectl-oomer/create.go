@@ -0,0 +1,164 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// runCreate creates an Oomer from flags.
+func runCreate(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	k := addKubeFlags(fs, false)
+
+	replicas := fs.Int("replicas", 1, "Number of allocators, or pods to attach to in the Ephemeral mode.")
+	mode := fs.String("mode", string(oomv1beta1.DeploymentMode), "One of Deployment, MultiContainer, NodePressure, Target, Squeeze, Ephemeral or Sidecar.")
+	image := fs.String("image", "", "Allocator image, defaults to the image of the operator.")
+	podLabels := fs.String("labels", "", "Labels for the created pods, such as app=oomer.")
+	targetDeployment := fs.String("target-deployment", "", "Deployment targeted in the Target, Squeeze and Ephemeral modes.")
+	targetSelector := fs.String("target-selector", "", "Selector for the pods targeted in the Ephemeral and Sidecar modes.")
+	container := fs.String("container", "", "Name of the targeted container.")
+	memoryLimit := fs.String("memory-limit", "", "Memory limit applied to the targeted containers, or the injected sidecar.")
+	squeezeStep := fs.String("squeeze-step", "", "Amount the memory limit is lowered by at each step in the Squeeze mode.")
+	squeezeFloor := fs.String("squeeze-floor", "", "Lowest memory limit applied in the Squeeze mode.")
+	squeezeInterval := fs.Duration("squeeze-interval", 0, "Time between each step in the Squeeze mode, defaults to 1m.")
+	profile := fs.String("profile", "", "Allocation profile, one of instant, leak or sawtooth.")
+	leakRate := fs.String("leak-rate", "", "Memory leaked each second in the leak and sawtooth profiles, defaults to 1Mi.")
+	startAt := fs.String("start-at", "", "RFC3339 time to start injecting at.")
+	duration := fs.Duration("duration", 0, "How long to inject for, runs until deleted when unset.")
+	paused := fs.Bool("paused", false, "Create the Oomer without starting injection.")
+
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	name, err := exactlyOne(args, "name")
+	if err != nil {
+		return err
+	}
+
+	c, namespace, err := e.client(k)
+	if err != nil {
+		return err
+	}
+
+	o := &oomv1beta1.Oomer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: oomv1beta1.OomerSpec{
+			Image:    *image,
+			Replicas: int32(*replicas),
+			Mode:     oomv1beta1.OomerMode(*mode),
+			Paused:   *paused,
+		},
+	}
+
+	if *podLabels != "" {
+		set, err := labels.ConvertSelectorToLabelsMap(*podLabels)
+		if err != nil {
+			return fmt.Errorf("invalid labels: %w", err)
+		}
+		o.Spec.Selector = &metav1.LabelSelector{MatchLabels: set}
+	}
+
+	if *targetDeployment != "" || *targetSelector != "" || *container != "" || *memoryLimit != "" {
+		o.Spec.Target = &oomv1beta1.TargetSpec{
+			Deployment:    *targetDeployment,
+			ContainerName: *container,
+		}
+
+		if *targetSelector != "" {
+			selector, err := metav1.ParseToLabelSelector(*targetSelector)
+			if err != nil {
+				return fmt.Errorf("invalid target selector: %w", err)
+			}
+			o.Spec.Target.Selector = selector
+		}
+
+		if *memoryLimit != "" {
+			limit, err := resource.ParseQuantity(*memoryLimit)
+			if err != nil {
+				return fmt.Errorf("invalid memory limit: %w", err)
+			}
+			o.Spec.Target.MemoryLimit = &limit
+		}
+	}
+
+	if *squeezeStep != "" || *squeezeFloor != "" || *squeezeInterval != 0 {
+		step, err := resource.ParseQuantity(*squeezeStep)
+		if err != nil {
+			return fmt.Errorf("invalid squeeze step: %w", err)
+		}
+		floor, err := resource.ParseQuantity(*squeezeFloor)
+		if err != nil {
+			return fmt.Errorf("invalid squeeze floor: %w", err)
+		}
+		o.Spec.Squeeze = &oomv1beta1.SqueezeSpec{Step: step, Floor: floor}
+
+		if *squeezeInterval != 0 {
+			o.Spec.Squeeze.Interval = &metav1.Duration{Duration: *squeezeInterval}
+		}
+	}
+
+	if *profile != "" || *leakRate != "" {
+		o.Spec.Profile = &oomv1beta1.ProfileSpec{Type: oomv1beta1.ProfileType(*profile)}
+
+		if *leakRate != "" {
+			rate, err := resource.ParseQuantity(*leakRate)
+			if err != nil {
+				return fmt.Errorf("invalid leak rate: %w", err)
+			}
+			o.Spec.Profile.Rate = &rate
+		}
+	}
+
+	if *startAt != "" || *duration != 0 {
+		o.Spec.Schedule = &oomv1beta1.ScheduleSpec{}
+
+		if *startAt != "" {
+			t, err := time.Parse(time.RFC3339, *startAt)
+			if err != nil {
+				return fmt.Errorf("invalid start time: %w", err)
+			}
+			start := metav1.NewTime(t)
+			o.Spec.Schedule.StartAt = &start
+		}
+
+		if *duration != 0 {
+			o.Spec.Schedule.Duration = &metav1.Duration{Duration: *duration}
+		}
+	}
+
+	if err := c.Create(ctx, o); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.out, "oomer.jdocklabs.co.uk/%s created\n", name)
+	return nil
+}
@@ -0,0 +1,271 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+)
+
+// age formats the time since t as kubectl does.
+func age(t metav1.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t.Time))
+}
+
+// status summarises the state of an Oomer from its phase, or from its spec and
+// conditions when it has not been given a phase yet.
+func status(o *oomv1beta1.Oomer) string {
+	if o.Status.Phase != "" {
+		return string(o.Status.Phase)
+	}
+
+	if o.Spec.Paused {
+		return "Paused"
+	}
+
+	if meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.CompletedCondition) {
+		return "Completed"
+	}
+
+	c := meta.FindStatusCondition(o.Status.Conditions, oomv1beta1.InjectingCondition)
+	switch {
+	case c == nil:
+		return "Pending"
+	case c.Status == metav1.ConditionTrue:
+		return "Injecting"
+	default:
+		return c.Reason
+	}
+}
+
+// liveOOMKilled counts the containers affected by an Oomer which are currently
+// OOMKilled, this is "<unknown>" when the pods cannot be found.
+func liveOOMKilled(ctx context.Context, c client.Client, o *oomv1beta1.Oomer) string {
+	selector, err := oomkill.PodSelector(ctx, c, o)
+	if err != nil {
+		return "<unknown>"
+	}
+
+	var pods corev1.PodList
+	if err := c.List(ctx, &pods, client.InNamespace(o.ObjectMeta.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return "<unknown>"
+	}
+
+	return fmt.Sprint(oomkill.Count(pods.Items))
+}
+
+// runList lists Oomers with their live OOMKilled count.
+func runList(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	k := addKubeFlags(fs, true)
+
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	c, namespace, err := e.client(k)
+	if err != nil {
+		return err
+	}
+
+	var oomers oomv1beta1.OomerList
+	if err := c.List(ctx, &oomers, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	if len(oomers.Items) == 0 {
+		fmt.Fprintln(e.out, "No oomers found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(e.out, 0, 0, 3, ' ', 0)
+	if k.allNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tMODE\tREPLICAS\tOOMKILLED\tSTATUS\tAGE")
+
+	for i := range oomers.Items {
+		o := &oomers.Items[i]
+		if k.allNamespaces {
+			fmt.Fprintf(w, "%s\t", o.ObjectMeta.Namespace)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\t%s\t%s\n",
+			o.ObjectMeta.Name, o.Spec.Mode, o.Status.ObservedReplicas, o.Spec.Replicas,
+			liveOOMKilled(ctx, c, o), status(o), age(o.ObjectMeta.CreationTimestamp))
+	}
+
+	return w.Flush()
+}
+
+// runDescribe shows the status and conditions of an Oomer.
+func runDescribe(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	k := addKubeFlags(fs, false)
+
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	name, err := exactlyOne(args, "name")
+	if err != nil {
+		return err
+	}
+
+	c, namespace, err := e.client(k)
+	if err != nil {
+		return err
+	}
+
+	o := &oomv1beta1.Oomer{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, o); err != nil {
+		return err
+	}
+
+	var reports oomv1beta1.OomReportList
+	if err := c.List(ctx, &reports, client.InNamespace(namespace), client.MatchingLabels{oomv1beta1.OomerLabel: name}); err != nil {
+		return err
+	}
+
+	describe(e.out, o, liveOOMKilled(ctx, c, o), reports.Items)
+	return nil
+}
+
+// describe writes the details of an Oomer in the style of kubectl describe.
+func describe(out io.Writer, o *oomv1beta1.Oomer, oomKilled string, reports []oomv1beta1.OomReport) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	image := o.Spec.Image
+	if image == "" {
+		image = "<default>"
+	}
+
+	started := "<not started>"
+	if o.Status.StartTime != nil {
+		started = o.Status.StartTime.Format(time.RFC3339)
+	}
+
+	fmt.Fprintf(w, "Name:\t%s\n", o.ObjectMeta.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", o.ObjectMeta.Namespace)
+	fmt.Fprintf(w, "Mode:\t%s\n", o.Spec.Mode)
+	fmt.Fprintf(w, "Image:\t%s\n", image)
+	fmt.Fprintf(w, "Replicas:\t%d desired | %d observed\n", o.Spec.Replicas, o.Status.ObservedReplicas)
+	fmt.Fprintf(w, "Status:\t%s\n", status(o))
+	fmt.Fprintf(w, "Started:\t%s\n", started)
+	fmt.Fprintf(w, "OOMKilled:\t%s\n", oomKilled)
+
+	if t := o.Spec.Target; t != nil {
+		fmt.Fprintln(w, "Target:")
+		if t.Deployment != "" {
+			fmt.Fprintf(w, "  Deployment:\t%s\n", t.Deployment)
+		}
+		if t.Selector != nil {
+			fmt.Fprintf(w, "  Selector:\t%s\n", metav1.FormatLabelSelector(t.Selector))
+		}
+		if t.ContainerName != "" {
+			fmt.Fprintf(w, "  Container:\t%s\n", t.ContainerName)
+		}
+		if t.MemoryLimit != nil {
+			fmt.Fprintf(w, "  Memory Limit:\t%s\n", t.MemoryLimit.String())
+		}
+	}
+
+	if s := o.Spec.Squeeze; s != nil {
+		fmt.Fprintln(w, "Squeeze:")
+		fmt.Fprintf(w, "  Step:\t%s\n", s.Step.String())
+		fmt.Fprintf(w, "  Floor:\t%s\n", s.Floor.String())
+		if status := o.Status.Squeeze; status != nil {
+			fmt.Fprintf(w, "  Steps Taken:\t%d\n", status.Steps)
+
+			containers := make([]string, 0, len(status.Limits))
+			for name := range status.Limits {
+				containers = append(containers, name)
+			}
+			sort.Strings(containers)
+			for _, name := range containers {
+				limit := status.Limits[name]
+				fmt.Fprintf(w, "  Limit (%s):\t%s\n", name, limit.String())
+			}
+		}
+	}
+
+	if mc := o.Spec.MultiContainer; mc != nil {
+		fmt.Fprintln(w, "Multi Container:")
+		fmt.Fprintf(w, "  Memory Limit:\t%s\n", mc.MemoryLimit.String())
+		fmt.Fprintf(w, "  Overcommit:\t%d%%\n", mc.OvercommitPercent)
+		for _, c := range o.Status.ContainerOOMKills {
+			fmt.Fprintf(w, "  OOMKilled (%s):\t%d | %d restarts\n", c.Name, c.OOMKilled, c.Restarts)
+		}
+	}
+
+	if s := o.Spec.Schedule; s != nil {
+		fmt.Fprintln(w, "Schedule:")
+		if s.StartAt != nil {
+			fmt.Fprintf(w, "  Start At:\t%s\n", s.StartAt.Format(time.RFC3339))
+		}
+		if s.Duration != nil {
+			fmt.Fprintf(w, "  Duration:\t%s\n", s.Duration.Duration)
+		}
+	}
+
+	fmt.Fprintln(w, "Conditions:")
+	if len(o.Status.Conditions) == 0 {
+		fmt.Fprintln(w, "  <none>")
+	} else {
+		fmt.Fprintln(w, "  Type\tStatus\tReason\tAge\tMessage")
+		for _, c := range o.Status.Conditions {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", c.Type, c.Status, c.Reason, age(c.LastTransitionTime), c.Message)
+		}
+	}
+
+	fmt.Fprintln(w, "History:")
+	if len(o.Status.History) == 0 {
+		fmt.Fprintln(w, "  <none>")
+	} else {
+		fmt.Fprintln(w, "  Phase\tReason\tAge")
+		for _, t := range o.Status.History {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", t.Phase, t.Reason, age(t.Time))
+		}
+	}
+
+	fmt.Fprintln(w, "Reports:")
+	if len(reports) == 0 {
+		fmt.Fprintln(w, "  <none>")
+	}
+	for _, r := range reports {
+		fmt.Fprintf(w, "  %s\t%s\t%d OOMKilled\n", r.ObjectMeta.Name, r.Spec.Reason, r.Spec.OOMKilledContainers)
+	}
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-oomer is a kubectl plugin for running Oomers without writing YAML.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomclient "github.com/jdockerty/oom-operator/pkg/client"
+)
+
+// command is a subcommand of the plugin.
+type command struct {
+	usage       string
+	description string
+	run         func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"create":   {"create NAME [flags]", "Create an Oomer", runCreate},
+	"list":     {"list [flags]", "List Oomers with their live OOMKilled count", runList},
+	"describe": {"describe NAME [flags]", "Show the status and conditions of an Oomer", runDescribe},
+	"pause":    {"pause NAME... [flags]", "Pause injection for Oomers", runPause},
+	"resume":   {"resume NAME... [flags]", "Resume injection for paused Oomers", runResume},
+	"stop-all": {"stop-all [flags]", "Delete every Oomer, generating their reports", runStopAll},
+	"report":   {"report [NAME] [flags]", "List reports, or show the latest report of an Oomer", runReport},
+	"wait":     {"wait NAME --for CONDITION", "Wait until an Oomer meets a condition, such as 3 OOMKilled containers with oomkilled=3", runWait},
+}
+
+// env is the environment which a command runs in.
+type env struct {
+	out io.Writer
+
+	// connect returns a client for the cluster and the namespace of the current
+	// context, it is replaced in tests.
+	connect func(kubeconfig string) (client.Client, string, error)
+}
+
+// connect builds a client from the kubeconfig, following the same loading rules as kubectl.
+func connect(kubeconfig string) (client.Client, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+
+	namespace, _, err := config.Namespace()
+	if err != nil {
+		return nil, "", err
+	}
+
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	c, err := client.New(restConfig, client.Options{Scheme: oomclient.Scheme})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return c, namespace, nil
+}
+
+// kubeFlags are the flags shared by every command for connecting to a cluster.
+type kubeFlags struct {
+	kubeconfig    string
+	namespace     string
+	allNamespaces bool
+}
+
+// addKubeFlags registers the shared flags, the all namespaces flag is only
+// registered for commands which support it.
+func addKubeFlags(fs *flag.FlagSet, allNamespaces bool) *kubeFlags {
+	k := &kubeFlags{}
+	fs.StringVar(&k.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	fs.StringVar(&k.namespace, "namespace", "", "Namespace to use, defaults to the namespace of the current context.")
+	fs.StringVar(&k.namespace, "n", "", "Shorthand for --namespace.")
+	if allNamespaces {
+		fs.BoolVar(&k.allNamespaces, "all-namespaces", false, "Use every namespace.")
+		fs.BoolVar(&k.allNamespaces, "A", false, "Shorthand for --all-namespaces.")
+	}
+	return k
+}
+
+// client connects to the cluster, returning the namespace to use. This is empty
+// when every namespace is used.
+func (e *env) client(k *kubeFlags) (client.Client, string, error) {
+	c, namespace, err := e.connect(k.kubeconfig)
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch {
+	case k.allNamespaces:
+		namespace = ""
+	case k.namespace != "":
+		namespace = k.namespace
+	case namespace == "":
+		namespace = "default"
+	}
+
+	return c, namespace, nil
+}
+
+// parse parses flags which may be given before or after positional arguments, as
+// kubectl allows, returning the positional arguments.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// exactlyOne returns the single positional argument of a command.
+func exactlyOne(args []string, what string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("expected exactly one %s, got %d", what, len(args))
+	}
+	return args[0], nil
+}
+
+func usage(out io.Writer) {
+	fmt.Fprintln(out, "kubectl oomer runs Oomers without writing YAML.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Usage:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(out, "  kubectl oomer %-28s %s\n", commands[name].usage, commands[name].description)
+	}
+
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Use \"kubectl oomer <command> -h\" for the flags of a command.")
+}
+
+// run runs the command named by the first argument.
+func run(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 {
+		usage(e.out)
+		return errors.New("no command given")
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(e.out)
+		return nil
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		usage(e.out)
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	return cmd.run(ctx, e, args[1:])
+}
+
+func main() {
+	e := &env{out: os.Stdout, connect: connect}
+
+	if err := run(context.Background(), e, os.Args[1:]); err != nil {
+		// The flag package has already printed the usage of the command.
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fmt.Fprintln(os.Stderr, "error:", strings.TrimSpace(err.Error()))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	oomclient "github.com/jdockerty/oom-operator/pkg/client"
+)
+
+var _ = Describe("kubectl oomer", func() {
+	const namespace = "drills"
+
+	ctx := context.Background()
+
+	var (
+		c   client.Client
+		out *bytes.Buffer
+		e   *env
+	)
+
+	BeforeEach(func() {
+		c = fake.NewClientBuilder().WithScheme(oomclient.Scheme).Build()
+		out = &bytes.Buffer{}
+		e = &env{
+			out: out,
+			connect: func(string) (client.Client, string, error) {
+				return c, namespace, nil
+			},
+		}
+	})
+
+	get := func(name string) *oomv1beta1.Oomer {
+		o := &oomv1beta1.Oomer{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, o)).To(Succeed())
+		return o
+	}
+
+	It("Should create an oomer from flags", func() {
+		Expect(run(ctx, e, []string{"create", "leak", "--replicas", "3", "--labels", "app=leak", "--duration", "10m"})).To(Succeed())
+		Expect(out.String()).Should(Equal("oomer.jdocklabs.co.uk/leak created\n"))
+
+		o := get("leak")
+		Expect(o.Spec.Replicas).Should(Equal(int32(3)))
+		Expect(o.Spec.Mode).Should(Equal(oomv1beta1.DeploymentMode))
+		Expect(o.Spec.Selector.MatchLabels).Should(Equal(map[string]string{"app": "leak"}))
+		Expect(o.Spec.Schedule.Duration.Duration).Should(Equal(10 * time.Minute))
+	})
+
+	It("Should accept flags after the name", func() {
+		Expect(run(ctx, e, []string{"create", "sidecar", "--mode", "Sidecar", "--target-selector", "app=web", "--memory-limit", "32Mi", "-n", "other"})).To(Succeed())
+
+		o := &oomv1beta1.Oomer{}
+		Expect(c.Get(ctx, types.NamespacedName{Name: "sidecar", Namespace: "other"}, o)).To(Succeed())
+		Expect(o.Spec.Target.Selector.MatchLabels).Should(Equal(map[string]string{"app": "web"}))
+		Expect(o.Spec.Target.MemoryLimit.String()).Should(Equal("32Mi"))
+	})
+
+	It("Should create and describe a squeeze", func() {
+		Expect(run(ctx, e, []string{"create", "squeeze", "--mode", "Squeeze", "--target-deployment", "web",
+			"--squeeze-step", "32Mi", "--squeeze-floor", "64Mi", "--squeeze-interval", "30s"})).To(Succeed())
+
+		o := get("squeeze")
+		Expect(o.Spec.Squeeze.Step.String()).Should(Equal("32Mi"))
+		Expect(o.Spec.Squeeze.Floor.String()).Should(Equal("64Mi"))
+		Expect(o.Spec.Squeeze.Interval.Duration).Should(Equal(30 * time.Second))
+
+		o.Status.Squeeze = &oomv1beta1.SqueezeStatus{
+			Steps:  2,
+			Limits: map[string]resource.Quantity{"app": resource.MustParse("192Mi")},
+		}
+		Expect(c.Status().Update(ctx, o)).To(Succeed())
+		out.Reset()
+
+		Expect(run(ctx, e, []string{"describe", "squeeze"})).To(Succeed())
+		Expect(out.String()).Should(MatchRegexp(`Steps Taken:\s+2`))
+		Expect(out.String()).Should(MatchRegexp(`Limit \(app\):\s+192Mi`))
+	})
+
+	It("Should create an oomer with a leak profile", func() {
+		Expect(run(ctx, e, []string{"create", "slow", "--profile", "leak", "--leak-rate", "512Ki"})).To(Succeed())
+
+		o := get("slow")
+		Expect(o.Spec.Profile.Type).Should(Equal(oomv1beta1.LeakProfile))
+		Expect(o.Spec.Profile.Rate.String()).Should(Equal("512Ki"))
+	})
+
+	It("Should list oomers with their live OOMKilled count", func() {
+		Expect(run(ctx, e, []string{"create", "leak", "--labels", "app=leak"})).To(Succeed())
+		Expect(c.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "leak-1", Namespace: namespace, Labels: map[string]string{"app": "leak"}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "oomer",
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 137,
+					Reason:   "OOMKilled",
+				}},
+			}}},
+		})).To(Succeed())
+		out.Reset()
+
+		Expect(run(ctx, e, []string{"list"})).To(Succeed())
+		Expect(out.String()).Should(ContainSubstring("OOMKILLED"))
+		Expect(out.String()).Should(MatchRegexp(`leak\s+Deployment\s+0/1\s+1\s+Pending`))
+	})
+
+	It("Should wait until an oomer has OOMKilled containers", func() {
+		Expect(run(ctx, e, []string{"create", "leak", "--labels", "app=leak"})).To(Succeed())
+		out.Reset()
+
+		err := run(ctx, e, []string{"wait", "leak", "--for", "oomkilled=1", "--timeout", "50ms", "--interval", "10ms"})
+		Expect(err).To(MatchError(ContainSubstring("last seen Pending with 0 OOMKilled containers")))
+
+		Expect(c.Create(ctx, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "leak-1", Namespace: namespace, Labels: map[string]string{"app": "leak"}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "oomer",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+			}}},
+		})).To(Succeed())
+
+		Expect(run(ctx, e, []string{"wait", "leak", "--for", "oomkilled=1", "--interval", "10ms"})).To(Succeed())
+		Expect(out.String()).Should(Equal("oomer.jdocklabs.co.uk/leak condition met, Pending with 1 OOMKilled containers\n"))
+
+		out.Reset()
+		Expect(run(ctx, e, []string{"wait", "leak", "--for", "oomkilled=1", "-o", "json"})).To(Succeed())
+		Expect(out.String()).Should(ContainSubstring(`"oomKilledContainers": 1`))
+
+		Expect(run(ctx, e, []string{"wait", "leak", "--for", "ready"})).To(MatchError(ContainSubstring("unknown condition")))
+	})
+
+	It("Should pause and resume an oomer", func() {
+		Expect(run(ctx, e, []string{"create", "leak"})).To(Succeed())
+
+		Expect(run(ctx, e, []string{"pause", "leak"})).To(Succeed())
+		Expect(get("leak").Spec.Paused).To(BeTrue())
+
+		Expect(run(ctx, e, []string{"resume", "leak"})).To(Succeed())
+		Expect(get("leak").Spec.Paused).To(BeFalse())
+	})
+
+	It("Should stop every oomer", func() {
+		Expect(run(ctx, e, []string{"create", "one"})).To(Succeed())
+		Expect(run(ctx, e, []string{"create", "two"})).To(Succeed())
+
+		Expect(run(ctx, e, []string{"stop-all"})).To(Succeed())
+
+		var oomers oomv1beta1.OomerList
+		Expect(c.List(ctx, &oomers)).To(Succeed())
+		Expect(oomers.Items).Should(BeEmpty())
+	})
+
+	It("Should describe the phase history of an oomer", func() {
+		Expect(run(ctx, e, []string{"create", "leak"})).To(Succeed())
+		o := get("leak")
+		o.Status.Phase = oomv1beta1.OOMingPhase
+		o.Status.History = []oomv1beta1.PhaseTransition{
+			{Phase: oomv1beta1.PendingPhase, Reason: "Created", Time: metav1.Now()},
+			{Phase: oomv1beta1.InjectingPhase, Reason: "Started", Time: metav1.Now()},
+			{Phase: oomv1beta1.OOMingPhase, Reason: "OOMKilled", Time: metav1.Now()},
+		}
+		Expect(c.Status().Update(ctx, o)).To(Succeed())
+		out.Reset()
+
+		Expect(run(ctx, e, []string{"describe", "leak"})).To(Succeed())
+		Expect(out.String()).Should(MatchRegexp(`Status:\s+OOMing`))
+		Expect(out.String()).Should(MatchRegexp(`(?s)Pending\s+Created.*Injecting\s+Started.*OOMing\s+OOMKilled`))
+	})
+
+	It("Should show the latest report of an oomer", func() {
+		report := func(name string, start time.Time, oomKilled int32) *oomv1beta1.OomReport {
+			return &oomv1beta1.OomReport{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+					Labels:    map[string]string{oomv1beta1.OomerLabel: "leak"},
+				},
+				Spec: oomv1beta1.OomReportSpec{
+					Oomer:               "leak",
+					Reason:              oomv1beta1.CompletedReason,
+					StartTime:           metav1.NewTime(start),
+					EndTime:             metav1.NewTime(start.Add(time.Hour)),
+					OOMKilledContainers: oomKilled,
+				},
+			}
+		}
+
+		start := time.Date(2023, time.January, 1, 12, 0, 0, 0, time.UTC)
+		Expect(c.Create(ctx, report("leak-old", start, 1))).To(Succeed())
+		Expect(c.Create(ctx, report("leak-new", start.Add(24*time.Hour), 5))).To(Succeed())
+
+		Expect(run(ctx, e, []string{"report", "leak"})).To(Succeed())
+		Expect(out.String()).Should(ContainSubstring("leak-new"))
+		Expect(out.String()).Should(MatchRegexp(`OOMKilled:\s+5`))
+	})
+
+	It("Should reject an unknown command", func() {
+		Expect(run(ctx, e, []string{"explode"})).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	oomclient "github.com/jdockerty/oom-operator/pkg/client"
+)
+
+// runPause pauses injection for Oomers.
+func runPause(ctx context.Context, e *env, args []string) error {
+	return setPaused(ctx, e, "pause", args, true)
+}
+
+// runResume resumes injection for paused Oomers.
+func runResume(ctx context.Context, e *env, args []string) error {
+	return setPaused(ctx, e, "resume", args, false)
+}
+
+// setPaused patches spec.paused of the named Oomers.
+func setPaused(ctx context.Context, e *env, name string, args []string, paused bool) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	k := addKubeFlags(fs, false)
+
+	names, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		return errors.New("expected at least one name")
+	}
+
+	c, namespace, err := e.client(k)
+	if err != nil {
+		return err
+	}
+
+	action := "paused"
+	if !paused {
+		action = "resumed"
+	}
+
+	for _, n := range names {
+		if err := oomclient.NewFromClient(c).Oomers(namespace).SetPaused(ctx, n, paused); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(e.out, "oomer.jdocklabs.co.uk/%s %s\n", n, action)
+	}
+
+	return nil
+}
+
+// runStopAll deletes every Oomer, the operator generates a report for each of them.
+func runStopAll(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("stop-all", flag.ContinueOnError)
+	k := addKubeFlags(fs, true)
+
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	c, namespace, err := e.client(k)
+	if err != nil {
+		return err
+	}
+
+	var oomers oomv1beta1.OomerList
+	if err := c.List(ctx, &oomers, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	if len(oomers.Items) == 0 {
+		fmt.Fprintln(e.out, "No oomers found.")
+		return nil
+	}
+
+	for i := range oomers.Items {
+		o := &oomers.Items[i]
+		if err := c.Delete(ctx, o); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		fmt.Fprintf(e.out, "oomer.jdocklabs.co.uk/%s deleted from %s\n", o.ObjectMeta.Name, o.ObjectMeta.Namespace)
+	}
+
+	return nil
+}
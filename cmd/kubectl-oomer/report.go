@@ -0,0 +1,151 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	oomclient "github.com/jdockerty/oom-operator/pkg/client"
+)
+
+// runReport lists reports, or shows a single report. The name may be that of a
+// report or of an Oomer, in which case its latest report is shown.
+func runReport(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	k := addKubeFlags(fs, true)
+
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 1 {
+		return fmt.Errorf("expected at most one name, got %d", len(args))
+	}
+
+	c, namespace, err := e.client(k)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		var reports oomv1beta1.OomReportList
+		if err := c.List(ctx, &reports, client.InNamespace(namespace)); err != nil {
+			return err
+		}
+		return listReports(e.out, reports.Items, k.allNamespaces)
+	}
+
+	report, err := findReport(ctx, c, namespace, args[0])
+	if err != nil {
+		return err
+	}
+
+	showReport(e.out, report)
+	return nil
+}
+
+// findReport returns the named report, or the latest report of the named Oomer.
+func findReport(ctx context.Context, c client.Client, namespace, name string) (*oomv1beta1.OomReport, error) {
+	report := &oomv1beta1.OomReport{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, report)
+	if err == nil {
+		return report, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	return oomclient.NewFromClient(c).OomReports(namespace).Latest(ctx, name)
+}
+
+// listReports writes a table of reports.
+func listReports(out io.Writer, reports []oomv1beta1.OomReport, allNamespaces bool) error {
+	if len(reports) == 0 {
+		fmt.Fprintln(out, "No reports found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	if allNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, "NAME\tOOMER\tREASON\tOOMKILLED\tRESTARTS\tDURATION\tAGE")
+
+	for _, r := range reports {
+		if allNamespaces {
+			fmt.Fprintf(w, "%s\t", r.ObjectMeta.Namespace)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%s\t%s\n",
+			r.ObjectMeta.Name, r.Spec.Oomer, r.Spec.Reason, r.Spec.OOMKilledContainers, r.Spec.TotalRestarts,
+			r.Spec.EndTime.Sub(r.Spec.StartTime.Time).Round(time.Second), age(r.ObjectMeta.CreationTimestamp))
+	}
+
+	return w.Flush()
+}
+
+// showReport writes the details of a report.
+func showReport(out io.Writer, r *oomv1beta1.OomReport) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	timeToFirstOOM := "<none>"
+	if r.Spec.TimeToFirstOOM != nil {
+		timeToFirstOOM = r.Spec.TimeToFirstOOM.Duration.String()
+	}
+
+	fmt.Fprintf(w, "Name:\t%s\n", r.ObjectMeta.Name)
+	fmt.Fprintf(w, "Namespace:\t%s\n", r.ObjectMeta.Namespace)
+	fmt.Fprintf(w, "Oomer:\t%s\n", r.Spec.Oomer)
+	fmt.Fprintf(w, "Reason:\t%s\n", r.Spec.Reason)
+	fmt.Fprintf(w, "Mode:\t%s\n", r.Spec.OomerSpec.Mode)
+	fmt.Fprintf(w, "Start Time:\t%s\n", r.Spec.StartTime.Format(time.RFC3339))
+	fmt.Fprintf(w, "End Time:\t%s\n", r.Spec.EndTime.Format(time.RFC3339))
+	fmt.Fprintf(w, "OOMKilled:\t%d\n", r.Spec.OOMKilledContainers)
+	fmt.Fprintf(w, "Restarts:\t%d\n", r.Spec.TotalRestarts)
+	fmt.Fprintf(w, "Time To First OOM:\t%s\n", timeToFirstOOM)
+
+	fmt.Fprintln(w, "Containers:")
+	if len(r.Spec.Containers) == 0 {
+		fmt.Fprintln(w, "  <none>")
+	} else {
+		fmt.Fprintln(w, "  Pod\tContainer\tNode\tRestarts\tOOMKilled")
+		for _, c := range r.Spec.Containers {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%t\n", c.Pod, c.Container, c.Node, c.RestartCount, c.OOMKilled)
+		}
+	}
+
+	fmt.Fprintln(w, "Events:")
+	if len(r.Spec.Events) == 0 {
+		fmt.Fprintln(w, "  <none>")
+	} else {
+		fmt.Fprintln(w, "  Type\tReason\tObject\tCount\tMessage")
+		for _, e := range r.Spec.Events {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\n", e.Type, e.Reason, e.Object, e.Count, e.Message)
+		}
+	}
+}
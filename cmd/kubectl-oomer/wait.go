@@ -0,0 +1,112 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	oomclient "github.com/jdockerty/oom-operator/pkg/client"
+)
+
+// parseCondition parses the condition given to --for, one of completed,
+// oomkilled=N or phase=PHASE. oomkilled=N is met once N containers were last
+// terminated by the OOM killer, which counts containers rather than OOM kills.
+func parseCondition(s string) (oomclient.Condition, error) {
+	key, value, _ := strings.Cut(s, "=")
+	switch strings.ToLower(key) {
+	case "completed":
+		return oomclient.Completed, nil
+	case "oomkilled":
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("oomkilled must be a positive number, got %q", value)
+		}
+		return oomclient.ContainersOOMKilledAtLeast(int32(n)), nil
+	case "phase":
+		if value == "" {
+			return nil, fmt.Errorf("expected a phase, such as phase=OOMing")
+		}
+		return oomclient.InPhase(oomv1beta1.OomerPhase(value)), nil
+	default:
+		return nil, fmt.Errorf("unknown condition %q, expected completed, oomkilled=N or phase=PHASE", s)
+	}
+}
+
+// runWait waits until an Oomer meets a condition, writing its status once it does.
+func runWait(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("wait", flag.ContinueOnError)
+	k := addKubeFlags(fs, false)
+
+	forCondition := fs.String("for", "", "Condition to wait for: completed, oomkilled=N containers last terminated by the OOM killer, or phase=PHASE.")
+	timeout := fs.Duration("timeout", 5*time.Minute, "How long to wait for, 0 waits forever.")
+	interval := fs.Duration("interval", oomclient.DefaultPollInterval, "How often the Oomer is read.")
+	output := fs.String("output", "", "Write the status snapshot in the given format, only json is supported.")
+	fs.StringVar(output, "o", "", "Shorthand for --output.")
+
+	args, err := parse(fs, args)
+	if err != nil {
+		return err
+	}
+
+	name, err := exactlyOne(args, "name")
+	if err != nil {
+		return err
+	}
+
+	if *output != "" && *output != "json" {
+		return fmt.Errorf("unsupported output %q, only json is supported", *output)
+	}
+
+	condition, err := parseCondition(*forCondition)
+	if err != nil {
+		return err
+	}
+
+	c, namespace, err := e.client(k)
+	if err != nil {
+		return err
+	}
+
+	s, err := oomclient.NewFromClient(c).Oomers(namespace).Wait(ctx, name, *timeout, *interval, condition)
+	if err != nil {
+		if s != nil {
+			return fmt.Errorf("%w, last seen %s", err, summary(s))
+		}
+		return err
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(e.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	}
+
+	fmt.Fprintf(e.out, "oomer.jdocklabs.co.uk/%s condition met, %s\n", name, summary(s))
+	return nil
+}
+
+// summary describes the state of an Oomer in a snapshot.
+func summary(s *oomclient.Snapshot) string {
+	return fmt.Sprintf("%s with %d OOMKilled containers", status(s.Oomer), s.OOMKilledContainers)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+const (
+	// abortInterval is how often the abort criteria of an injecting Oomer are
+	// evaluated when nothing else triggers a reconcile.
+	abortInterval = 10 * time.Second
+
+	// abortedReason is given for the conditions of an aborted Oomer.
+	abortedReason = "Aborted"
+)
+
+// abortCriterionMet returns a message describing why injection must stop, this
+// is empty while every criterion holds. A criterion which cannot be evaluated is
+// logged rather than aborting, so that an unavailable Prometheus does not end a run.
+func (r *OomerReconciler) abortCriterionMet(ctx context.Context, o *oomv1beta1.Oomer) string {
+	log := log.FromContext(ctx)
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: verifyTimeout}
+	}
+
+	for _, criterion := range o.Spec.AbortWhen {
+		switch {
+		case criterion.Deployment != nil:
+			var d appsv1.Deployment
+			if err := r.Get(ctx, types.NamespacedName{Name: criterion.Deployment.Name, Namespace: o.ObjectMeta.Namespace}, &d); err != nil {
+				log.Error(err, "unable to evaluate abort criterion", "criterion", criterion.Name)
+				continue
+			}
+			if d.Status.AvailableReplicas < criterion.Deployment.MinAvailableReplicas {
+				return fmt.Sprintf("%s: deployment %s has %d available replicas, fewer than %d",
+					criterion.Name, d.ObjectMeta.Name, d.Status.AvailableReplicas, criterion.Deployment.MinAvailableReplicas)
+			}
+		case criterion.Prometheus != nil:
+			exceeded, err := checkPrometheus(ctx, httpClient, criterion.Prometheus)
+			if err != nil {
+				log.Error(err, "unable to evaluate abort criterion", "criterion", criterion.Name)
+				continue
+			}
+			if exceeded {
+				return fmt.Sprintf("%s: query %s returned a value greater than %g",
+					criterion.Name, criterion.Prometheus.Query, threshold(criterion.Prometheus))
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Oomer abort criteria", func() {
+
+	ctx := context.Background()
+
+	deployment := func(available int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "default"},
+			Status:     appsv1.DeploymentStatus{AvailableReplicas: available},
+		}
+	}
+
+	newOomer := func(criteria ...oomv1beta1.AbortCriterion) *oomv1beta1.Oomer {
+		return &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "guarded", Namespace: "default"},
+			Spec:       oomv1beta1.OomerSpec{AbortWhen: criteria},
+		}
+	}
+
+	deploymentCriterion := oomv1beta1.AbortCriterion{
+		Name:       "checkout-available",
+		Deployment: &oomv1beta1.DeploymentAbortCriterion{Name: "checkout", MinAvailableReplicas: 2},
+	}
+
+	It("Should abort when a deployment has too few available replicas", func() {
+		o := newOomer(deploymentCriterion)
+
+		r := &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(deployment(2)).Build()}
+		Expect(r.abortCriterionMet(ctx, o)).To(BeEmpty())
+
+		r = &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(deployment(1)).Build()}
+		Expect(r.abortCriterionMet(ctx, o)).To(ContainSubstring("checkout-available"))
+
+		By("polling the criteria")
+		Expect(scheduledRequeue(o, config.DefaultRequeueInterval, time.Now())).To(Equal(abortInterval))
+	})
+
+	It("Should abort when a query exceeds its threshold", func() {
+		server := prometheusServer("5")
+		defer server.Close()
+
+		threshold := resource.MustParse("10")
+		o := newOomer(oomv1beta1.AbortCriterion{
+			Name:       "error-rate",
+			Prometheus: &oomv1beta1.PrometheusCheck{URL: server.URL, Query: "ALERTS{alertname=\"OOMKilled\"}", GreaterThan: &threshold},
+		})
+		r := &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(), HTTPClient: server.Client()}
+		Expect(r.abortCriterionMet(ctx, o)).To(BeEmpty())
+
+		threshold = resource.MustParse("4.5")
+		Expect(r.abortCriterionMet(ctx, o)).To(ContainSubstring("greater than 4.5"))
+	})
+
+	It("Should not abort when a criterion cannot be evaluated", func() {
+		o := newOomer(deploymentCriterion, oomv1beta1.AbortCriterion{
+			Name:       "unreachable",
+			Prometheus: &oomv1beta1.PrometheusCheck{URL: "http://127.0.0.1:0", Query: "up"},
+		})
+		r := &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()}
+		Expect(r.abortCriterionMet(ctx, o)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Oomer Operator with abort criteria", func() {
+	const (
+		operatorName   = "test-aborted"
+		guardedName    = "test-guarded"
+		oomerNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	ctx := context.Background()
+
+	Context("When a guarded deployment has too few available replicas", func() {
+		It("Should not inject and mark the oomer as aborted", func() {
+			labels := map[string]string{"app": guardedName}
+			guarded := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: guardedName, Namespace: oomerNamespace},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "nginx"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, guarded)).Should(Succeed())
+
+			oom := &oomv1beta1.Oomer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      operatorName,
+					Namespace: oomerNamespace,
+				},
+				Spec: oomv1beta1.OomerSpec{
+					Replicas: 1,
+					AbortWhen: []oomv1beta1.AbortCriterion{{
+						Name:       "guarded-available",
+						Deployment: &oomv1beta1.DeploymentAbortCriterion{Name: guardedName, MinAvailableReplicas: 1},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+			lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
+			Eventually(func() bool {
+				o := &oomv1beta1.Oomer{}
+				if err := k8sClient.Get(ctx, lookupOomer, o); err != nil {
+					return false
+				}
+				return meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.AbortedCondition)
+			}, timeout, interval).Should(BeTrue())
+
+			err := k8sClient.Get(ctx, lookupOomer, &appsv1.Deployment{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, guarded)).Should(Succeed())
+		})
+	})
+})
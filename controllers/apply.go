@@ -0,0 +1,71 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// fieldManager owns the fields which the operator applies, other managers can
+// own the remaining fields of the same objects without conflicting.
+const fieldManager = "oom-operator"
+
+// applyOptions are given to every apply, the operator is authoritative for the
+// fields which it sets.
+var applyOptions = []client.PatchOption{client.FieldOwner(fieldManager), client.ForceOwnership}
+
+// apply server-side applies an object, the operator takes ownership of the fields
+// which are set on it and any it previously owned but are no longer set are removed.
+// The object must have its apiVersion and kind set.
+func (r *OomerReconciler) apply(ctx context.Context, obj client.Object) error {
+	return r.Patch(ctx, obj, client.Apply, applyOptions...)
+}
+
+// oomerApplyConfiguration returns an Oomer containing only its identity, so that
+// applying it only affects the fields which are added to it.
+func oomerApplyConfiguration(o *oomv1beta1.Oomer) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(oomv1beta1.GroupVersion.WithKind("Oomer"))
+	u.SetName(o.ObjectMeta.Name)
+	u.SetNamespace(o.ObjectMeta.Namespace)
+	return u
+}
+
+// applyStatus applies the status of an Oomer through its status subresource.
+func (r *OomerReconciler) applyStatus(ctx context.Context, o *oomv1beta1.Oomer) error {
+	status, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&o.Status)
+	if err != nil {
+		return err
+	}
+
+	u := oomerApplyConfiguration(o)
+	u.Object["status"] = status
+
+	opts := &client.SubResourcePatchOptions{}
+	opts.PatchOptions.ApplyOptions(applyOptions)
+	if err := r.Status().Patch(ctx, u, client.Apply, opts); err != nil {
+		return err
+	}
+	o.ObjectMeta.ResourceVersion = u.GetResourceVersion()
+	return nil
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// managedBy returns whether a field manager has applied fields of an object.
+func managedBy(obj client.Object, manager string) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == manager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+	return false
+}
+
+var _ = Describe("Oomer Operator with server-side apply", func() {
+	const (
+		operatorName   = "test-apply"
+		oomerNamespace = "default"
+		otherFinalizer = "example.com/keep"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	ctx := context.Background()
+	lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
+
+	It("Should share the deployment with other field managers", func() {
+		oom := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      operatorName,
+				Namespace: oomerNamespace,
+			},
+			Spec: oomv1beta1.OomerSpec{Replicas: 1},
+		}
+		Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+		d := &appsv1.Deployment{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, lookupOomer, d)
+		}, timeout, interval).Should(Succeed())
+		Expect(managedBy(d, fieldManager)).To(BeTrue())
+		Expect(metav1.IsControlledBy(d, oom)).To(BeTrue())
+
+		Eventually(func() bool {
+			if err := k8sClient.Get(ctx, lookupOomer, oom); err != nil {
+				return false
+			}
+			return managedBy(oom, fieldManager) && oom.Status.Phase == oomv1beta1.InjectingPhase
+		}, timeout, interval).Should(BeTrue())
+
+		By("changing an unrelated field as another manager")
+		patch := client.MergeFrom(d.DeepCopy())
+		d.Spec.Template.ObjectMeta.Annotations = map[string]string{"example.com/owner": "platform"}
+		Expect(k8sClient.Patch(ctx, d, patch, client.FieldOwner("platform-team"))).Should(Succeed())
+
+		By("scaling the oomer")
+		Expect(k8sClient.Get(ctx, lookupOomer, oom)).Should(Succeed())
+		oom.Spec.Replicas = 2
+		Expect(k8sClient.Update(ctx, oom)).Should(Succeed())
+
+		Eventually(func() int32 {
+			if err := k8sClient.Get(ctx, lookupOomer, d); err != nil {
+				return 0
+			}
+			return *d.Spec.Replicas
+		}, timeout, interval).Should(Equal(int32(2)))
+		Expect(d.Spec.Template.ObjectMeta.Annotations).To(HaveKeyWithValue("example.com/owner", "platform"))
+	})
+
+	It("Should only remove its own finalizer", func() {
+		oom := &oomv1beta1.Oomer{}
+		Expect(k8sClient.Get(ctx, lookupOomer, oom)).Should(Succeed())
+		Expect(ctrlutil.ContainsFinalizer(oom, oomerFinalizer)).To(BeTrue())
+
+		ctrlutil.AddFinalizer(oom, otherFinalizer)
+		Expect(k8sClient.Update(ctx, oom)).Should(Succeed())
+		Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+
+		Eventually(func() []string {
+			if err := k8sClient.Get(ctx, lookupOomer, oom); err != nil {
+				return nil
+			}
+			return oom.ObjectMeta.Finalizers
+		}, timeout, interval).Should(Equal([]string{otherFinalizer}))
+
+		ctrlutil.RemoveFinalizer(oom, otherFinalizer)
+		Expect(k8sClient.Update(ctx, oom)).Should(Succeed())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, lookupOomer, oom))
+		}, timeout, interval).Should(BeTrue())
+	})
+})
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	configv1alpha1 "github.com/jdockerty/oom-operator/api/config/v1alpha1"
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/config"
+)
+
+// benchmarkOomers is the number of Oomers created for every iteration.
+const benchmarkOomers = 100
+
+// BenchmarkReconcileOomers measures how long it takes to start injecting into
+// many Oomers at different concurrencies, along with the number of requests
+// made to the API server.
+//
+// It runs against its own envtest environment and is skipped when
+// KUBEBUILDER_ASSETS is not set, run it with
+//
+//	go test ./controllers -run '^$' -bench .
+func BenchmarkReconcileOomers(b *testing.B) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		b.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+	}
+	restConfig, err := env.Start()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer env.Stop() //nolint:errcheck
+
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		b.Fatal(err)
+	}
+	if err := oomv1beta1.AddToScheme(s); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, concurrency := range []int{1, 10} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			benchmarkReconcile(b, restConfig, s, concurrency)
+		})
+	}
+}
+
+func benchmarkReconcile(b *testing.B, restConfig *rest.Config, s *runtime.Scheme, concurrency int) {
+	var requests int64
+	counted := rest.CopyConfig(restConfig)
+	counted.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt64(&requests, 1)
+			return rt.RoundTrip(req)
+		})
+	}
+
+	operatorConfig := &configv1alpha1.OomOperatorConfig{}
+	operatorConfig.OomerController.MaxConcurrentReconciles = concurrency
+	config.SetDefaults(operatorConfig)
+
+	mgr, err := ctrl.NewManager(counted, ctrl.Options{
+		Scheme:             s,
+		MetricsBindAddress: "0",
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	err = (&OomerReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Config: config.NewStore(operatorConfig),
+	}).SetupWithManager(mgr)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mgr.Start(ctx) //nolint:errcheck
+
+	c, err := client.New(restConfig, client.Options{Scheme: s})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// Only the requests made while starting to inject are measured, not those
+	// made while cleaning up between iterations.
+	var measured int64
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("bench-%d-%d-%d", concurrency, i, time.Now().UnixNano()),
+		}}
+		if err := c.Create(ctx, ns); err != nil {
+			b.Fatal(err)
+		}
+		before := atomic.LoadInt64(&requests)
+		b.StartTimer()
+
+		for j := 0; j < benchmarkOomers; j++ {
+			o := &oomv1beta1.Oomer{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("oomer-%d", j), Namespace: ns.Name},
+				Spec:       oomv1beta1.OomerSpec{Replicas: 1},
+			}
+			if err := c.Create(ctx, o); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := waitForInjecting(ctx, c, ns.Name); err != nil {
+			b.Fatal(err)
+		}
+
+		// The Oomers of earlier iterations would otherwise still be reconciled,
+		// namespaces are not removed by envtest so they are deleted directly.
+		b.StopTimer()
+		measured += atomic.LoadInt64(&requests) - before
+		if err := deleteOomers(ctx, c, ns.Name); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(measured)/float64(b.N), "requests/op")
+}
+
+// waitForInjecting polls until every Oomer in the namespace is injecting.
+func waitForInjecting(ctx context.Context, c client.Client, namespace string) error {
+	deadline := time.Now().Add(2 * time.Minute)
+	for time.Now().Before(deadline) {
+		var oomers oomv1beta1.OomerList
+		if err := c.List(ctx, &oomers, client.InNamespace(namespace)); err != nil {
+			return err
+		}
+		injecting := 0
+		for _, o := range oomers.Items {
+			if meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.InjectingCondition) {
+				injecting++
+			}
+		}
+		if injecting == benchmarkOomers {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for %d Oomers in %s to inject", benchmarkOomers, namespace)
+}
+
+// deleteOomers deletes every Oomer in the namespace and polls until they are gone,
+// which requires their finalizers to be removed by the running manager.
+func deleteOomers(ctx context.Context, c client.Client, namespace string) error {
+	if err := c.DeleteAllOf(ctx, &oomv1beta1.Oomer{}, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(2 * time.Minute)
+	for time.Now().Before(deadline) {
+		var oomers oomv1beta1.OomerList
+		if err := c.List(ctx, &oomers, client.InNamespace(namespace)); err != nil {
+			return err
+		}
+		if len(oomers.Items) == 0 {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for the Oomers in %s to be deleted", namespace)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
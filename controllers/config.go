@@ -0,0 +1,73 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/util/workqueue"
+
+	configv1alpha1 "github.com/jdockerty/oom-operator/api/config/v1alpha1"
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+const (
+	// budgetInterval is how often an Oomer which is waiting for the injection
+	// budget checks whether it may start.
+	budgetInterval = 30 * time.Second
+
+	// budgetExceededReason is given while an Oomer waits for the injection budget.
+	budgetExceededReason = "BudgetExceeded"
+)
+
+// budgetExhausted returns whether an Oomer must wait to start injecting, as the
+// configured number of Oomers are already injecting. An Oomer which is already
+// injecting continues to do so. This is best effort, as Oomers which start at the
+// same time may not yet observe each other.
+func (r *OomerReconciler) budgetExhausted(ctx context.Context, o *oomv1beta1.Oomer, settings *configv1alpha1.OomOperatorConfig) (bool, error) {
+	max := settings.Budget.MaxInjecting
+	if max <= 0 || meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.InjectingCondition) {
+		return false, nil
+	}
+
+	var oomers oomv1beta1.OomerList
+	if err := r.List(ctx, &oomers); err != nil {
+		return false, err
+	}
+
+	var injecting int32
+	for _, other := range oomers.Items {
+		if other.ObjectMeta.UID != o.ObjectMeta.UID && meta.IsStatusConditionTrue(other.Status.Conditions, oomv1beta1.InjectingCondition) {
+			injecting++
+		}
+	}
+
+	return injecting >= max, nil
+}
+
+// rateLimiter builds the rate limiter of the Oomer controller, this has the same
+// form as the default of controller-runtime. It only delays failed reconciles and
+// rate limited requeues, requests to the API server are limited by the client.
+func rateLimiter(c configv1alpha1.RateLimiterConfig) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(c.BaseDelay.Duration, c.MaxDelay.Duration),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(c.QPS), int(c.Burst))},
+	)
+}
@@ -0,0 +1,66 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	configv1alpha1 "github.com/jdockerty/oom-operator/api/config/v1alpha1"
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Oomer operator config", func() {
+
+	ctx := context.Background()
+
+	It("Should back off failing Oomers using the configured rate limiter", func() {
+		c := &configv1alpha1.OomOperatorConfig{}
+		c.OomerController.RateLimiter.BaseDelay = &metav1.Duration{Duration: time.Second}
+		c.OomerController.RateLimiter.MaxDelay = &metav1.Duration{Duration: 3 * time.Second}
+		config.SetDefaults(c)
+
+		limiter := rateLimiter(c.OomerController.RateLimiter)
+		Expect(limiter.When("oomer")).To(Equal(time.Second))
+		Expect(limiter.When("oomer")).To(Equal(2 * time.Second))
+		Expect(limiter.When("oomer")).To(Equal(3 * time.Second))
+
+		limiter.Forget("oomer")
+		Expect(limiter.When("oomer")).To(Equal(time.Second))
+	})
+
+	It("Should wait for the injection budget", func() {
+		scheme := runtime.NewScheme()
+		utilruntime.Must(oomv1beta1.AddToScheme(scheme))
+
+		oomer := func(name string, injecting bool) *oomv1beta1.Oomer {
+			o := &oomv1beta1.Oomer{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID(name)}}
+			if injecting {
+				setCondition(o, oomv1beta1.InjectingCondition, metav1.ConditionTrue, startedReason, "")
+			}
+			return o
+		}
+
+		settings := &configv1alpha1.OomOperatorConfig{Budget: configv1alpha1.BudgetConfig{MaxInjecting: 2}}
+		waiting := oomer("waiting", false)
+
+		r := &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(oomer("first", true), waiting).Build()}
+		Expect(r.budgetExhausted(ctx, waiting, settings)).To(BeFalse())
+
+		r = &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(oomer("first", true), oomer("second", true), waiting).Build()}
+		Expect(r.budgetExhausted(ctx, waiting, settings)).To(BeTrue())
+
+		By("allowing those already injecting to continue")
+		Expect(r.budgetExhausted(ctx, oomer("second", true), settings)).To(BeFalse())
+
+		By("not limiting without a budget")
+		Expect(r.budgetExhausted(ctx, waiting, &configv1alpha1.OomOperatorConfig{})).To(BeFalse())
+	})
+})
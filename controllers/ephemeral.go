@@ -0,0 +1,145 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/allocator"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+)
+
+// requiresRemoval returns whether an Oomer expects its allocators to be removed
+// before it is deleted, through its scheduled duration, pausing or aborting. This
+// is not possible in the Ephemeral mode, as ephemeral containers keep running until
+// their pods are replaced.
+func requiresRemoval(o *oomv1beta1.Oomer) bool {
+	return (o.Spec.Schedule != nil && o.Spec.Schedule.Duration != nil) || o.Spec.Paused || len(o.Spec.AbortWhen) > 0
+}
+
+// hasEphemeralContainer reports whether a pod already has the named ephemeral container.
+func hasEphemeralContainer(pod *corev1.Pod, name string) bool {
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ephemeralContainer builds the allocator attached to a pod, targeting the first
+// selected container so that it shares its namespaces.
+func ephemeralContainer(o *oomv1beta1.Oomer, pod *corev1.Pod, image string) (corev1.EphemeralContainer, error) {
+	template := &corev1.PodTemplateSpec{ObjectMeta: pod.ObjectMeta, Spec: pod.Spec}
+	names, err := selectContainers(template, o.Spec.Target)
+	if err != nil {
+		return corev1.EphemeralContainer{}, err
+	}
+
+	env, err := allocator.Env(o)
+	if err != nil {
+		return corev1.EphemeralContainer{}, err
+	}
+
+	return corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                   allocator.ContainerName(o),
+			Image:                  image,
+			Env:                    env,
+			TerminationMessagePath: allocator.TerminationMessagePath,
+		},
+		TargetContainerName: names[0],
+	}, nil
+}
+
+// injectEphemeralContainers attaches an allocator to each running pod targeted by
+// an Oomer through the ephemeralcontainers subresource. When Replicas is non-zero,
+// it limits the number of pods which have an allocator attached.
+func (r *OomerReconciler) injectEphemeralContainers(ctx context.Context, o *oomv1beta1.Oomer) error {
+	log := log.FromContext(ctx)
+
+	selector, err := oomkill.TargetPodSelector(ctx, r, o)
+	if err != nil {
+		return err
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(o.ObjectMeta.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return err
+	}
+
+	limit := o.Spec.Replicas
+
+	name := allocator.ContainerName(o)
+	injected := int32(0)
+
+	// Pods which already have an allocator count towards the limit first, so that
+	// a different set of pods is not chosen on each reconcile.
+	for i := range pods.Items {
+		if hasEphemeralContainer(&pods.Items[i], name) {
+			injected++
+		}
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+
+		if limit > 0 && injected >= limit {
+			break
+		}
+
+		if pod.Status.Phase != corev1.PodRunning || hasEphemeralContainer(pod, name) {
+			continue
+		}
+
+		container, err := ephemeralContainer(o, pod, allocator.Image(o, r.Config))
+		if err != nil {
+			return err
+		}
+
+		// The listed pod may be stale, so the latest version is patched and a
+		// concurrent change to the pod is retried.
+		err = patchSubResourceWithRetry(ctx, r.Client, pod, "ephemeralcontainers", func() (bool, error) {
+			if hasEphemeralContainer(pod, name) {
+				return false, nil
+			}
+			pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, container)
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		log.Info("attached ephemeral allocator", "pod", pod.ObjectMeta.Name, "target", container.TargetContainerName)
+		injected++
+	}
+
+	if o.Status.ObservedReplicas != injected {
+		o.Status.ObservedReplicas = injected
+		if err := r.applyStatus(ctx, o); err != nil {
+			log.Error(err, "unable to update oomer status observed replicas", "ObservedReplicas", injected)
+			return err
+		}
+	}
+
+	return nil
+}
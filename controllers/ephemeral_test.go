@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/allocator"
+	"github.com/jdockerty/oom-operator/pkg/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Oomer ephemeral containers", func() {
+
+	It("Should target the selected container of the pod", func() {
+		o := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "live"},
+			Spec: oomv1beta1.OomerSpec{
+				Mode:   oomv1beta1.EphemeralMode,
+				Target: &oomv1beta1.TargetSpec{ContainerName: "sidecar"},
+			},
+		}
+		pod := &corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}},
+			},
+		}
+
+		c, err := ephemeralContainer(o, pod, allocator.Image(o, nil))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Name).Should(Equal("oomer-live"))
+		Expect(c.Image).Should(Equal(config.DefaultImage))
+		Expect(c.TargetContainerName).Should(Equal("sidecar"))
+	})
+
+	It("Should attach a single allocator to the latest version of each pod", func() {
+		ctx := context.Background()
+		o := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "live", Namespace: "default"},
+			Spec: oomv1beta1.OomerSpec{
+				Mode: oomv1beta1.EphemeralMode,
+				Target: &oomv1beta1.TargetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+			},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		r := snapshotReconciler(o, pod)
+
+		Expect(r.injectEphemeralContainers(ctx, o)).To(Succeed())
+		Expect(r.injectEphemeralContainers(ctx, o)).To(Succeed())
+
+		attached := &corev1.Pod{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(pod), attached)).To(Succeed())
+		Expect(attached.Spec.EphemeralContainers).To(HaveLen(1))
+		Expect(attached.Spec.EphemeralContainers[0].Name).To(Equal("oomer-live"))
+		Expect(o.Status.ObservedReplicas).To(Equal(int32(1)))
+	})
+
+	It("Should not be ready when the allocators are expected to be removed", func() {
+		o := &oomv1beta1.Oomer{Spec: oomv1beta1.OomerSpec{Mode: oomv1beta1.EphemeralMode}}
+		r := &OomerReconciler{}
+		_, message := r.validateSpec(o)
+		Expect(message).To(BeEmpty())
+
+		for _, mutate := range []func(*oomv1beta1.OomerSpec){
+			func(s *oomv1beta1.OomerSpec) {
+				s.Schedule = &oomv1beta1.ScheduleSpec{Duration: &metav1.Duration{Duration: time.Minute}}
+			},
+			func(s *oomv1beta1.OomerSpec) { s.Paused = true },
+			func(s *oomv1beta1.OomerSpec) { s.AbortWhen = []oomv1beta1.AbortCriterion{{}} },
+		} {
+			invalid := o.DeepCopy()
+			mutate(&invalid.Spec)
+
+			reason, message := r.validateSpec(invalid)
+			Expect(reason).To(Equal(invalidSpecReason))
+			Expect(message).To(ContainSubstring("cannot be removed"))
+		}
+
+		By("allowing a scheduled start time")
+		startAt := metav1.Now()
+		o.Spec.Schedule = &oomv1beta1.ScheduleSpec{StartAt: &startAt}
+		_, message = r.validateSpec(o)
+		Expect(message).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Oomer Operator in Ephemeral mode", func() {
+	const (
+		operatorName   = "test-ephemeral"
+		podName        = "test-ephemeral-pod"
+		oomerNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	var replicas int32 = 0
+
+	ctx := context.Background()
+	labels := map[string]string{"app": podName}
+
+	Context("When creating the object", func() {
+		It("Should attach an allocator to running pods", func() {
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      podName,
+					Namespace: oomerNamespace,
+					Labels:    labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: "example.com/app:v1"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).Should(Succeed())
+
+			pod.Status.Phase = corev1.PodRunning
+			Expect(k8sClient.Status().Update(ctx, pod)).Should(Succeed())
+
+			oom := &oomv1beta1.Oomer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      operatorName,
+					Namespace: oomerNamespace,
+				},
+				Spec: oomv1beta1.OomerSpec{
+					Replicas: replicas,
+					Mode:     oomv1beta1.EphemeralMode,
+					Target: &oomv1beta1.TargetSpec{
+						Selector: &metav1.LabelSelector{MatchLabels: labels},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+			lookupPod := types.NamespacedName{Name: podName, Namespace: oomerNamespace}
+			Eventually(func() []corev1.EphemeralContainer {
+				p := &corev1.Pod{}
+				if err := k8sClient.Get(ctx, lookupPod, p); err != nil {
+					return nil
+				}
+				return p.Spec.EphemeralContainers
+			}, timeout, interval).Should(HaveLen(1))
+
+			Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+		})
+	})
+})
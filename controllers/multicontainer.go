@@ -0,0 +1,184 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/allocator"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+)
+
+const defaultOvercommitPercent int32 = 150
+
+// multiContainerSpec returns the MultiContainer configuration of the Oomer,
+// populating any defaults which have not been set.
+func multiContainerSpec(o *oomv1beta1.Oomer) (*oomv1beta1.MultiContainerSpec, error) {
+	if o.Spec.MultiContainer == nil {
+		return nil, fmt.Errorf("multi container mode requires multiContainer to be set")
+	}
+
+	mc := o.Spec.MultiContainer.DeepCopy()
+	if len(mc.Containers) < 2 {
+		return nil, fmt.Errorf("multi container mode requires at least 2 containers, got %d", len(mc.Containers))
+	}
+
+	if mc.OvercommitPercent == 0 {
+		mc.OvercommitPercent = defaultOvercommitPercent
+	}
+
+	names := make(map[string]bool, len(mc.Containers))
+	for i := range mc.Containers {
+		c := &mc.Containers[i]
+		if names[c.Name] {
+			return nil, fmt.Errorf("container %s is listed more than once", c.Name)
+		}
+		names[c.Name] = true
+
+		if c.Weight == 0 {
+			c.Weight = 1
+		}
+	}
+
+	return mc, nil
+}
+
+// multiContainerAllocators builds the allocator containers of a pod in the
+// MultiContainer mode. The overcommitted allocation is divided between the
+// containers by their weights. Each container is limited to the memory limit of
+// the pod, a container may not exceed it, so that the limit of the pod rather
+// than of the containers is reached once their combined allocation exceeds it.
+func multiContainerAllocators(mc *oomv1beta1.MultiContainerSpec, image string, env []corev1.EnvVar) []corev1.Container {
+	var weights int64
+	for _, c := range mc.Containers {
+		weights += int64(c.Weight)
+	}
+
+	allocation := mc.MemoryLimit.Value() * int64(mc.OvercommitPercent) / 100
+
+	containers := make([]corev1.Container, 0, len(mc.Containers))
+	for _, c := range mc.Containers {
+		bytes := allocation * int64(c.Weight) / weights
+
+		containers = append(containers, corev1.Container{
+			Name:                   c.Name,
+			Image:                  image,
+			TerminationMessagePath: allocator.TerminationMessagePath,
+			Env:                    append([]corev1.EnvVar{{Name: allocateBytesEnv, Value: strconv.FormatInt(bytes, 10)}}, env...),
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: mc.MemoryLimit.DeepCopy()},
+			},
+		})
+	}
+
+	return containers
+}
+
+// errPodLevelResourcesUnsupported is returned when the API server drops the memory
+// limit of the pod, as pod-level resources are not enabled in the cluster.
+var errPodLevelResourcesUnsupported = errors.New("the MultiContainer mode requires pod-level resources, which are not enabled in the cluster")
+
+// podMemoryLimitPath is the path of the pod-level memory limit in a Deployment,
+// the vendored PodSpec predates pod-level resources so this is set unstructured.
+var podMemoryLimitPath = []string{"spec", "template", "spec", "resources", "limits", "memory"}
+
+// withPodMemoryLimit returns a Deployment to apply whose pods are limited to the
+// given memory in total.
+func withPodMemoryLimit(d *appsv1.Deployment, limit resource.Quantity) (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(d)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &unstructured.Unstructured{Object: obj}
+	if err := unstructured.SetNestedField(u.Object, limit.String(), podMemoryLimitPath...); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// hasPodMemoryLimit returns whether the pods of an applied Deployment are limited
+// in total. The API server drops the limit when pod-level resources are disabled.
+func hasPodMemoryLimit(u *unstructured.Unstructured) bool {
+	_, found, _ := unstructured.NestedString(u.Object, podMemoryLimitPath...)
+	return found
+}
+
+// containerOOMKills attributes the OOM kills in the given pods to each of the named
+// containers, in the order in which they are named.
+func containerOOMKills(pods []corev1.Pod, names []string) []oomv1beta1.ContainerOOMKills {
+	kills := make([]oomv1beta1.ContainerOOMKills, len(names))
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		kills[i].Name = name
+		index[name] = i
+	}
+
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			i, ok := index[status.Name]
+			if !ok {
+				continue
+			}
+			if oomkill.KilledAt(status) != nil {
+				kills[i].OOMKilled++
+			}
+			kills[i].Restarts += status.RestartCount
+		}
+	}
+
+	return kills
+}
+
+// attributeOOMKills records the OOM kills of each container in the MultiContainer
+// mode on the status of the Oomer. It reports whether the status was changed.
+func (r *OomerReconciler) attributeOOMKills(ctx context.Context, o *oomv1beta1.Oomer) (bool, error) {
+	selector, err := oomkill.PodSelector(ctx, r, o)
+	if err != nil {
+		return false, err
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(o.ObjectMeta.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return false, err
+	}
+
+	names := make([]string, 0, len(o.Spec.MultiContainer.Containers))
+	for _, c := range o.Spec.MultiContainer.Containers {
+		names = append(names, c.Name)
+	}
+
+	kills := containerOOMKills(pods.Items, names)
+	if equality.Semantic.DeepEqual(o.Status.ContainerOOMKills, kills) {
+		return false, nil
+	}
+
+	o.Status.ContainerOOMKills = kills
+	return true, nil
+}
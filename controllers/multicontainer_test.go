@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/allocator"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// podResourcesDroppingClient drops the pod-level resources of applied objects, as
+// an API server without pod-level resources does.
+type podResourcesDroppingClient struct {
+	client.Client
+}
+
+func (c podResourcesDroppingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		unstructured.RemoveNestedField(u.Object, "spec", "template", "spec", "resources")
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+var _ = Describe("Oomer multi-container", func() {
+
+	ctx := context.Background()
+
+	var o *oomv1beta1.Oomer
+
+	BeforeEach(func() {
+		o = &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-limit", Namespace: "default"},
+			Spec: oomv1beta1.OomerSpec{
+				Mode: oomv1beta1.MultiContainerMode,
+				MultiContainer: &oomv1beta1.MultiContainerSpec{
+					MemoryLimit:       resource.MustParse("300Mi"),
+					OvercommitPercent: 120,
+					Containers: []oomv1beta1.AllocatorContainer{
+						{Name: "greedy", Weight: 2},
+						{Name: "modest"},
+					},
+				},
+			},
+		}
+	})
+
+	It("Should split the overcommitted allocation by weight", func() {
+		mc, err := multiContainerSpec(o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mc.Containers[1].Weight).To(Equal(int32(1)))
+
+		containers := multiContainerAllocators(mc, "oomer:test", []corev1.EnvVar{{Name: "OOMER_HOLD_FOR", Value: "1s"}})
+		Expect(containers).To(HaveLen(2))
+
+		greedy, modest := containers[0], containers[1]
+		Expect(greedy.Name).To(Equal("greedy"))
+		Expect(greedy.Env[0]).To(Equal(corev1.EnvVar{Name: allocateBytesEnv, Value: "251658240"}))
+		Expect(greedy.Env).To(ContainElement(corev1.EnvVar{Name: "OOMER_HOLD_FOR", Value: "1s"}))
+
+		Expect(modest.Env[0]).To(Equal(corev1.EnvVar{Name: allocateBytesEnv, Value: "125829120"}))
+		Expect(modest.TerminationMessagePath).To(Equal(allocator.TerminationMessagePath))
+
+		By("limiting each container to the pod limit so only their combined allocation exceeds it")
+		for _, c := range containers {
+			Expect(c.Resources.Limits.Memory().String()).To(Equal("300Mi"))
+		}
+	})
+
+	It("Should limit the memory of the pod as a whole", func() {
+		d := &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-limit", Namespace: "default"},
+		}
+		u, err := withPodMemoryLimit(d, o.Spec.MultiContainer.MemoryLimit)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u.GetName()).To(Equal("pod-limit"))
+		Expect(hasPodMemoryLimit(u)).To(BeTrue())
+
+		limit, _, err := unstructured.NestedString(u.Object, "spec", "template", "spec", "resources", "limits", "memory")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limit).To(Equal("300Mi"))
+
+		By("detecting a cluster which dropped the limit")
+		unstructured.RemoveNestedField(u.Object, "spec", "template", "spec", "resources")
+		Expect(hasPodMemoryLimit(u)).To(BeFalse())
+	})
+
+	It("Should report the mode as unsupported without creating a deployment when pod-level resources are dropped", func() {
+		o.ObjectMeta.Finalizers = []string{oomerFinalizer}
+		o.Spec.Replicas = 1
+		r := snapshotReconciler(o)
+		r.Client = podResourcesDroppingClient{r.Client}
+		key := client.ObjectKeyFromObject(o)
+
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+
+		err = r.Get(ctx, key, &appsv1.Deployment{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		unsupported := &oomv1beta1.Oomer{}
+		Expect(r.Get(ctx, key, unsupported)).To(Succeed())
+		ready := meta.FindStatusCondition(unsupported.Status.Conditions, oomv1beta1.ReadyCondition)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(unsupportedReason))
+		Expect(meta.IsStatusConditionTrue(unsupported.Status.Conditions, oomv1beta1.InjectingCondition)).To(BeFalse())
+
+		By("not applying it again until the Oomer changes")
+		result, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+
+		unchanged := &oomv1beta1.Oomer{}
+		Expect(r.Get(ctx, key, unchanged)).To(Succeed())
+		Expect(unchanged.ObjectMeta.ResourceVersion).To(Equal(unsupported.ObjectMeta.ResourceVersion))
+	})
+
+	It("Should refuse fewer than two uniquely named containers", func() {
+		o.Spec.MultiContainer.Containers = o.Spec.MultiContainer.Containers[:1]
+		_, err := multiContainerSpec(o)
+		Expect(err).To(MatchError(ContainSubstring("at least 2 containers")))
+
+		o.Spec.MultiContainer.Containers = []oomv1beta1.AllocatorContainer{{Name: "a"}, {Name: "a"}}
+		_, err = multiContainerSpec(o)
+		Expect(err).To(MatchError(ContainSubstring("listed more than once")))
+
+		o.Spec.MultiContainer = nil
+		_, err = multiContainerSpec(o)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should attribute OOM kills to the containers of the pods", func() {
+		oomKilled := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: oomKilledReason}}
+		pods := []corev1.Pod{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default", Labels: oomkill.PodLabels(o)},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "greedy", LastTerminationState: oomKilled, RestartCount: 3},
+					{Name: "modest"},
+				}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default", Labels: oomkill.PodLabels(o)},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "greedy", State: oomKilled, RestartCount: 1},
+					{Name: "modest", RestartCount: 1},
+				}},
+			},
+		}
+
+		r := snapshotReconciler(&pods[0], &pods[1])
+		changed, err := r.attributeOOMKills(ctx, o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeTrue())
+		Expect(o.Status.ContainerOOMKills).To(Equal([]oomv1beta1.ContainerOOMKills{
+			{Name: "greedy", OOMKilled: 2, Restarts: 4},
+			{Name: "modest", OOMKilled: 0, Restarts: 1},
+		}))
+
+		changed, err = r.attributeOOMKills(ctx, o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(BeFalse())
+	})
+})
+
+var _ = Describe("Oomer Operator in MultiContainer mode", func() {
+	const (
+		operatorName   = "test-multi-container"
+		oomerNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	ctx := context.Background()
+
+	Context("When creating the object", func() {
+		It("Should limit the pods of the deployment as a whole", func() {
+			// The API server of envtest is 1.26, which predates pod-level resources
+			// and drops the limit, so the mode is only reported as unsupported.
+			Skip("pod-level resources require Kubernetes 1.34, envtest runs 1.26")
+
+			oom := &oomv1beta1.Oomer{
+				ObjectMeta: metav1.ObjectMeta{Name: operatorName, Namespace: oomerNamespace},
+				Spec: oomv1beta1.OomerSpec{
+					Replicas: 1,
+					Mode:     oomv1beta1.MultiContainerMode,
+					MultiContainer: &oomv1beta1.MultiContainerSpec{
+						MemoryLimit: resource.MustParse("300Mi"),
+						Containers:  []oomv1beta1.AllocatorContainer{{Name: "greedy"}, {Name: "modest"}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+			d := &unstructured.Unstructured{}
+			d.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(oom), d); err != nil {
+					return false
+				}
+				return hasPodMemoryLimit(d)
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,302 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/allocator"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+)
+
+const (
+	// allocateBytesEnv is passed to the allocator container and contains the
+	// number of bytes which it should consume.
+	allocateBytesEnv = "OOMER_ALLOCATE_BYTES"
+
+	defaultAllocatablePercent int32 = 95
+)
+
+// daemonSetTolerations are the taints which the DaemonSet controller tolerates on
+// the pods it creates. Nodes under MemoryPressure, which the allocators cause, are
+// still selected so that allocators are not removed from them.
+var daemonSetTolerations = map[string]bool{
+	corev1.TaintNodeNotReady:       true,
+	corev1.TaintNodeUnreachable:    true,
+	corev1.TaintNodeDiskPressure:   true,
+	corev1.TaintNodeMemoryPressure: true,
+	corev1.TaintNodePIDPressure:    true,
+}
+
+// schedulable returns whether allocators can be placed onto a node, this is not
+// the case when it is cordoned or has a taint which the allocators do not tolerate.
+func schedulable(n *corev1.Node) bool {
+	if n.Spec.Unschedulable {
+		return false
+	}
+
+	for _, t := range n.Spec.Taints {
+		if t.Effect == corev1.TaintEffectPreferNoSchedule || daemonSetTolerations[t.Key] {
+			continue
+		}
+		return false
+	}
+
+	return true
+}
+
+// selectNodes returns the nodes which NodePressure allocators should be placed onto,
+// these must match both the node selector and list of node names, when provided,
+// and be schedulable.
+func (r *OomerReconciler) selectNodes(ctx context.Context, np *oomv1beta1.NodePressureSpec) ([]corev1.Node, error) {
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes, client.MatchingLabels(np.NodeSelector)); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(np.NodeNames))
+	for _, name := range np.NodeNames {
+		names[name] = true
+	}
+
+	var selected []corev1.Node
+	for i := range nodes.Items {
+		n := &nodes.Items[i]
+		if len(names) > 0 && !names[n.ObjectMeta.Name] {
+			continue
+		}
+		if schedulable(n) {
+			selected = append(selected, *n)
+		}
+	}
+
+	return selected, nil
+}
+
+// allocationBytes calculates the number of bytes each allocator should consume.
+// As a DaemonSet shares a single pod template, the smallest node is used so that
+// no allocator asks for more memory than its node can provide. Nodes which have
+// not reported their allocatable memory are skipped.
+func allocationBytes(nodes []corev1.Node, percent int32) int64 {
+	var smallest int64
+	for _, n := range nodes {
+		allocatable := n.Status.Allocatable.Memory().Value()
+		if allocatable == 0 {
+			continue
+		}
+		if smallest == 0 || allocatable < smallest {
+			smallest = allocatable
+		}
+	}
+
+	return smallest * int64(percent) / 100
+}
+
+// nodePressureSpec returns the NodePressure configuration of the Oomer, populating
+// any defaults which have not been set.
+func nodePressureSpec(o *oomv1beta1.Oomer) *oomv1beta1.NodePressureSpec {
+	np := &oomv1beta1.NodePressureSpec{}
+	if o.Spec.NodePressure != nil {
+		np = o.Spec.NodePressure.DeepCopy()
+	}
+
+	if np.AllocatablePercent == 0 {
+		np.AllocatablePercent = defaultAllocatablePercent
+	}
+
+	return np
+}
+
+// daemonSetPodSpec builds the pod specification used by the NodePressure allocators,
+// these are only placed onto the selected nodes.
+func daemonSetPodSpec(o *oomv1beta1.Oomer, np *oomv1beta1.NodePressureSpec, nodes []corev1.Node, bytes int64, image string) corev1.PodSpec {
+	spec := corev1.PodSpec{
+		NodeSelector:      np.NodeSelector,
+		PriorityClassName: np.PriorityClassName,
+		Containers: []corev1.Container{
+			{
+				Name:                   "oomer",
+				Image:                  image,
+				TerminationMessagePath: allocator.TerminationMessagePath,
+				Env: []corev1.EnvVar{
+					{Name: allocateBytesEnv, Value: strconv.FormatInt(bytes, 10)},
+				},
+			},
+		},
+	}
+
+	if np.MemoryRequest != nil {
+		spec.Containers[0].Resources.Requests = corev1.ResourceList{
+			corev1.ResourceMemory: *np.MemoryRequest,
+		}
+	}
+
+	names := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		names = append(names, n.ObjectMeta.Name)
+	}
+
+	if len(names) > 0 {
+		spec.Affinity = &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{
+							MatchFields: []corev1.NodeSelectorRequirement{
+								{
+									Key:      "metadata.name",
+									Operator: corev1.NodeSelectorOpIn,
+									Values:   names,
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	return spec
+}
+
+// applyDaemonSet applies the allocator DaemonSet for an Oomer in the NodePressure
+// mode, keeping the allocation in line with the selected nodes.
+func (r *OomerReconciler) applyDaemonSet(ctx context.Context, o *oomv1beta1.Oomer) error {
+	log := log.FromContext(ctx)
+
+	np := nodePressureSpec(o)
+	nodes, err := r.selectNodes(ctx, np)
+	if err != nil {
+		return err
+	}
+
+	if len(nodes) == 0 {
+		log.Info("no nodes match the node pressure selection, skipping allocators")
+		return nil
+	}
+
+	env, err := allocator.Env(o)
+	if err != nil {
+		return err
+	}
+
+	bytes := allocationBytes(nodes, np.AllocatablePercent)
+	if bytes == 0 {
+		log.Info("no selected node reports allocatable memory, skipping allocators")
+		return nil
+	}
+
+	podSpec := daemonSetPodSpec(o, np, nodes, bytes, allocator.Image(o, r.Config))
+	podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, env...)
+	if port := allocator.MetricsPort(o.Spec.Profile); port > 0 {
+		allocator.ExposeMetrics(&podSpec.Containers[0], port)
+	}
+
+	namespacedName := types.NamespacedName{
+		Name:      o.ObjectMeta.Name,
+		Namespace: o.ObjectMeta.Namespace,
+	}
+
+	labels := oomkill.PodLabels(o)
+
+	// The selector of a DaemonSet is immutable, so an existing DaemonSet keeps
+	// the labels it was created with.
+	existing := &appsv1.DaemonSet{}
+	if err := r.Get(ctx, namespacedName, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		log.Info("underlying daemonset not found, creating...", "nodes", len(nodes), "bytes", bytes)
+	} else if existing.Spec.Selector != nil {
+		labels = existing.Spec.Selector.MatchLabels
+	}
+
+	ds := &appsv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "DaemonSet",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      o.ObjectMeta.Name,
+			Namespace: o.ObjectMeta.Namespace,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: podSpec,
+			},
+		},
+	}
+
+	if err := ctrl.SetControllerReference(o, ds, r.Scheme); err != nil {
+		return err
+	}
+
+	if err := r.apply(ctx, ds); err != nil {
+		return err
+	}
+
+	observed := int32(len(nodes))
+	if o.Status.ObservedReplicas != observed {
+		o.Status.ObservedReplicas = observed
+		if err := r.applyStatus(ctx, o); err != nil {
+			log.Error(err, "unable to update oomer status observed replicas", "ObservedReplicas", observed)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteDaemonSet is used to delete the underlying DaemonSet object, this is the
+// counterpart of deleteDeployment for the NodePressure mode.
+// A DaemonSet may never have been created when no nodes were selected, so a
+// missing object is not treated as an error.
+func (r *OomerReconciler) deleteDaemonSet(ctx context.Context, o *oomv1beta1.Oomer) error {
+	log := log.FromContext(ctx)
+
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      o.ObjectMeta.Name,
+			Namespace: o.ObjectMeta.Namespace,
+		},
+	}
+
+	if err := r.Delete(ctx, ds); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	log.Info("daemonset deleted", "name", ds.ObjectMeta.Name, "namespace", ds.ObjectMeta.Namespace)
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+var _ = Describe("Oomer node pressure", func() {
+
+	node := func(name, memory string, mutate func(*corev1.Node)) *corev1.Node {
+		n := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"pool": "oom"}},
+		}
+		if memory != "" {
+			n.Status.Allocatable = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(memory)}
+		}
+		if mutate != nil {
+			mutate(n)
+		}
+		return n
+	}
+
+	taint := func(key string, effect corev1.TaintEffect) func(*corev1.Node) {
+		return func(n *corev1.Node) {
+			n.Spec.Taints = append(n.Spec.Taints, corev1.Taint{Key: key, Effect: effect})
+		}
+	}
+
+	names := func(nodes []corev1.Node) []string {
+		var names []string
+		for _, n := range nodes {
+			names = append(names, n.ObjectMeta.Name)
+		}
+		return names
+	}
+
+	DescribeTable("selecting nodes",
+		func(np *oomv1beta1.NodePressureSpec, n *corev1.Node, selected bool) {
+			r := snapshotReconciler(n)
+
+			nodes, err := r.selectNodes(context.Background(), np)
+			Expect(err).NotTo(HaveOccurred())
+			if selected {
+				Expect(names(nodes)).To(ConsistOf(n.ObjectMeta.Name))
+			} else {
+				Expect(nodes).To(BeEmpty())
+			}
+		},
+		Entry("matching the node selector", &oomv1beta1.NodePressureSpec{NodeSelector: map[string]string{"pool": "oom"}},
+			node("a", "1Gi", nil), true),
+		Entry("not matching the node selector", &oomv1beta1.NodePressureSpec{NodeSelector: map[string]string{"pool": "other"}},
+			node("a", "1Gi", nil), false),
+		Entry("listed by name", &oomv1beta1.NodePressureSpec{NodeNames: []string{"a"}},
+			node("a", "1Gi", nil), true),
+		Entry("not listed by name", &oomv1beta1.NodePressureSpec{NodeNames: []string{"b"}},
+			node("a", "1Gi", nil), false),
+		Entry("cordoned", &oomv1beta1.NodePressureSpec{},
+			node("a", "1Gi", func(n *corev1.Node) { n.Spec.Unschedulable = true }), false),
+		Entry("with a NoSchedule taint", &oomv1beta1.NodePressureSpec{},
+			node("a", "1Gi", taint("dedicated", corev1.TaintEffectNoSchedule)), false),
+		Entry("with a NoExecute taint", &oomv1beta1.NodePressureSpec{},
+			node("a", "1Gi", taint("dedicated", corev1.TaintEffectNoExecute)), false),
+		Entry("with a PreferNoSchedule taint", &oomv1beta1.NodePressureSpec{},
+			node("a", "1Gi", taint("dedicated", corev1.TaintEffectPreferNoSchedule)), true),
+		Entry("under MemoryPressure", &oomv1beta1.NodePressureSpec{},
+			node("a", "1Gi", taint(corev1.TaintNodeMemoryPressure, corev1.TaintEffectNoSchedule)), true),
+	)
+
+	DescribeTable("calculating the allocation",
+		func(nodes []*corev1.Node, percent int32, expected int64) {
+			var items []corev1.Node
+			for _, n := range nodes {
+				items = append(items, *n)
+			}
+			Expect(allocationBytes(items, percent)).To(Equal(expected))
+		},
+		Entry("no nodes", nil, int32(50), int64(0)),
+		Entry("a single node", []*corev1.Node{node("a", "2Gi", nil)}, int32(50), int64(1<<30)),
+		Entry("the smallest node", []*corev1.Node{node("a", "4Gi", nil), node("b", "2Gi", nil)}, int32(50), int64(1<<30)),
+		Entry("skipping nodes without allocatable memory", []*corev1.Node{node("a", "", nil), node("b", "2Gi", nil)}, int32(100), int64(2<<30)),
+		Entry("only nodes without allocatable memory", []*corev1.Node{node("a", "", nil)}, int32(100), int64(0)),
+	)
+
+	It("Should pin the allocators to the selected nodes and set the memory request", func() {
+		request := resource.MustParse("64Mi")
+		np := &oomv1beta1.NodePressureSpec{MemoryRequest: &request}
+		nodes := []corev1.Node{*node("a", "1Gi", nil), *node("b", "1Gi", nil)}
+
+		spec := daemonSetPodSpec(&oomv1beta1.Oomer{}, np, nodes, 1<<20, "oomer")
+		Expect(spec.Containers[0].Resources.Requests).To(HaveKeyWithValue(corev1.ResourceMemory, request))
+
+		terms := spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		Expect(terms).To(HaveLen(1))
+		Expect(terms[0].MatchFields[0].Values).To(ConsistOf("a", "b"))
+	})
+
+	It("Should leave the allocators BestEffort without a memory request", func() {
+		spec := daemonSetPodSpec(&oomv1beta1.Oomer{}, &oomv1beta1.NodePressureSpec{}, []corev1.Node{*node("a", "1Gi", nil)}, 1<<20, "oomer")
+		Expect(spec.Containers[0].Resources.Requests).To(BeEmpty())
+	})
+})
@@ -0,0 +1,348 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+const (
+	// signatureHeader holds the HMAC-SHA256 signature of a notification body.
+	signatureHeader = "X-Oomer-Signature"
+
+	// defaultNotifyRetries is how many times a failed notification is retried
+	// when the Oomer does not specify it.
+	defaultNotifyRetries = 3
+
+	// notifyRetryDelay is the delay before the first retry, this doubles with
+	// each attempt up to notifyRetryCap.
+	notifyRetryDelay = 10 * time.Second
+
+	// notifyRetryCap is the longest delay between retries, these happen on a
+	// later reconcile so that a failing webhook does not hold up the run.
+	notifyRetryCap = 5 * time.Minute
+
+	// notifyInterval is how often pods are checked for the first OOM while it has
+	// not been notified.
+	notifyInterval = 30 * time.Second
+)
+
+// notification is the payload sent to webhooks, it is also the data available to
+// their templates.
+type notification struct {
+	Event     oomv1beta1.NotifyEvent `json:"event"`
+	Oomer     string                 `json:"oomer"`
+	Namespace string                 `json:"namespace"`
+	Mode      oomv1beta1.OomerMode   `json:"mode,omitempty"`
+	Message   string                 `json:"message"`
+	Time      time.Time              `json:"time"`
+}
+
+// notified returns whether a notification has been sent for an event, or it is
+// not required as the Oomer does not notify.
+func notified(o *oomv1beta1.Oomer, event oomv1beta1.NotifyEvent) bool {
+	if o.Spec.Notify == nil {
+		return true
+	}
+	for _, n := range o.Status.Notifications {
+		if n.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// awaitingFirstOOM returns whether pods are checked for the first OOM, so that it
+// is notified, and the Oomer becomes OOMing, shortly after it happens.
+func awaitingFirstOOM(o *oomv1beta1.Oomer) bool {
+	return o.Status.Phase == oomv1beta1.InjectingPhase || !notified(o, oomv1beta1.OOMKilledNotifyEvent)
+}
+
+// notifyProgress sends the notifications which are due while an Oomer is injecting,
+// and retries those which are pending, returning whether any were recorded in its
+// status.
+func (r *OomerReconciler) notifyProgress(ctx context.Context, o *oomv1beta1.Oomer, now time.Time) bool {
+	log := log.FromContext(ctx)
+
+	changed := r.retryNotifications(ctx, o, now)
+	changed = r.notify(ctx, o, oomv1beta1.StartedNotifyEvent, "OOM injection has started", now) || changed
+
+	if !notified(o, oomv1beta1.OOMKilledNotifyEvent) {
+		if count, err := r.countOOMKilled(ctx, o); err != nil {
+			log.Error(err, "unable to count OOMKilled containers for notification")
+		} else if count > 0 {
+			message := fmt.Sprintf("Observed %d OOMKilled containers", count)
+			changed = r.notify(ctx, o, oomv1beta1.OOMKilledNotifyEvent, message, now) || changed
+		}
+	}
+
+	if c := meta.FindStatusCondition(o.Status.Conditions, oomv1beta1.VerificationFailedCondition); c != nil && c.Status == metav1.ConditionTrue {
+		changed = r.notify(ctx, o, oomv1beta1.FailedNotifyEvent, c.Message, now) || changed
+	}
+
+	return changed
+}
+
+// notify records a notification for an event in the status of an Oomer and sends
+// it to each webhook which subscribes to it, returning whether it was recorded.
+// An event is only recorded once, the webhooks which it could not be delivered to
+// are retried by retryNotifications on later reconciles.
+func (r *OomerReconciler) notify(ctx context.Context, o *oomv1beta1.Oomer, event oomv1beta1.NotifyEvent, message string, now time.Time) bool {
+	if notified(o, event) {
+		return false
+	}
+
+	record := oomv1beta1.NotificationRecord{
+		Event:   event,
+		Time:    metav1.NewTime(now),
+		Message: message,
+	}
+	for _, target := range o.Spec.Notify.Webhooks {
+		if subscribed(target, event) {
+			record.Pending = append(record.Pending, target.URL)
+		}
+	}
+
+	r.deliver(ctx, o, &record, now)
+	o.Status.Notifications = append(o.Status.Notifications, record)
+
+	return true
+}
+
+// retryNotifications sends the pending notifications of an Oomer which are due to
+// be retried, returning whether any were attempted.
+func (r *OomerReconciler) retryNotifications(ctx context.Context, o *oomv1beta1.Oomer, now time.Time) bool {
+	changed := false
+	for i := range o.Status.Notifications {
+		record := &o.Status.Notifications[i]
+		if len(record.Pending) > 0 && !now.Before(nextNotifyAttempt(record)) {
+			r.deliver(ctx, o, record, now)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// notifyRequeue returns how long until the next pending notification of an Oomer
+// is retried, this is zero when none are pending.
+func notifyRequeue(o *oomv1beta1.Oomer, now time.Time) time.Duration {
+	var after time.Duration
+	for i := range o.Status.Notifications {
+		record := &o.Status.Notifications[i]
+		if len(record.Pending) == 0 {
+			continue
+		}
+		wait := nextNotifyAttempt(record).Sub(now)
+		if wait < time.Second {
+			wait = time.Second
+		}
+		if after == 0 || wait < after {
+			after = wait
+		}
+	}
+	return after
+}
+
+// nextNotifyAttempt returns when a pending notification is next retried.
+func nextNotifyAttempt(record *oomv1beta1.NotificationRecord) time.Time {
+	if record.LastAttemptTime == nil {
+		return time.Time{}
+	}
+	return record.LastAttemptTime.Add(notifyBackoff(record.Attempts - 1))
+}
+
+// deliver sends a notification to each of its pending webhooks once, those which
+// fail remain pending until the retries of the Oomer are exhausted. The notification
+// is only marked as delivered once every webhook has received it.
+func (r *OomerReconciler) deliver(ctx context.Context, o *oomv1beta1.Oomer, record *oomv1beta1.NotificationRecord, now time.Time) {
+	log := log.FromContext(ctx)
+
+	n := notification{
+		Event:     record.Event,
+		Oomer:     o.ObjectMeta.Name,
+		Namespace: o.ObjectMeta.Namespace,
+		Mode:      o.Spec.Mode,
+		Message:   record.Message,
+		Time:      record.Time.UTC(),
+	}
+
+	var pending, errs []string
+	for _, url := range record.Pending {
+		// Webhooks which have since been removed from the Oomer are not retried.
+		target, ok := webhook(o, url)
+		if !ok {
+			continue
+		}
+		if err := r.sendWebhook(ctx, o, target, n); err != nil {
+			log.Error(err, "unable to send notification", "event", record.Event, "url", url)
+			pending = append(pending, url)
+			errs = append(errs, fmt.Sprintf("%s: %s", url, err))
+		}
+	}
+
+	attempted := metav1.NewTime(now)
+	record.Attempts++
+	record.LastAttemptTime = &attempted
+	record.Pending = pending
+	record.Error = strings.Join(errs, "; ")
+
+	switch {
+	case len(pending) == 0:
+		log.Info("sent notification", "event", record.Event)
+		record.DeliveredTime = &attempted
+	case record.Attempts > notifyRetries(o):
+		log.Info("giving up on notification", "event", record.Event, "attempts", record.Attempts)
+		record.Pending = nil
+	}
+}
+
+// webhook returns the webhook of an Oomer with the given URL.
+func webhook(o *oomv1beta1.Oomer, url string) (oomv1beta1.WebhookTarget, bool) {
+	if o.Spec.Notify == nil {
+		return oomv1beta1.WebhookTarget{}, false
+	}
+	for _, target := range o.Spec.Notify.Webhooks {
+		if target.URL == url {
+			return target, true
+		}
+	}
+	return oomv1beta1.WebhookTarget{}, false
+}
+
+// notifyRetries returns how many times a failed notification is retried.
+func notifyRetries(o *oomv1beta1.Oomer) int32 {
+	if o.Spec.Notify != nil && o.Spec.Notify.Retries != nil {
+		return *o.Spec.Notify.Retries
+	}
+	return defaultNotifyRetries
+}
+
+// subscribed returns whether a webhook receives notifications for an event.
+func subscribed(target oomv1beta1.WebhookTarget, event oomv1beta1.NotifyEvent) bool {
+	if len(target.Events) == 0 {
+		return true
+	}
+	for _, e := range target.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationBody renders the body of a notification for a webhook, using its
+// template when one is provided.
+func notificationBody(target oomv1beta1.WebhookTarget, n notification) ([]byte, error) {
+	if target.Template == "" {
+		return json.Marshal(n)
+	}
+
+	t, err := template.New("webhook").Option("missingkey=error").Parse(target.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	if err := t.Execute(&body, n); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// sign returns the signature of a body, as sent in the signature header.
+func sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendWebhook POSTs a notification to a webhook.
+func (r *OomerReconciler) sendWebhook(ctx context.Context, o *oomv1beta1.Oomer, target oomv1beta1.WebhookTarget, n notification) error {
+	body, err := notificationBody(target, n)
+	if err != nil {
+		return err
+	}
+
+	var signature string
+	if ref := target.SigningSecret; ref != nil {
+		var secret corev1.Secret
+		if err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: o.ObjectMeta.Namespace}, &secret); err != nil {
+			return err
+		}
+		key, ok := secret.Data[ref.Key]
+		if !ok {
+			return fmt.Errorf("secret %s does not contain key %s", ref.Name, ref.Key)
+		}
+		signature = sign(key, body)
+	}
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: verifyTimeout}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if signature != "" {
+		req.Header.Set(signatureHeader, signature)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// notifyBackoff returns the delay before retrying a notification which failed on
+// the given attempt, counting from zero.
+func notifyBackoff(attempt int32) time.Duration {
+	delay := notifyRetryDelay
+	for i := int32(0); i < attempt && delay < notifyRetryCap; i++ {
+		delay *= 2
+	}
+	if delay > notifyRetryCap {
+		return notifyRetryCap
+	}
+	return delay
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/config"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// receiver records the notifications sent to it, failing the first requests
+// when requested.
+type receiver struct {
+	sync.Mutex
+	failures   int
+	bodies     []string
+	signatures []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	r.bodies = append(r.bodies, string(body))
+	r.signatures = append(r.signatures, req.Header.Get(signatureHeader))
+}
+
+var _ = Describe("Oomer notifications", func() {
+
+	ctx := context.Background()
+	now := time.Now()
+	start := metav1.NewTime(now)
+
+	newOomer := func(webhooks ...oomv1beta1.WebhookTarget) *oomv1beta1.Oomer {
+		retries := int32(1)
+		return &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "drill", Namespace: "default"},
+			Spec: oomv1beta1.OomerSpec{
+				Mode:   oomv1beta1.DeploymentMode,
+				Notify: &oomv1beta1.NotifySpec{Webhooks: webhooks, Retries: &retries},
+			},
+			Status: oomv1beta1.OomerStatus{StartTime: &start},
+		}
+	}
+
+	newReconciler := func(objs ...runtime.Object) *OomerReconciler {
+		return &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithRuntimeObjects(objs...).Build()}
+	}
+
+	It("Should send each event once with a signature", func() {
+		rec := &receiver{}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "webhook", Namespace: "default"},
+			Data:       map[string][]byte{"key": []byte("s3cret")},
+		}
+		o := newOomer(oomv1beta1.WebhookTarget{
+			URL:           server.URL,
+			SigningSecret: &oomv1beta1.SecretKeyReference{Name: "webhook", Key: "key"},
+		})
+		r := newReconciler(secret)
+
+		Expect(r.notifyProgress(ctx, o, now)).To(BeTrue())
+		Expect(r.notifyProgress(ctx, o, now)).To(BeFalse())
+
+		Expect(rec.bodies).To(HaveLen(1))
+		var n notification
+		Expect(json.Unmarshal([]byte(rec.bodies[0]), &n)).To(Succeed())
+		Expect(n.Event).To(Equal(oomv1beta1.StartedNotifyEvent))
+		Expect(n.Oomer).To(Equal("drill"))
+		Expect(rec.signatures[0]).To(Equal(sign([]byte("s3cret"), []byte(rec.bodies[0]))))
+
+		Expect(o.Status.Notifications).To(HaveLen(1))
+		Expect(o.Status.Notifications[0].Error).To(BeEmpty())
+	})
+
+	It("Should render the body from a template", func() {
+		rec := &receiver{}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		o := newOomer(oomv1beta1.WebhookTarget{URL: server.URL, Template: `{"text": "Drill {{ .Oomer }} {{ .Event }}"}`})
+		Expect(newReconciler().notify(ctx, o, oomv1beta1.CompletedNotifyEvent, "", now)).To(BeTrue())
+		Expect(rec.bodies).To(ConsistOf(`{"text": "Drill drill Completed"}`))
+	})
+
+	It("Should retry a failing webhook on a later reconcile and only then mark it delivered", func() {
+		rec := &receiver{failures: 1}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		o := newOomer(oomv1beta1.WebhookTarget{URL: server.URL})
+		r := newReconciler()
+
+		Expect(r.notify(ctx, o, oomv1beta1.StartedNotifyEvent, "OOM injection has started", now)).To(BeTrue())
+		Expect(rec.bodies).To(BeEmpty())
+		record := &o.Status.Notifications[0]
+		Expect(record.Pending).To(ConsistOf(server.URL))
+		Expect(record.Error).To(ContainSubstring("503"))
+		Expect(record.DeliveredTime).To(BeNil())
+
+		By("requeueing for the retry rather than waiting within the reconcile")
+		Expect(notifyRequeue(o, now)).To(Equal(notifyRetryDelay))
+		Expect(scheduledRequeue(o, config.DefaultRequeueInterval, now)).To(Equal(notifyRetryDelay))
+		Expect(r.retryNotifications(ctx, o, now)).To(BeFalse())
+
+		retried := now.Add(notifyRetryDelay)
+		Expect(r.retryNotifications(ctx, o, retried)).To(BeTrue())
+		Expect(rec.bodies).To(HaveLen(1))
+		Expect(rec.bodies[0]).To(ContainSubstring("OOM injection has started"))
+		Expect(record.Pending).To(BeEmpty())
+		Expect(record.Error).To(BeEmpty())
+		Expect(record.Attempts).To(Equal(int32(2)))
+		Expect(record.DeliveredTime.Time).To(Equal(retried))
+		Expect(notifyRequeue(o, retried)).To(BeZero())
+
+		By("giving up after the configured retries")
+		rec.failures = 2
+		Expect(r.notify(ctx, o, oomv1beta1.CompletedNotifyEvent, "", retried)).To(BeTrue())
+		Expect(r.retryNotifications(ctx, o, retried.Add(notifyRetryDelay))).To(BeTrue())
+		Expect(rec.bodies).To(HaveLen(1))
+		record = &o.Status.Notifications[1]
+		Expect(record.Pending).To(BeEmpty())
+		Expect(record.Error).To(ContainSubstring("503"))
+		Expect(record.DeliveredTime).To(BeNil())
+		Expect(notifyRequeue(o, retried)).To(BeZero())
+	})
+
+	It("Should cap the delay between retries", func() {
+		Expect(notifyBackoff(0)).To(Equal(notifyRetryDelay))
+		Expect(notifyBackoff(1)).To(Equal(2 * notifyRetryDelay))
+		Expect(notifyBackoff(9)).To(Equal(notifyRetryCap))
+	})
+
+	It("Should notify the first OOM and a failed verification", func() {
+		rec := &receiver{}
+		server := httptest.NewServer(rec)
+		defer server.Close()
+
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "drill-abc", Namespace: "default", Labels: oomkill.PodLabels(newOomer())},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "oomer",
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Reason:     oomKilledReason,
+					FinishedAt: metav1.NewTime(now.Add(time.Second)),
+				}},
+			}}},
+		}
+
+		o := newOomer(oomv1beta1.WebhookTarget{
+			URL:    server.URL,
+			Events: []oomv1beta1.NotifyEvent{oomv1beta1.OOMKilledNotifyEvent, oomv1beta1.FailedNotifyEvent},
+		})
+		Expect(awaitingFirstOOM(o)).To(BeTrue())
+		Expect(scheduledRequeue(o, config.DefaultRequeueInterval, now)).To(Equal(notifyInterval))
+
+		setCondition(o, oomv1beta1.VerificationFailedCondition, metav1.ConditionTrue, checksFailedReason, "Checks have not passed: alert")
+		Expect(newReconciler(pod).notifyProgress(ctx, o, now)).To(BeTrue())
+		Expect(awaitingFirstOOM(o)).To(BeFalse())
+
+		By("only sending the subscribed events")
+		Expect(o.Status.Notifications).To(HaveLen(3))
+		Expect(rec.bodies).To(HaveLen(2))
+		Expect(rec.bodies[0]).To(ContainSubstring("Observed 1 OOMKilled containers"))
+		Expect(rec.bodies[1]).To(ContainSubstring("Checks have not passed: alert"))
+	})
+})
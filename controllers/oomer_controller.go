@@ -18,6 +18,8 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -28,102 +30,165 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
-	oomv1alpha1 "github.com/jdockerty/oom-operator/api/v1alpha1"
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/allocator"
+	"github.com/jdockerty/oom-operator/pkg/config"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 )
 
-const (
-	defaultImage           = "jdockerty/oomer:v0.0.1"
-	terminationMessagePath = "/tmp/oomed-pod.log"
-	oomerFinalizer         = "jdocklabs.co.uk/finalizer"
-)
+const oomerFinalizer = "jdocklabs.co.uk/finalizer"
 
 // OomerReconciler reconciles a Oomer object
 type OomerReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// HTTPClient is used by the verification checks and notifications of an Oomer,
+	// a client with a timeout is used when this is nil.
+	HTTPClient *http.Client
+
+	// Config holds the configuration of the operator, the defaults are used when
+	// this is nil.
+	Config *config.Store
+
+	// APIReader reads from the API server rather than the cache, it is used for
+	// the snapshots of targeted workloads. The Client is used when this is nil.
+	APIReader client.Reader
+
+	// Namespaced is set when the operator only watches the namespaces given with
+	// --namespace, it is then granted a Role and cannot access nodes.
+	Namespaced bool
 }
 
-func (r *OomerReconciler) createOrUpdateDeployment(ctx context.Context, o *oomv1alpha1.Oomer) error {
+// applyDeployment applies the Deployment of allocators for an Oomer, only the
+// fields set here are owned so others may be changed without being reverted.
+func (r *OomerReconciler) applyDeployment(ctx context.Context, o *oomv1beta1.Oomer, replicas int32) error {
 
 	log := log.FromContext(ctx)
 
+	env, err := allocator.Env(o)
+	if err != nil {
+		return err
+	}
+
+	containers := []corev1.Container{{
+		Name:                   "oomer",
+		Image:                  allocator.Image(o, r.Config),
+		TerminationMessagePath: allocator.TerminationMessagePath,
+		Env:                    env,
+	}}
+	var mc *oomv1beta1.MultiContainerSpec
+	if o.Spec.Mode == oomv1beta1.MultiContainerMode {
+		if mc, err = multiContainerSpec(o); err != nil {
+			return err
+		}
+		containers = multiContainerAllocators(mc, allocator.Image(o, r.Config), env)
+	}
+
+	// The containers of a pod share its network namespace, so each serves metrics
+	// on its own port.
+	if port := allocator.MetricsPort(o.Spec.Profile); port > 0 {
+		for i := range containers {
+			allocator.ExposeMetrics(&containers[i], port+int32(i))
+		}
+	}
+
+	labels := oomkill.PodLabels(o)
+
+	namespacedName := types.NamespacedName{
+		Name:      o.ObjectMeta.Name,
+		Namespace: o.ObjectMeta.Namespace,
+	}
+
+	// The selector of a Deployment is immutable, so an existing Deployment keeps
+	// the labels it was created with.
+	existing := &appsv1.Deployment{}
+	create := false
+	if err := r.Get(ctx, namespacedName, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		log.Info("underlying deployment not found, creating...")
+		create = true
+	} else if existing.Spec.Selector != nil {
+		labels = existing.Spec.Selector.MatchLabels
+	}
+
 	d := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Kind:       "Deployment",
+		},
 		ObjectMeta: metav1.ObjectMeta{
-			Labels:      make(map[string]string),
-			Annotations: make(map[string]string),
-			Name:        o.ObjectMeta.Name,
-			Namespace:   o.ObjectMeta.Namespace,
+			Name:      o.ObjectMeta.Name,
+			Namespace: o.ObjectMeta.Namespace,
 		},
 		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
 			Selector: &metav1.LabelSelector{
-				MatchLabels: make(map[string]string),
+				MatchLabels: labels,
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels:      make(map[string]string),
-					Annotations: make(map[string]string),
+					Labels: labels,
 				},
 				Spec: corev1.PodSpec{
-					Containers: make([]corev1.Container, 1),
+					Containers: containers,
 				},
 			},
 		},
 	}
 
-	namespacedName := types.NamespacedName{
-		Name:      o.ObjectMeta.Name,
-		Namespace: o.ObjectMeta.Namespace,
+	if err := ctrl.SetControllerReference(o, d, r.Scheme); err != nil {
+		return err
 	}
-	if err := r.Get(ctx, namespacedName, d); err != nil {
-
-		// If not found, create the Deployment
-		if apierrors.IsNotFound(err) {
-
-			d.Spec.Replicas = o.Spec.Replicas
 
-			if o.Spec.Labels != nil {
-				d.Spec.Selector.MatchLabels = o.Spec.Labels
-				d.Spec.Template.ObjectMeta.Labels = o.Spec.Labels
-			} else {
-				d.Spec.Selector.MatchLabels["app"] = "oomer"
-				d.Spec.Template.ObjectMeta.Labels["app"] = "oomer"
-			}
-
-			if o.Spec.Image != nil {
-				d.Spec.Template.Spec.Containers[0].Image = *o.Spec.Image
-			} else {
-				d.Spec.Template.Spec.Containers[0].Image = defaultImage
-			}
-
-			log.Info("set image", "image", d.Spec.Template.Spec.Containers[0].Image)
-			d.Spec.Template.Spec.Containers[0].Name = "oomer"
-			d.Spec.Template.Spec.Containers[0].TerminationMessagePath = terminationMessagePath
-
-			log.Info("underlying deployment not found, creating...")
-
-			if err := r.Create(ctx, d); err != nil {
+	log.Info("applying deployment", "replicas", replicas, "image", d.Spec.Template.Spec.Containers[0].Image)
+	if mc == nil {
+		if err := r.apply(ctx, d); err != nil {
+			return err
+		}
+	} else {
+		u, err := withPodMemoryLimit(d, mc.MemoryLimit)
+		if err != nil {
+			return err
+		}
+		// Without the limit of the pod, each container is only limited on its own,
+		// so the Deployment is not created when the API server would drop it.
+		if create {
+			dryRun := u.DeepCopy()
+			if err := r.Patch(ctx, dryRun, client.Apply, append(applyOptions, client.DryRunAll)...); err != nil {
 				return err
 			}
-
-			if err := ctrl.SetControllerReference(o, d, r.Scheme); err != nil {
-				return err
+			if !hasPodMemoryLimit(dryRun) {
+				return errPodLevelResourcesUnsupported
 			}
+		}
+		if err := r.apply(ctx, u); err != nil {
+			return err
+		}
+		// The cleanup of the unsupported Oomer removes a Deployment which existed.
+		if !hasPodMemoryLimit(u) {
+			return errPodLevelResourcesUnsupported
+		}
+	}
 
-			log.Info("updating oomer observed replicas status", "replicas", o.Spec.Replicas)
+	if o.Status.ObservedReplicas != replicas {
+		log.Info("updating oomer observed replicas status", "replicas", replicas)
 
-			// Update the status of observed replicas to those which are
-			// provided in the spec/to the deployment
-			o.Status.ObservedReplicas = o.Spec.Replicas
-			if err := r.Status().Update(ctx, o); err != nil {
-				log.Error(err, "unable to update oomer status observed replicas", "ObservedReplicas", o.Status.ObservedReplicas, "Spec.Replicas", o.Spec.Replicas)
-				return err
-			}
+		// Update the status of observed replicas to those which are
+		// provided in the spec/to the deployment
+		o.Status.ObservedReplicas = replicas
+		if err := r.applyStatus(ctx, o); err != nil {
+			log.Error(err, "unable to update oomer status observed replicas", "ObservedReplicas", o.Status.ObservedReplicas, "Spec.Replicas", o.Spec.Replicas)
+			return err
 		}
-
 	}
 
 	return nil
@@ -132,7 +197,7 @@ func (r *OomerReconciler) createOrUpdateDeployment(ctx context.Context, o *oomv1
 // deleteDeployment is used to delete the underlying Deployment object.
 // As the name and namespace of the Deployment is the same as the Oomer kind, this can be used
 // to populate values before the Deployment object itself is retrieved.
-func (r *OomerReconciler) deleteDeployment(ctx context.Context, o *oomv1alpha1.Oomer) error {
+func (r *OomerReconciler) deleteDeployment(ctx context.Context, o *oomv1beta1.Oomer) error {
 	log := log.FromContext(ctx)
 
 	d := &appsv1.Deployment{
@@ -143,6 +208,10 @@ func (r *OomerReconciler) deleteDeployment(ctx context.Context, o *oomv1alpha1.O
 	}
 
 	if err := r.Delete(ctx, d); err != nil {
+		// The Deployment is already removed when the Oomer has completed.
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
 		return err
 	}
 
@@ -151,19 +220,44 @@ func (r *OomerReconciler) deleteDeployment(ctx context.Context, o *oomv1alpha1.O
 	return nil
 }
 
+// cleanup removes, or restores, the resources which an Oomer has modified
+// depending on its mode.
+func (r *OomerReconciler) cleanup(ctx context.Context, o *oomv1beta1.Oomer) error {
+	switch o.Spec.Mode {
+	case oomv1beta1.NodePressureMode:
+		return r.deleteDaemonSet(ctx, o)
+	case oomv1beta1.TargetMode, oomv1beta1.SqueezeMode:
+		return r.restoreTarget(ctx, o)
+	case oomv1beta1.EphemeralMode, oomv1beta1.SidecarMode:
+		// Ephemeral containers and injected sidecars cannot be removed from a pod,
+		// the allocators remain until their pods are replaced.
+		return nil
+	default:
+		return r.deleteDeployment(ctx, o)
+	}
+}
+
 //+kubebuilder:rbac:groups=jdocklabs.co.uk,resources=oomers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=jdocklabs.co.uk,resources=oomers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=jdocklabs.co.uk,resources=oomers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=jdocklabs.co.uk,resources=oomreports,verbs=get;list;watch;create
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=apps,resources=deployments/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=apps,resources=deployments/finalizers,verbs=update
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;create;patch;delete
+//+kubebuilder:rbac:groups=core,resources=events,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods/ephemeralcontainers,verbs=update;patch
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *OomerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
-	var oomer oomv1alpha1.Oomer
+	var oomer oomv1beta1.Oomer
 	if err := r.Get(ctx, req.NamespacedName, &oomer); err != nil {
 		if apierrors.IsNotFound(err) {
 			// we'll ignore not-found errors, since they can't be fixed by an immediate
@@ -179,13 +273,14 @@ func (r *OomerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 	// or has not had the finalizer set yet.
 	if oomer.ObjectMeta.DeletionTimestamp.IsZero() {
 
-		// If the finalizer is not present, register it and update the object.
+		// If the finalizer is not present, register it and apply it to the object.
 		if !ctrlutil.ContainsFinalizer(&oomer, oomerFinalizer) {
 
 			ctrlutil.AddFinalizer(&oomer, oomerFinalizer)
 
-			if err := r.Update(ctx, &oomer); err != nil {
-				return ctrl.Result{}, err
+			// The Oomer may have been deleted since it was read from the cache.
+			if err := r.patchFinalizer(ctx, &oomer); err != nil {
+				return ctrl.Result{}, client.IgnoreNotFound(err)
 			}
 		}
 	} else { // Object is being deleted
@@ -196,41 +291,328 @@ func (r *OomerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 			// resource upon a deletion request first.
 			// This means that our Oomer kind cannot be force deleted, leaving an orphaned
 			// Deployment object, this will now be deleted beforehand.
-			if err := r.deleteDeployment(ctx, &oomer); err != nil {
+			// The report is generated first, as it is built from those resources.
+			if err := r.generateReport(ctx, &oomer, oomv1beta1.DeletedReason); err != nil {
 				return ctrl.Result{}, err
 			}
 
-			// Remove finalizer from the list and update object
-			ctrlutil.RemoveFinalizer(&oomer, oomerFinalizer)
-			if err := r.Update(ctx, &oomer); err != nil {
+			if err := r.cleanup(ctx, &oomer); err != nil {
 				return ctrl.Result{}, err
 			}
 
+			// Remove finalizer from the list and apply it to the object
+			ctrlutil.RemoveFinalizer(&oomer, oomerFinalizer)
+			if err := r.patchFinalizer(ctx, &oomer); err != nil {
+				return ctrl.Result{}, client.IgnoreNotFound(err)
+			}
+
 			// Object is deleted, stop reconcile loop
 			return ctrl.Result{}, nil
 		}
 
 	}
 
-	if *oomer.Spec.Replicas == int32(0) {
+	now := time.Now()
+	settings := r.Config.Get()
+	requeueInterval := settings.RequeueInterval.Duration
+
+	// Nothing is injected once the scheduled duration has passed, or the Oomer
+	// has been aborted.
+	// Notifications which could not be delivered are still retried.
+	if meta.IsStatusConditionTrue(oomer.Status.Conditions, oomv1beta1.CompletedCondition) ||
+		meta.IsStatusConditionTrue(oomer.Status.Conditions, oomv1beta1.AbortedCondition) {
+		if r.retryNotifications(ctx, &oomer, now) {
+			if err := r.applyStatus(ctx, &oomer); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: notifyRequeue(&oomer, now)}, nil
+	}
+
+	// The scheduled duration continues to elapse while paused, so this is checked
+	// before the pause.
+	remaining, scheduled := scheduleRemaining(&oomer, now)
+	if scheduled && remaining <= 0 {
+		log.Info("scheduled duration has passed, completing oomer")
+
+		if err := r.generateReport(ctx, &oomer, oomv1beta1.CompletedReason); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := r.cleanup(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		setCondition(&oomer, oomv1beta1.InjectingCondition, metav1.ConditionFalse, scheduleElapsedReason, "The scheduled duration has passed")
+		setCondition(&oomer, oomv1beta1.CompletedCondition, metav1.ConditionTrue, scheduleElapsedReason, "The scheduled duration has passed")
+		r.verify(ctx, &oomer, now, true)
+		phase, reason := finishedPhase(&oomer)
+		setPhase(&oomer, phase, reason, now)
+		r.notifyProgress(ctx, &oomer, now)
+		r.notify(ctx, &oomer, oomv1beta1.CompletedNotifyEvent, "The scheduled duration has passed", now)
+		if err := r.applyStatus(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: notifyRequeue(&oomer, now)}, nil
+	}
+
+	// Every Oomer starts as Pending, the phases which follow are set as it is
+	// reconciled below.
+	update := oomer.Status.Phase == "" && setPhase(&oomer, oomv1beta1.PendingPhase, createdReason, now)
+
+	// Oomers which cannot be injected as specified stop injecting until changed.
+	reason, message := r.validateSpec(&oomer)
+	if message != "" {
+		return ctrl.Result{}, r.notReady(ctx, &oomer, reason, message, update)
+	}
+	update = setCondition(&oomer, oomv1beta1.ReadyCondition, metav1.ConditionTrue, reason, "The Oomer can be injected") || update
+
+	// Resources are removed, or restored, while paused and recreated once resumed.
+	if oomer.Spec.Paused {
+		log.Info("oomer is paused")
+
+		if err := r.cleanup(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		update = setCondition(&oomer, oomv1beta1.InjectingCondition, metav1.ConditionFalse, pausedReason, "Injection is paused") || update
+		update = setPhase(&oomer, oomv1beta1.PausedPhase, pausedReason, now) || update
+		if update {
+			if err := r.applyStatus(ctx, &oomer); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	// Injection waits for the scheduled start time.
+	if wait := untilScheduledStart(oomer.Spec.Schedule, now); wait > 0 {
+		log.Info("waiting for scheduled start", "startAt", oomer.Spec.Schedule.StartAt)
+
+		update = setCondition(&oomer, oomv1beta1.InjectingCondition, metav1.ConditionFalse, scheduledReason, "Waiting for the scheduled start time") || update
+		update = setPhase(&oomer, oomv1beta1.PendingPhase, scheduledReason, now) || update
+		if update {
+			if err := r.applyStatus(ctx, &oomer); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		return ctrl.Result{RequeueAfter: wait}, nil
+	}
+
+	// Oomers wait to start injecting while the budget is used by others.
+	if exhausted, err := r.budgetExhausted(ctx, &oomer, settings); err != nil {
+		return ctrl.Result{}, err
+	} else if exhausted {
+		log.Info("waiting for injection budget", "maxInjecting", settings.Budget.MaxInjecting)
+
+		update = setCondition(&oomer, oomv1beta1.InjectingCondition, metav1.ConditionFalse, budgetExceededReason, "Waiting for other Oomers to stop injecting") || update
+		update = setPhase(&oomer, oomv1beta1.PendingPhase, budgetExceededReason, now) || update
+		if update {
+			if err := r.applyStatus(ctx, &oomer); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
+		return ctrl.Result{RequeueAfter: budgetInterval}, nil
+	}
+
+	// Patterns and the scheduled duration are calculated from the first time the
+	// Oomer started injecting.
+	if oomer.Status.StartTime == nil {
+		start := metav1.NewTime(now)
+		oomer.Status.StartTime = &start
+		update = true
+	}
+
+	// Injection stops immediately once an abort criterion is met.
+	if message := r.abortCriterionMet(ctx, &oomer); message != "" {
+		log.Info("abort criterion met, aborting oomer", "reason", message)
+
+		if err := r.generateReport(ctx, &oomer, oomv1beta1.AbortedReason); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if err := r.cleanup(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		setCondition(&oomer, oomv1beta1.InjectingCondition, metav1.ConditionFalse, abortedReason, message)
+		setCondition(&oomer, oomv1beta1.AbortedCondition, metav1.ConditionTrue, abortedReason, message)
+		r.verify(ctx, &oomer, now, true)
+		setPhase(&oomer, oomv1beta1.AbortedPhase, abortedReason, now)
+		r.notifyProgress(ctx, &oomer, now)
+		r.notify(ctx, &oomer, oomv1beta1.FailedNotifyEvent, "Aborted, "+message, now)
+		if err := r.applyStatus(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: notifyRequeue(&oomer, now)}, nil
+	}
+
+	if setCondition(&oomer, oomv1beta1.InjectingCondition, metav1.ConditionTrue, startedReason, "OOM conditions are being injected") {
+		update = true
+	}
+
+	if r.verify(ctx, &oomer, now, false) {
+		update = true
+	}
+
+	if phase, reason := r.injectingPhase(ctx, &oomer); setPhase(&oomer, phase, reason, now) {
+		update = true
+	}
+
+	if r.notifyProgress(ctx, &oomer, now) {
+		update = true
+	}
+
+	if update {
+		if err := r.applyStatus(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Allocators are placed onto each selected node, so replicas do not apply.
+	if oomer.Spec.Mode == oomv1beta1.NodePressureMode {
+		log.Info("reconciling oomer node pressure")
+
+		if err := r.applyDaemonSet(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: scheduledRequeue(&oomer, requeueInterval, now)}, nil
+	}
+
+	// Existing workloads are modified rather than creating new ones.
+	if oomer.Spec.Mode == oomv1beta1.TargetMode {
+		log.Info("reconciling oomer target")
+
+		if err := r.applyTarget(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: scheduledRequeue(&oomer, requeueInterval, now)}, nil
+	}
+
+	// The memory limit of an existing workload is lowered in steps until it OOMs.
+	if oomer.Spec.Mode == oomv1beta1.SqueezeMode {
+		log.Info("reconciling oomer squeeze")
+
+		untilNextStep, err := r.applySqueeze(ctx, &oomer, now)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		requeueAfter := requeueInterval
+		if untilNextStep > 0 && untilNextStep < requeueAfter {
+			requeueAfter = untilNextStep
+		}
+
+		return ctrl.Result{RequeueAfter: scheduledRequeue(&oomer, requeueAfter, now)}, nil
+	}
+
+	// Allocators are attached to running pods without modifying their workload.
+	if oomer.Spec.Mode == oomv1beta1.EphemeralMode {
+		log.Info("reconciling oomer ephemeral containers")
+
+		if err := r.injectEphemeralContainers(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: scheduledRequeue(&oomer, requeueInterval, now)}, nil
+	}
+
+	// Sidecars are injected by the pod webhook, so only observation happens here.
+	if oomer.Spec.Mode == oomv1beta1.SidecarMode {
+		log.Info("reconciling oomer sidecars")
+
+		if err := r.observeSidecars(ctx, &oomer); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		return ctrl.Result{RequeueAfter: scheduledRequeue(&oomer, requeueInterval, now)}, nil
+	}
+
+	if oomer.Spec.Pattern == nil && oomer.Spec.Replicas == int32(0) {
 		log.Info("0 replicas, no creation")
-		return ctrl.Result{}, nil
+		return ctrl.Result{RequeueAfter: notifyRequeue(&oomer, now)}, nil
 	}
 
-	log.Info("reconciling oomer", "replicas", oomer.Spec.Replicas)
+	replicas, untilNextChange, err := desiredReplicas(oomer.Spec.Pattern, oomer.Spec.Replicas, time.Since(oomer.Status.StartTime.Time))
+	if err != nil {
+		log.Error(err, "unable to calculate replicas from pattern")
+		return ctrl.Result{}, err
+	}
+
+	if max := settings.Budget.MaxReplicas; max > 0 && replicas > max {
+		log.Info("limiting replicas to the budget", "replicas", replicas, "maxReplicas", max)
+		replicas = max
+	}
 
-	if err := r.createOrUpdateDeployment(ctx, &oomer); err != nil {
+	log.Info("reconciling oomer", "replicas", replicas)
+
+	if err := r.applyDeployment(ctx, &oomer, replicas); err != nil {
+		if errors.Is(err, errPodLevelResourcesUnsupported) {
+			return ctrl.Result{}, r.notReady(ctx, &oomer, unsupportedReason, err.Error(), true)
+		}
 		return ctrl.Result{}, err
 	}
 
+	if oomer.Spec.Mode == oomv1beta1.MultiContainerMode {
+		changed, err := r.attributeOOMKills(ctx, &oomer)
+		if err != nil {
+			log.Error(err, "unable to attribute OOM kills to containers")
+			return ctrl.Result{}, err
+		}
+		if changed {
+			if err := r.applyStatus(ctx, &oomer); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+	}
+
+	// Patterns requeue at the next point where the number of replicas changes
+	if untilNextChange > 0 && untilNextChange < requeueInterval {
+		return ctrl.Result{RequeueAfter: scheduledRequeue(&oomer, untilNextChange, now)}, nil
+	}
+
 	// Check for any new state after 5 minutes if no events have occurred
-	return ctrl.Result{RequeueAfter: 5 * time.Minute}, nil
+	return ctrl.Result{RequeueAfter: scheduledRequeue(&oomer, requeueInterval, now)}, nil
+}
+
+// notReady removes the resources of an Oomer which cannot be injected and reports
+// why through its Ready condition. It is not requeued, as only a change to the
+// Oomer can resolve this.
+func (r *OomerReconciler) notReady(ctx context.Context, o *oomv1beta1.Oomer, reason, message string, update bool) error {
+	log.FromContext(ctx).Info("oomer cannot be injected", "reason", message)
+
+	if err := r.cleanup(ctx, o); err != nil {
+		return err
+	}
+
+	update = setCondition(o, oomv1beta1.ReadyCondition, metav1.ConditionFalse, reason, message) || update
+	update = setCondition(o, oomv1beta1.InjectingCondition, metav1.ConditionFalse, reason, message) || update
+	if !update {
+		return nil
+	}
+	return r.applyStatus(ctx, o)
 }
 
 // SetupWithManager sets up the controller with the Manager.
+// The concurrency and rate limiting are read from the configuration once, changes
+// to them require a restart.
 func (r *OomerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	settings := r.Config.Get().OomerController
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&oomv1alpha1.Oomer{}).
+		For(&oomv1beta1.Oomer{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.DaemonSet{}).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: settings.MaxConcurrentReconciles,
+			RateLimiter:             rateLimiter(settings.RateLimiter),
+		}).
 		Complete(r)
 }
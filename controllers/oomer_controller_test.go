@@ -4,12 +4,14 @@ import (
 	"context"
 	"time"
 
-	oomv1alpha1 "github.com/jdockerty/oom-operator/api/v1alpha1"
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 )
@@ -18,7 +20,7 @@ var _ = Describe("Oomer Operator", func() {
 	// Define utility constants for object names and testing timeouts/durations and intervals.
 	const (
 		operatorName    = "test-oomer"
-		oomerApiVersion = "jdocklabs.co.uk/v1alpha1"
+		oomerApiVersion = "jdocklabs.co.uk/v1beta1"
 		oomerKind       = "Oomer"
 		oomerNamespace  = "default"
 
@@ -30,7 +32,7 @@ var _ = Describe("Oomer Operator", func() {
 	var replicas int32 = 1
 
 	ctx := context.Background()
-	oom := &oomv1alpha1.Oomer{
+	oom := &oomv1beta1.Oomer{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: oomerApiVersion,
 			Kind:       oomerKind,
@@ -39,8 +41,8 @@ var _ = Describe("Oomer Operator", func() {
 			Name:      operatorName,
 			Namespace: oomerNamespace,
 		},
-		Spec: oomv1alpha1.OomerSpec{
-			Replicas: &replicas,
+		Spec: oomv1beta1.OomerSpec{
+			Replicas: replicas,
 		},
 	}
 
@@ -50,7 +52,7 @@ var _ = Describe("Oomer Operator", func() {
 			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
 
 			lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
-			createdOomer := &oomv1alpha1.Oomer{}
+			createdOomer := &oomv1beta1.Oomer{}
 
 			Eventually(func() bool {
 				err := k8sClient.Get(ctx, lookupOomer, createdOomer)
@@ -71,14 +73,14 @@ var _ = Describe("Oomer Operator", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			Expect(d.ObjectMeta.Name).Should(Equal(oom.ObjectMeta.Name))
-			Expect(d.Spec.Replicas).Should(Equal(oom.Spec.Replicas))
+			Expect(*d.Spec.Replicas).Should(Equal(oom.Spec.Replicas))
 
 		})
 
 		It("Should update the status to reflect the observed replicas", func() {
 
 			lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
-			createdOomer := &oomv1alpha1.Oomer{}
+			createdOomer := &oomv1beta1.Oomer{}
 
 			Eventually(func() bool {
 				err := k8sClient.Get(ctx, lookupOomer, createdOomer)
@@ -97,7 +99,7 @@ var _ = Describe("Oomer Operator", func() {
 				return true
 			}, timeout, interval).Should(BeTrue())
 
-			Expect(d.Spec.Replicas).Should(Equal(createdOomer.Spec.Replicas))
+			Expect(*d.Spec.Replicas).Should(Equal(createdOomer.Spec.Replicas))
 			Expect(createdOomer.Status.ObservedReplicas).Should(Equal(createdOomer.Spec.Replicas))
 		})
 	})
@@ -106,7 +108,7 @@ var _ = Describe("Oomer Operator", func() {
 		It("should delete the underlying deployment", func() {
 
 			lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
-			createdOomer := &oomv1alpha1.Oomer{}
+			createdOomer := &oomv1beta1.Oomer{}
 
 			By("ensuring finalizers exist")
 			Eventually(func() bool {
@@ -133,3 +135,83 @@ var _ = Describe("Oomer Operator", func() {
 		})
 	})
 })
+
+var _ = Describe("Oomer Operator in NodePressure mode", func() {
+	const (
+		operatorName   = "test-node-pressure"
+		oomerNamespace = "default"
+		nodeName       = "test-pressure-node"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	var replicas int32 = 0
+
+	ctx := context.Background()
+	oom := &oomv1beta1.Oomer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      operatorName,
+			Namespace: oomerNamespace,
+		},
+		Spec: oomv1beta1.OomerSpec{
+			Replicas: replicas,
+			Mode:     oomv1beta1.NodePressureMode,
+			NodePressure: &oomv1beta1.NodePressureSpec{
+				NodeNames:          []string{nodeName},
+				AllocatablePercent: 50,
+			},
+		},
+	}
+
+	Context("When creating the object", func() {
+		It("Should create an allocator daemonset for the selected nodes", func() {
+
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: nodeName,
+				},
+			}
+			Expect(k8sClient.Create(ctx, node)).Should(Succeed())
+
+			node.Status.Allocatable = corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("2Gi"),
+			}
+			Expect(k8sClient.Status().Update(ctx, node)).Should(Succeed())
+
+			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+			lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
+
+			By("checking the underlying daemonset exists")
+			ds := &appsv1.DaemonSet{}
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, lookupOomer, ds)
+				if err != nil {
+					return false
+				}
+				return true
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(ds.Spec.Template.Spec.Containers[0].Env).Should(ContainElement(corev1.EnvVar{
+				Name:  allocateBytesEnv,
+				Value: "1073741824",
+			}))
+			Expect(ds.Spec.Template.Spec.Affinity.NodeAffinity).ShouldNot(BeNil())
+		})
+	})
+
+	Context("When deleting the object", func() {
+		It("should delete the underlying daemonset", func() {
+
+			lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
+
+			Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, lookupOomer, &oomv1beta1.Oomer{})
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+		})
+	})
+})
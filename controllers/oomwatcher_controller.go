@@ -0,0 +1,270 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+)
+
+// defaultMaxRecords is the number of records retained by an OomWatcher when unset.
+const defaultMaxRecords = 100
+
+var (
+	watcherOOMKilledTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "oom_operator_watcher_oomkilled_total",
+		Help: "Number of OOMKilled containers observed by an OomWatcher.",
+	}, []string{"watcher_namespace", "watcher", "namespace", "container"})
+
+	watcherOOMKilledMemoryLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oom_operator_watcher_oomkilled_memory_limit_bytes",
+		Help: "Memory limit of a container when it was last OOMKilled in any pod, zero when it had no limit.",
+	}, []string{"watcher_namespace", "watcher", "namespace", "container"})
+
+	watcherOOMKilledRestarts = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "oom_operator_watcher_oomkilled_restarts",
+		Help: "Restart count of a container when it was last OOMKilled in any pod.",
+	}, []string{"watcher_namespace", "watcher", "namespace", "container"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(watcherOOMKilledTotal, watcherOOMKilledMemoryLimit, watcherOOMKilledRestarts)
+}
+
+// OomWatcherReconciler records the OOMKilled containers of any pod in the cluster
+// onto the OomWatchers which select them.
+type OomWatcherReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// hasOOMKilledContainer reports whether any container of a pod was last terminated
+// by the OOM killer, only these pods are reconciled.
+func hasOOMKilledContainer(obj client.Object) bool {
+	pod, ok := obj.(*corev1.Pod)
+	return ok && oomkill.Count([]corev1.Pod{*pod}) > 0
+}
+
+// oomKilledRecords returns a record for each OOMKilled container of a pod.
+func oomKilledRecords(pod *corev1.Pod) []oomv1beta1.OOMKilledRecord {
+	resources := map[string]corev1.ResourceRequirements{}
+	for _, c := range pod.Spec.Containers {
+		resources[c.Name] = c.Resources
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		resources[c.Name] = c.Resources
+	}
+
+	var records []oomv1beta1.OOMKilledRecord
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.ContainerStatuses, pod.Status.EphemeralContainerStatuses} {
+		for _, status := range statuses {
+			at := oomkill.KilledAt(status)
+			if at == nil {
+				continue
+			}
+
+			record := oomv1beta1.OOMKilledRecord{
+				Namespace:    pod.ObjectMeta.Namespace,
+				Pod:          pod.ObjectMeta.Name,
+				Container:    status.Name,
+				Node:         pod.Spec.NodeName,
+				RestartCount: status.RestartCount,
+				FinishedAt:   *at,
+			}
+
+			if limit, ok := resources[status.Name].Limits[corev1.ResourceMemory]; ok {
+				record.MemoryLimit = &limit
+			}
+			if request, ok := resources[status.Name].Requests[corev1.ResourceMemory]; ok {
+				record.MemoryRequest = &request
+			}
+
+			records = append(records, record)
+		}
+	}
+
+	return records
+}
+
+// watches reports whether an OomWatcher observes a pod.
+func watches(w *oomv1beta1.OomWatcher, pod *corev1.Pod) (bool, error) {
+	if len(w.Spec.Namespaces) > 0 {
+		found := false
+		for _, ns := range w.Spec.Namespaces {
+			if ns == pod.ObjectMeta.Namespace {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+
+	if w.Spec.Selector == nil {
+		return true, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(w.Spec.Selector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(pod.ObjectMeta.Labels)), nil
+}
+
+// sameOOM reports whether two records are for the same OOM kill of a container.
+func sameOOM(a, b *oomv1beta1.OOMKilledRecord) bool {
+	return a.Namespace == b.Namespace && a.Pod == b.Pod && a.Container == b.Container && a.FinishedAt.Equal(&b.FinishedAt)
+}
+
+// recordOOMKilled adds the records which an OomWatcher has not yet observed to its
+// status, returning those which were added. OOM kills from before the watcher was
+// created, or older than every retained record once the limit is reached, are ignored.
+func recordOOMKilled(w *oomv1beta1.OomWatcher, records []oomv1beta1.OOMKilledRecord) []oomv1beta1.OOMKilledRecord {
+	maxRecords := int(w.Spec.MaxRecords)
+	if maxRecords <= 0 {
+		maxRecords = defaultMaxRecords
+	}
+
+	var added []oomv1beta1.OOMKilledRecord
+	for i := range records {
+		r := &records[i]
+
+		if r.FinishedAt.Before(&w.ObjectMeta.CreationTimestamp) {
+			continue
+		}
+
+		existing := w.Status.Records
+		if len(existing) >= maxRecords && r.FinishedAt.Before(&existing[0].FinishedAt) {
+			continue
+		}
+
+		seen := false
+		for j := range existing {
+			if sameOOM(&existing[j], r) {
+				seen = true
+				break
+			}
+		}
+		if seen {
+			continue
+		}
+
+		w.Status.Records = append(w.Status.Records, *r)
+		w.Status.TotalOOMKilled++
+		if w.Status.LastOOMKilledTime == nil || w.Status.LastOOMKilledTime.Before(&r.FinishedAt) {
+			finishedAt := r.FinishedAt
+			w.Status.LastOOMKilledTime = &finishedAt
+		}
+		added = append(added, *r)
+	}
+
+	sort.SliceStable(w.Status.Records, func(i, j int) bool {
+		return w.Status.Records[i].FinishedAt.Before(&w.Status.Records[j].FinishedAt)
+	})
+	if excess := len(w.Status.Records) - maxRecords; excess > 0 {
+		w.Status.Records = w.Status.Records[excess:]
+	}
+
+	return added
+}
+
+//+kubebuilder:rbac:groups=jdocklabs.co.uk,resources=oomwatchers,verbs=get;list;watch
+//+kubebuilder:rbac:groups=jdocklabs.co.uk,resources=oomwatchers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+
+// Reconcile records the OOMKilled containers of a pod onto each OomWatcher which observes it.
+func (r *OomWatcherReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, req.NamespacedName, pod); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	records := oomKilledRecords(pod)
+	if len(records) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	var watchers oomv1beta1.OomWatcherList
+	if err := r.List(ctx, &watchers); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	for i := range watchers.Items {
+		w := &watchers.Items[i]
+
+		ok, err := watches(w, pod)
+		if err != nil {
+			log.Error(err, "invalid oomwatcher selector", "oomwatcher", w.ObjectMeta.Name)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		// Other pods may be recorded on the same OomWatcher concurrently, so the
+		// records are added to its latest status.
+		var added []oomv1beta1.OOMKilledRecord
+		err = patchStatusWithRetry(ctx, r.Client, w, func() (bool, error) {
+			added = recordOOMKilled(w, records)
+			return len(added) > 0, nil
+		})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		for _, a := range added {
+			log.Info("observed OOMKilled container", "oomwatcher", w.ObjectMeta.Name, "namespace", a.Namespace, "pod", a.Pod, "container", a.Container)
+
+			watcherOOMKilledTotal.WithLabelValues(w.ObjectMeta.Namespace, w.ObjectMeta.Name, a.Namespace, a.Container).Inc()
+
+			var limit float64
+			if a.MemoryLimit != nil {
+				limit = a.MemoryLimit.AsApproximateFloat64()
+			}
+			watcherOOMKilledMemoryLimit.WithLabelValues(w.ObjectMeta.Namespace, w.ObjectMeta.Name, a.Namespace, a.Container).Set(limit)
+			watcherOOMKilledRestarts.WithLabelValues(w.ObjectMeta.Namespace, w.ObjectMeta.Name, a.Namespace, a.Container).Set(float64(a.RestartCount))
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OomWatcherReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("oomwatcher").
+		For(&corev1.Pod{}, builder.WithPredicates(predicate.NewPredicateFuncs(hasOOMKilledContainer))).
+		Complete(r)
+}
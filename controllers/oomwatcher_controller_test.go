@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Oom watcher", func() {
+
+	created := time.Date(2023, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	oomKilledPod := func(name string, at time.Time) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Spec: corev1.PodSpec{
+				NodeName: "node-a",
+				Containers: []corev1.Container{{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+						Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Mi")},
+					},
+				}},
+			},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "app",
+				RestartCount: 2,
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode:   137,
+					Reason:     oomKilledReason,
+					FinishedAt: metav1.NewTime(at),
+				}},
+			}}},
+		}
+	}
+
+	watcher := func(maxRecords int32) *oomv1beta1.OomWatcher {
+		return &oomv1beta1.OomWatcher{
+			ObjectMeta: metav1.ObjectMeta{Name: "all", CreationTimestamp: metav1.NewTime(created)},
+			Spec:       oomv1beta1.OomWatcherSpec{MaxRecords: maxRecords},
+		}
+	}
+
+	It("Should record the resources of an OOMKilled container", func() {
+		records := oomKilledRecords(oomKilledPod("web-1", created.Add(time.Minute)))
+		Expect(records).Should(HaveLen(1))
+		Expect(records[0].Node).Should(Equal("node-a"))
+		Expect(records[0].RestartCount).Should(Equal(int32(2)))
+		Expect(records[0].MemoryLimit.String()).Should(Equal("64Mi"))
+		Expect(records[0].MemoryRequest.String()).Should(Equal("32Mi"))
+	})
+
+	It("Should only record each OOM kill once", func() {
+		w := watcher(0)
+		records := oomKilledRecords(oomKilledPod("web-1", created.Add(time.Minute)))
+
+		Expect(recordOOMKilled(w, records)).Should(HaveLen(1))
+		Expect(recordOOMKilled(w, records)).Should(BeEmpty())
+		Expect(w.Status.TotalOOMKilled).Should(Equal(int64(1)))
+		Expect(w.Status.LastOOMKilledTime.Time).Should(Equal(created.Add(time.Minute)))
+	})
+
+	It("Should ignore OOM kills from before the watcher was created", func() {
+		w := watcher(0)
+		Expect(recordOOMKilled(w, oomKilledRecords(oomKilledPod("web-1", created.Add(-time.Minute))))).Should(BeEmpty())
+	})
+
+	It("Should retain only the most recent records", func() {
+		w := watcher(2)
+		for i, name := range []string{"web-1", "web-2", "web-3"} {
+			recordOOMKilled(w, oomKilledRecords(oomKilledPod(name, created.Add(time.Duration(i+1)*time.Minute))))
+		}
+
+		Expect(w.Status.TotalOOMKilled).Should(Equal(int64(3)))
+		Expect(w.Status.Records).Should(HaveLen(2))
+		Expect(w.Status.Records[0].Pod).Should(Equal("web-2"))
+
+		By("not counting a dropped record again")
+		Expect(recordOOMKilled(w, oomKilledRecords(oomKilledPod("web-1", created.Add(time.Minute))))).Should(BeEmpty())
+	})
+
+	It("Should only watch the selected pods", func() {
+		w := watcher(0)
+		pod := oomKilledPod("web-1", created)
+
+		Expect(watches(w, pod)).To(BeTrue())
+
+		w.Spec.Namespaces = []string{"other"}
+		Expect(watches(w, pod)).To(BeFalse())
+
+		w.Spec.Namespaces = nil
+		w.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}
+		Expect(watches(w, pod)).To(BeFalse())
+	})
+})
+
+var _ = Describe("Oom watcher controller", func() {
+	const (
+		watcherName  = "test-watcher"
+		podName      = "test-watched-pod"
+		podNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	ctx := context.Background()
+
+	Context("When a pod is OOMKilled", func() {
+		It("Should record the container on the watcher", func() {
+			w := &oomv1beta1.OomWatcher{
+				ObjectMeta: metav1.ObjectMeta{Name: watcherName, Namespace: podNamespace},
+			}
+			Expect(k8sClient.Create(ctx, w)).Should(Succeed())
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: podNamespace},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: "example.com/app:v1"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pod)).Should(Succeed())
+
+			// The watcher ignores OOM kills from before it was created, which is
+			// only precise to the second.
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				Name:  "app",
+				Image: "example.com/app:v1",
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode:   137,
+					Reason:     oomKilledReason,
+					FinishedAt: metav1.NewTime(time.Now().Add(time.Second)),
+				}},
+			}}
+			Expect(k8sClient.Status().Update(ctx, pod)).Should(Succeed())
+
+			lookup := types.NamespacedName{Name: watcherName, Namespace: podNamespace}
+			Eventually(func() int64 {
+				if err := k8sClient.Get(ctx, lookup, w); err != nil {
+					return 0
+				}
+				return w.Status.TotalOOMKilled
+			}, timeout, interval).Should(Equal(int64(1)))
+
+			Expect(w.Status.Records[0].Pod).Should(Equal(podName))
+			Expect(k8sClient.Delete(ctx, pod)).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// mutateFunc changes an object which is about to be patched, returning whether
+// anything changed so that the patch can be skipped.
+type mutateFunc func() (bool, error)
+
+// patchWithRetry reads the latest version of an object, mutates it and merge
+// patches the difference. The patch only applies to the version which was read,
+// so a concurrent change causes a conflict and the whole read, mutate and patch
+// is retried. This is used for objects which the operator does not own, or lists
+// which a merge patch replaces, where server-side apply cannot be used.
+func patchWithRetry(ctx context.Context, c client.Client, obj client.Object, mutate mutateFunc) error {
+	return retryPatch(ctx, c, obj, mutate, func(patch client.Patch) error {
+		return c.Patch(ctx, obj, patch)
+	})
+}
+
+// patchStatusWithRetry is the counterpart of patchWithRetry for the status
+// subresource of an object.
+func patchStatusWithRetry(ctx context.Context, c client.Client, obj client.Object, mutate mutateFunc) error {
+	return retryPatch(ctx, c, obj, mutate, func(patch client.Patch) error {
+		return c.Status().Patch(ctx, obj, patch)
+	})
+}
+
+// patchSubResourceWithRetry is the counterpart of patchWithRetry for another
+// subresource of an object, such as the ephemeral containers of a pod.
+func patchSubResourceWithRetry(ctx context.Context, c client.Client, obj client.Object, subResource string, mutate mutateFunc) error {
+	return retryPatch(ctx, c, obj, mutate, func(patch client.Patch) error {
+		return c.SubResource(subResource).Patch(ctx, obj, patch)
+	})
+}
+
+// retryPatch performs the read, mutate and patch of an object until it does not
+// conflict. The backoff allows a cached client to observe the conflicting change.
+func retryPatch(ctx context.Context, c client.Client, obj client.Object, mutate mutateFunc, patch func(client.Patch) error) error {
+	key := client.ObjectKeyFromObject(obj)
+
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if err := c.Get(ctx, key, obj); err != nil {
+			return err
+		}
+
+		base := obj.DeepCopyObject().(client.Object)
+		changed, err := mutate()
+		if err != nil || !changed {
+			return err
+		}
+
+		return patch(client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// patchFinalizer adds or removes the finalizer of an Oomer, depending on whether
+// it is present on the given Oomer. Unlike an apply, a patch never creates the
+// Oomer, so one which was deleted after a stale read is not recreated and NotFound
+// is returned instead. Other finalizers are kept, as a concurrent change to the
+// list conflicts and is retried.
+func (r *OomerReconciler) patchFinalizer(ctx context.Context, o *oomv1beta1.Oomer) error {
+	add := ctrlutil.ContainsFinalizer(o, oomerFinalizer)
+	return patchWithRetry(ctx, r.Client, o, func() (bool, error) {
+		if add {
+			return ctrlutil.AddFinalizer(o, oomerFinalizer), nil
+		}
+		return ctrlutil.RemoveFinalizer(o, oomerFinalizer), nil
+	})
+}
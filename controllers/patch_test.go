@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlutil "sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// racingClient changes an object between it being read and patched, as another
+// writer would, for the given number of patches.
+type racingClient struct {
+	client.Client
+	races   int
+	patches int
+}
+
+func (c *racingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.patches++
+	if c.races > 0 {
+		c.races--
+
+		other := obj.DeepCopyObject().(client.Object)
+		if err := c.Client.Get(ctx, client.ObjectKeyFromObject(obj), other); err != nil {
+			return err
+		}
+		labels := other.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[fmt.Sprintf("race-%d", c.races)] = "true"
+		other.SetLabels(labels)
+		if err := c.Client.Update(ctx, other); err != nil {
+			return err
+		}
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+// staleClient reads the given object as it was cached, after it has changed or
+// been deleted.
+type staleClient struct {
+	client.Client
+	cached client.Object
+}
+
+func (c staleClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if key == client.ObjectKeyFromObject(c.cached) {
+		c.cached.(*oomv1beta1.Oomer).DeepCopyInto(obj.(*oomv1beta1.Oomer))
+		return nil
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+var _ = Describe("Oomer patches", func() {
+
+	ctx := context.Background()
+
+	deployment := func() *appsv1.Deployment {
+		return &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "default"}}
+	}
+
+	It("Should retry a patch which conflicts with another writer", func() {
+		c := &racingClient{
+			Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(deployment()).Build(),
+			races:  2,
+		}
+
+		d := deployment()
+		Expect(patchWithRetry(ctx, c, d, func() (bool, error) {
+			ctrlutil.AddFinalizer(d, oomerFinalizer)
+			return true, nil
+		})).To(Succeed())
+		Expect(c.patches).To(Equal(3))
+
+		By("keeping the changes of the other writer")
+		Expect(c.Client.Get(ctx, client.ObjectKeyFromObject(d), d)).To(Succeed())
+		Expect(d.ObjectMeta.Finalizers).To(ConsistOf(oomerFinalizer))
+		Expect(d.ObjectMeta.Labels).To(HaveKey("race-0"))
+		Expect(d.ObjectMeta.Labels).To(HaveKey("race-1"))
+	})
+
+	It("Should skip the patch when nothing changed", func() {
+		c := &racingClient{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(deployment()).Build()}
+
+		Expect(patchWithRetry(ctx, c, deployment(), func() (bool, error) {
+			return false, nil
+		})).To(Succeed())
+		Expect(c.patches).To(BeZero())
+	})
+
+	It("Should return errors from reading and mutating", func() {
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+		err := patchWithRetry(ctx, c, deployment(), func() (bool, error) { return true, nil })
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		c = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(deployment()).Build()
+		err = patchWithRetry(ctx, c, deployment(), func() (bool, error) { return false, fmt.Errorf("invalid") })
+		Expect(err).To(MatchError("invalid"))
+	})
+
+	It("Should not recreate an oomer which was deleted after it was cached", func() {
+		o := &oomv1beta1.Oomer{ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: "default"}}
+		r := snapshotReconciler(o)
+
+		cached := &oomv1beta1.Oomer{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(o), cached)).To(Succeed())
+		Expect(r.Delete(ctx, cached.DeepCopy())).To(Succeed())
+		r.Client = staleClient{Client: r.Client, cached: cached}
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(o)})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = r.Client.(staleClient).Client.Get(ctx, client.ObjectKeyFromObject(o), &oomv1beta1.Oomer{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Oomer Operator with concurrent changes", func() {
+	const (
+		oomerNamespace = "default"
+		writes         = 20
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	ctx := context.Background()
+
+	// annotateConcurrently annotates an object once for each write in the background,
+	// re-reading it after each conflict as any other client would.
+	annotateConcurrently := func(obj client.Object) *sync.WaitGroup {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer GinkgoRecover()
+			defer wg.Done()
+
+			for i := 0; i < writes; i++ {
+				Eventually(func() error {
+					latest := obj.DeepCopyObject().(client.Object)
+					if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), latest); err != nil {
+						return err
+					}
+					annotations := latest.GetAnnotations()
+					if annotations == nil {
+						annotations = map[string]string{}
+					}
+					annotations[fmt.Sprintf("example.com/write-%d", i)] = "true"
+					latest.SetAnnotations(annotations)
+					return k8sClient.Update(ctx, latest)
+				}, timeout, time.Millisecond*10).Should(Succeed())
+			}
+		}()
+		return &wg
+	}
+
+	It("Should reconcile an oomer which is modified while reconciling", func() {
+		const name = "test-concurrent"
+
+		oom := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: oomerNamespace},
+			Spec:       oomv1beta1.OomerSpec{Replicas: 1},
+		}
+		Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+		annotateConcurrently(oom).Wait()
+
+		lookupOomer := types.NamespacedName{Name: name, Namespace: oomerNamespace}
+		Eventually(func() bool {
+			if err := k8sClient.Get(ctx, lookupOomer, oom); err != nil {
+				return false
+			}
+			return ctrlutil.ContainsFinalizer(oom, oomerFinalizer) &&
+				meta.IsStatusConditionTrue(oom.Status.Conditions, oomv1beta1.InjectingCondition)
+		}, timeout, interval).Should(BeTrue())
+		Expect(oom.ObjectMeta.Annotations).To(HaveLen(writes))
+
+		Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, lookupOomer, oom))
+		}, timeout, interval).Should(BeTrue())
+	})
+
+	It("Should keep changes made to a target while lowering its limit", func() {
+		const (
+			name       = "test-concurrent-target"
+			targetName = "concurrent-target"
+		)
+
+		labels := map[string]string{"app": targetName}
+		target := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: targetName, Namespace: oomerNamespace},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "app", Image: "example.com/app:v1"}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, target)).Should(Succeed())
+
+		limit := resource.MustParse("16Mi")
+		oom := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: oomerNamespace},
+			Spec: oomv1beta1.OomerSpec{
+				Mode:   oomv1beta1.TargetMode,
+				Target: &oomv1beta1.TargetSpec{Deployment: targetName, MemoryLimit: &limit},
+			},
+		}
+		wg := annotateConcurrently(target)
+		Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+		wg.Wait()
+
+		lookupTarget := types.NamespacedName{Name: targetName, Namespace: oomerNamespace}
+		Eventually(func() string {
+			if err := k8sClient.Get(ctx, lookupTarget, target); err != nil {
+				return ""
+			}
+			return target.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()
+		}, timeout, interval).Should(Equal("16Mi"))
+
+		for i := 0; i < writes; i++ {
+			Expect(target.ObjectMeta.Annotations).To(HaveKey(fmt.Sprintf("example.com/write-%d", i)))
+		}
+		Expect(target.ObjectMeta.Annotations).To(HaveKeyWithValue(targetedByAnnotation, name))
+
+		Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+		Expect(k8sClient.Delete(ctx, target)).Should(Succeed())
+	})
+})
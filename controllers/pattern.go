@@ -0,0 +1,136 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+const (
+	defaultSteps       int32 = 1
+	defaultSineSamples int32 = 8
+)
+
+// desiredReplicas calculates the number of replicas a pattern should have once
+// elapsed time has passed since the Oomer started. The time until the number of
+// replicas next changes is also returned, this is zero when no further changes
+// will occur.
+// When no pattern is provided, the static number of replicas is used.
+func desiredReplicas(p *oomv1beta1.PatternSpec, replicas int32, elapsed time.Duration) (int32, time.Duration, error) {
+	if p == nil || p.Type == oomv1beta1.ConstantPattern {
+		return replicas, 0, nil
+	}
+
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	switch p.Type {
+	case oomv1beta1.LinearRampPattern:
+		if p.Duration == nil || p.Duration.Duration <= 0 {
+			return 0, 0, fmt.Errorf("pattern %s requires a positive duration", p.Type)
+		}
+
+		d := p.Duration.Duration
+		diff := int64(p.To - p.From)
+		if diff == 0 || elapsed >= d {
+			return p.To, 0, nil
+		}
+
+		// Each single replica change happens at an even interval across the duration.
+		changes := diff
+		sign := int64(1)
+		if diff < 0 {
+			changes = -diff
+			sign = -1
+		}
+
+		k := int64(elapsed) * changes / int64(d)
+		next := time.Duration(int64(d) * (k + 1) / changes)
+
+		return p.From + int32(sign*k), next - elapsed, nil
+
+	case oomv1beta1.StepPattern:
+		if p.Duration == nil || p.Duration.Duration <= 0 {
+			return 0, 0, fmt.Errorf("pattern %s requires a positive duration", p.Type)
+		}
+
+		steps := p.Steps
+		if steps <= 0 {
+			steps = defaultSteps
+		}
+
+		stepLength := p.Duration.Duration / time.Duration(steps)
+		if stepLength <= 0 {
+			return 0, 0, fmt.Errorf("pattern %s has more steps than its duration allows", p.Type)
+		}
+
+		k := int32(elapsed / stepLength)
+		if k >= steps {
+			return p.To, 0, nil
+		}
+
+		next := stepLength * time.Duration(k+1)
+
+		return p.From + (p.To-p.From)*k/steps, next - elapsed, nil
+
+	case oomv1beta1.SinePattern:
+		if p.Period == nil || p.Period.Duration <= 0 {
+			return 0, 0, fmt.Errorf("pattern %s requires a positive period", p.Type)
+		}
+
+		samples := p.Steps
+		if samples <= 0 {
+			samples = defaultSineSamples
+		}
+
+		sampleLength := p.Period.Duration / time.Duration(samples)
+		if sampleLength <= 0 {
+			return 0, 0, fmt.Errorf("pattern %s has more steps than its period allows", p.Type)
+		}
+
+		k := int64(elapsed / sampleLength)
+		phase := 2 * math.Pi * float64(k%int64(samples)) / float64(samples)
+
+		// Starting from the trough means the wave begins at From replicas.
+		amplitude := float64(p.To-p.From) * (1 - math.Cos(phase)) / 2
+		next := sampleLength * time.Duration(k+1)
+
+		return p.From + int32(math.Round(amplitude)), next - elapsed, nil
+
+	case oomv1beta1.BurstPattern:
+		if p.Period == nil || p.Period.Duration <= 0 {
+			return 0, 0, fmt.Errorf("pattern %s requires a positive period", p.Type)
+		}
+		if p.BurstDuration == nil || p.BurstDuration.Duration <= 0 || p.BurstDuration.Duration >= p.Period.Duration {
+			return 0, 0, fmt.Errorf("pattern %s requires a positive burst duration shorter than the period", p.Type)
+		}
+
+		position := elapsed % p.Period.Duration
+		if position < p.BurstDuration.Duration {
+			return p.To, p.BurstDuration.Duration - position, nil
+		}
+
+		return p.From, p.Period.Duration - position, nil
+	}
+
+	return 0, 0, fmt.Errorf("unknown pattern type %q", p.Type)
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Oomer patterns", func() {
+
+	duration := func(d time.Duration) *metav1.Duration {
+		return &metav1.Duration{Duration: d}
+	}
+
+	DescribeTable("calculating desired replicas",
+		func(p *oomv1beta1.PatternSpec, elapsed time.Duration, expectedReplicas int32, expectedNext time.Duration) {
+			replicas, next, err := desiredReplicas(p, 3, elapsed)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(replicas).Should(Equal(expectedReplicas))
+			Expect(next).Should(Equal(expectedNext))
+		},
+		Entry("no pattern uses the static replicas", nil, time.Hour, int32(3), time.Duration(0)),
+		Entry("constant uses the static replicas",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.ConstantPattern}, time.Hour, int32(3), time.Duration(0)),
+		Entry("linearRamp starts at from",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.LinearRampPattern, From: 0, To: 10, Duration: duration(10 * time.Minute)},
+			time.Duration(0), int32(0), time.Minute),
+		Entry("linearRamp part way through",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.LinearRampPattern, From: 0, To: 10, Duration: duration(10 * time.Minute)},
+			150*time.Second, int32(2), 30*time.Second),
+		Entry("linearRamp ramping down",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.LinearRampPattern, From: 4, To: 0, Duration: duration(4 * time.Minute)},
+			time.Minute, int32(3), time.Minute),
+		Entry("linearRamp finished",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.LinearRampPattern, From: 0, To: 10, Duration: duration(10 * time.Minute)},
+			time.Hour, int32(10), time.Duration(0)),
+		Entry("step within the second step",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.StepPattern, From: 2, To: 10, Steps: 4, Duration: duration(8 * time.Minute)},
+			3*time.Minute, int32(4), time.Minute),
+		Entry("step finished",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.StepPattern, From: 2, To: 10, Steps: 4, Duration: duration(8 * time.Minute)},
+			8*time.Minute, int32(10), time.Duration(0)),
+		Entry("sine starts at the trough",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.SinePattern, From: 1, To: 9, Period: duration(8 * time.Minute)},
+			30*time.Second, int32(1), 30*time.Second),
+		Entry("sine peaks half way through the period",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.SinePattern, From: 1, To: 9, Period: duration(8 * time.Minute)},
+			12*time.Minute, int32(9), time.Minute),
+		Entry("burst during a burst",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.BurstPattern, From: 1, To: 20, Period: duration(10 * time.Minute), BurstDuration: duration(2 * time.Minute)},
+			21*time.Minute, int32(20), time.Minute),
+		Entry("burst between bursts",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.BurstPattern, From: 1, To: 20, Period: duration(10 * time.Minute), BurstDuration: duration(2 * time.Minute)},
+			25*time.Minute, int32(1), 5*time.Minute),
+	)
+
+	DescribeTable("rejecting invalid patterns",
+		func(p *oomv1beta1.PatternSpec) {
+			_, _, err := desiredReplicas(p, 3, time.Minute)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("linearRamp without a duration", &oomv1beta1.PatternSpec{Type: oomv1beta1.LinearRampPattern, To: 3}),
+		Entry("sine without a period", &oomv1beta1.PatternSpec{Type: oomv1beta1.SinePattern, To: 3}),
+		Entry("burst longer than its period",
+			&oomv1beta1.PatternSpec{Type: oomv1beta1.BurstPattern, To: 3, Period: duration(time.Minute), BurstDuration: duration(2 * time.Minute)}),
+	)
+})
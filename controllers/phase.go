@@ -0,0 +1,140 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+)
+
+const (
+	// maxPhaseHistory is the number of transitions kept in the status of an Oomer,
+	// older transitions are dropped.
+	maxPhaseHistory = 10
+
+	// createdReason is given for the transition of a new Oomer into Pending.
+	createdReason = "Created"
+)
+
+// phaseTransitions lists the phases which may be entered from each phase. An Oomer
+// starts as Pending, while Completed, Aborted and Failed are final.
+var phaseTransitions = map[oomv1beta1.OomerPhase][]oomv1beta1.OomerPhase{
+	"": {oomv1beta1.PendingPhase},
+	oomv1beta1.PendingPhase: {
+		oomv1beta1.InjectingPhase, oomv1beta1.PausedPhase,
+		oomv1beta1.CompletedPhase, oomv1beta1.AbortedPhase, oomv1beta1.FailedPhase,
+	},
+	oomv1beta1.InjectingPhase: {
+		oomv1beta1.OOMingPhase, oomv1beta1.PausedPhase,
+		oomv1beta1.CompletedPhase, oomv1beta1.AbortedPhase, oomv1beta1.FailedPhase,
+	},
+	oomv1beta1.OOMingPhase: {
+		oomv1beta1.PausedPhase,
+		oomv1beta1.CompletedPhase, oomv1beta1.AbortedPhase, oomv1beta1.FailedPhase,
+	},
+	oomv1beta1.PausedPhase: {
+		oomv1beta1.PendingPhase, oomv1beta1.InjectingPhase, oomv1beta1.OOMingPhase,
+		oomv1beta1.CompletedPhase, oomv1beta1.AbortedPhase, oomv1beta1.FailedPhase,
+	},
+}
+
+// canTransition returns whether an Oomer may move from one phase to another.
+func canTransition(from, to oomv1beta1.OomerPhase) bool {
+	for _, phase := range phaseTransitions[from] {
+		if phase == to {
+			return true
+		}
+	}
+	return false
+}
+
+// setPhase moves an Oomer into a phase and records the transition in its history,
+// returning whether it changed. Transitions which are not allowed are ignored, so
+// an Oomer stays in a final phase and does not return to Injecting once OOMing.
+func setPhase(o *oomv1beta1.Oomer, phase oomv1beta1.OomerPhase, reason string, now time.Time) bool {
+	if o.Status.Phase == phase || !canTransition(o.Status.Phase, phase) {
+		return false
+	}
+
+	o.Status.Phase = phase
+	o.Status.History = append(o.Status.History, oomv1beta1.PhaseTransition{
+		Phase:  phase,
+		Time:   metav1.NewTime(now),
+		Reason: reason,
+	})
+	if n := len(o.Status.History); n > maxPhaseHistory {
+		o.Status.History = o.Status.History[n-maxPhaseHistory:]
+	}
+	return true
+}
+
+// finishedPhase returns the phase of an Oomer whose scheduled duration has passed,
+// this is Failed when its verification checks did not pass.
+func finishedPhase(o *oomv1beta1.Oomer) (oomv1beta1.OomerPhase, string) {
+	if meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.VerificationFailedCondition) {
+		return oomv1beta1.FailedPhase, checksFailedReason
+	}
+	return oomv1beta1.CompletedPhase, scheduleElapsedReason
+}
+
+// injectingPhase returns the phase of an Oomer which is injecting, this is OOMing
+// once an OOMKilled container has been observed. Pods which cannot be listed are
+// logged and the Oomer is treated as not having OOMed yet.
+func (r *OomerReconciler) injectingPhase(ctx context.Context, o *oomv1beta1.Oomer) (oomv1beta1.OomerPhase, string) {
+	if o.Status.Phase == oomv1beta1.OOMingPhase {
+		return oomv1beta1.OOMingPhase, oomKilledReason
+	}
+
+	count, err := r.countOOMKilled(ctx, o)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "unable to count OOMKilled containers")
+	}
+	if count > 0 {
+		return oomv1beta1.OOMingPhase, oomKilledReason
+	}
+	return oomv1beta1.InjectingPhase, startedReason
+}
+
+// countOOMKilled returns the number of containers in the pods selected by an Oomer
+// which were OOMKilled since it started injecting. Earlier OOM kills of a targeted
+// workload would otherwise stop the Squeeze mode before it lowers any limit.
+func (r *OomerReconciler) countOOMKilled(ctx context.Context, o *oomv1beta1.Oomer) (int32, error) {
+	selector, err := oomkill.PodSelector(ctx, r, o)
+	if err != nil {
+		return 0, err
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(o.ObjectMeta.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return 0, err
+	}
+
+	var since time.Time
+	if o.Status.StartTime != nil {
+		since = o.Status.StartTime.Time
+	}
+	return oomkill.CountSince(pods.Items, since), nil
+}
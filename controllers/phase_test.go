@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Oomer phases", func() {
+
+	ctx := context.Background()
+	now := time.Date(2023, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	inPhase := func(phase oomv1beta1.OomerPhase) *oomv1beta1.Oomer {
+		return &oomv1beta1.Oomer{Status: oomv1beta1.OomerStatus{Phase: phase}}
+	}
+
+	DescribeTable("Transitions",
+		func(from, to oomv1beta1.OomerPhase, allowed bool) {
+			o := inPhase(from)
+			Expect(setPhase(o, to, "Testing", now)).To(Equal(allowed))
+
+			if allowed {
+				Expect(o.Status.Phase).To(Equal(to))
+				Expect(o.Status.History).To(Equal([]oomv1beta1.PhaseTransition{
+					{Phase: to, Time: metav1.NewTime(now), Reason: "Testing"},
+				}))
+			} else {
+				Expect(o.Status.Phase).To(Equal(from))
+				Expect(o.Status.History).To(BeEmpty())
+			}
+		},
+		Entry("new to Pending", oomv1beta1.OomerPhase(""), oomv1beta1.PendingPhase, true),
+		Entry("new to Injecting", oomv1beta1.OomerPhase(""), oomv1beta1.InjectingPhase, false),
+
+		Entry("Pending to Injecting", oomv1beta1.PendingPhase, oomv1beta1.InjectingPhase, true),
+		Entry("Pending to Paused", oomv1beta1.PendingPhase, oomv1beta1.PausedPhase, true),
+		Entry("Pending to Completed", oomv1beta1.PendingPhase, oomv1beta1.CompletedPhase, true),
+		Entry("Pending to Aborted", oomv1beta1.PendingPhase, oomv1beta1.AbortedPhase, true),
+		Entry("Pending to Failed", oomv1beta1.PendingPhase, oomv1beta1.FailedPhase, true),
+		Entry("Pending to OOMing", oomv1beta1.PendingPhase, oomv1beta1.OOMingPhase, false),
+
+		Entry("Injecting to OOMing", oomv1beta1.InjectingPhase, oomv1beta1.OOMingPhase, true),
+		Entry("Injecting to Paused", oomv1beta1.InjectingPhase, oomv1beta1.PausedPhase, true),
+		Entry("Injecting to Completed", oomv1beta1.InjectingPhase, oomv1beta1.CompletedPhase, true),
+		Entry("Injecting to Aborted", oomv1beta1.InjectingPhase, oomv1beta1.AbortedPhase, true),
+		Entry("Injecting to Failed", oomv1beta1.InjectingPhase, oomv1beta1.FailedPhase, true),
+		Entry("Injecting to Pending", oomv1beta1.InjectingPhase, oomv1beta1.PendingPhase, false),
+
+		Entry("OOMing to Paused", oomv1beta1.OOMingPhase, oomv1beta1.PausedPhase, true),
+		Entry("OOMing to Completed", oomv1beta1.OOMingPhase, oomv1beta1.CompletedPhase, true),
+		Entry("OOMing to Aborted", oomv1beta1.OOMingPhase, oomv1beta1.AbortedPhase, true),
+		Entry("OOMing to Failed", oomv1beta1.OOMingPhase, oomv1beta1.FailedPhase, true),
+		Entry("OOMing to Injecting", oomv1beta1.OOMingPhase, oomv1beta1.InjectingPhase, false),
+		Entry("OOMing to Pending", oomv1beta1.OOMingPhase, oomv1beta1.PendingPhase, false),
+
+		Entry("Paused to Pending", oomv1beta1.PausedPhase, oomv1beta1.PendingPhase, true),
+		Entry("Paused to Injecting", oomv1beta1.PausedPhase, oomv1beta1.InjectingPhase, true),
+		Entry("Paused to OOMing", oomv1beta1.PausedPhase, oomv1beta1.OOMingPhase, true),
+		Entry("Paused to Completed", oomv1beta1.PausedPhase, oomv1beta1.CompletedPhase, true),
+		Entry("Paused to Aborted", oomv1beta1.PausedPhase, oomv1beta1.AbortedPhase, true),
+		Entry("Paused to Failed", oomv1beta1.PausedPhase, oomv1beta1.FailedPhase, true),
+
+		Entry("Completed to Injecting", oomv1beta1.CompletedPhase, oomv1beta1.InjectingPhase, false),
+		Entry("Completed to Failed", oomv1beta1.CompletedPhase, oomv1beta1.FailedPhase, false),
+		Entry("Aborted to Pending", oomv1beta1.AbortedPhase, oomv1beta1.PendingPhase, false),
+		Entry("Aborted to Completed", oomv1beta1.AbortedPhase, oomv1beta1.CompletedPhase, false),
+		Entry("Failed to Paused", oomv1beta1.FailedPhase, oomv1beta1.PausedPhase, false),
+		Entry("Failed to Completed", oomv1beta1.FailedPhase, oomv1beta1.CompletedPhase, false),
+	)
+
+	It("Should not record a transition into the current phase", func() {
+		o := inPhase(oomv1beta1.PendingPhase)
+		Expect(setPhase(o, oomv1beta1.PendingPhase, budgetExceededReason, now)).To(BeFalse())
+		Expect(o.Status.History).To(BeEmpty())
+	})
+
+	It("Should keep only the most recent transitions", func() {
+		o := inPhase(oomv1beta1.PendingPhase)
+		for i := 0; i < maxPhaseHistory; i++ {
+			Expect(setPhase(o, oomv1beta1.PausedPhase, pausedReason, now.Add(time.Duration(2*i)*time.Minute))).To(BeTrue())
+			Expect(setPhase(o, oomv1beta1.PendingPhase, scheduledReason, now.Add(time.Duration(2*i+1)*time.Minute))).To(BeTrue())
+		}
+
+		Expect(o.Status.History).To(HaveLen(maxPhaseHistory))
+		Expect(o.Status.History[0].Time.Time).To(Equal(now.Add(time.Duration(maxPhaseHistory) * time.Minute)))
+		Expect(o.Status.History[maxPhaseHistory-1].Phase).To(Equal(oomv1beta1.PendingPhase))
+	})
+
+	It("Should fail a finished Oomer whose verification failed", func() {
+		o := inPhase(oomv1beta1.InjectingPhase)
+		phase, _ := finishedPhase(o)
+		Expect(phase).To(Equal(oomv1beta1.CompletedPhase))
+
+		setCondition(o, oomv1beta1.VerificationFailedCondition, metav1.ConditionTrue, checksFailedReason, "")
+		phase, reason := finishedPhase(o)
+		Expect(phase).To(Equal(oomv1beta1.FailedPhase))
+		Expect(reason).To(Equal(checksFailedReason))
+	})
+
+	It("Should become OOMing once an OOMKilled container is observed", func() {
+		o := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "drill", Namespace: "default"},
+			Spec: oomv1beta1.OomerSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "oomer"}},
+			},
+			Status: oomv1beta1.OomerStatus{Phase: oomv1beta1.InjectingPhase},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "drill-abc", Namespace: "default", Labels: map[string]string{"app": "oomer"}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "oomer",
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Reason: oomKilledReason,
+				}},
+			}}},
+		}
+
+		r := &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()}
+		phase, _ := r.injectingPhase(ctx, o)
+		Expect(phase).To(Equal(oomv1beta1.InjectingPhase))
+		Expect(awaitingFirstOOM(o)).To(BeTrue())
+
+		r = &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(pod).Build()}
+		phase, reason := r.injectingPhase(ctx, o)
+		Expect(phase).To(Equal(oomv1beta1.OOMingPhase))
+		Expect(reason).To(Equal(oomKilledReason))
+		Expect(setPhase(o, phase, reason, now)).To(BeTrue())
+		Expect(awaitingFirstOOM(o)).To(BeFalse())
+
+		By("staying OOMing once the pods have been replaced")
+		r = &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()}
+		phase, _ = r.injectingPhase(ctx, o)
+		Expect(phase).To(Equal(oomv1beta1.OOMingPhase))
+	})
+})
@@ -0,0 +1,64 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/allocator"
+)
+
+// Reasons given for the Ready condition of an Oomer.
+const (
+	validReason       = "Valid"
+	invalidSpecReason = "InvalidSpec"
+	unsupportedReason = "Unsupported"
+)
+
+// validateSpec returns why an Oomer cannot be injected as specified, the message
+// is empty when it can. These are not retried, as only a change to the Oomer, or
+// how the operator is deployed, can resolve them.
+func (r *OomerReconciler) validateSpec(o *oomv1beta1.Oomer) (string, string) {
+	if _, err := allocator.Env(o); err != nil {
+		return invalidSpecReason, err.Error()
+	}
+
+	// This is also rejected by the CRD, Oomers created before the rule was added
+	// are reported here.
+	if o.Spec.Mode == oomv1beta1.EphemeralMode && requiresRemoval(o) {
+		return invalidSpecReason, "ephemeral containers cannot be removed, so the Ephemeral mode does not support schedule.duration, paused or abortWhen"
+	}
+
+	// A Role cannot grant access to nodes, so listing them would fail on every
+	// reconcile.
+	if o.Spec.Mode == oomv1beta1.NodePressureMode && r.Namespaced {
+		return unsupportedReason, "the NodePressure mode requires listing nodes, which is not available when the operator only watches the namespaces given with --namespace"
+	}
+
+	// Pod-level resources are only found to be unsupported once the Deployment is
+	// applied, this is kept until the Oomer changes rather than applying it again.
+	if o.Spec.Mode == oomv1beta1.MultiContainerMode {
+		ready := meta.FindStatusCondition(o.Status.Conditions, oomv1beta1.ReadyCondition)
+		if ready != nil && ready.Status == metav1.ConditionFalse && ready.Reason == unsupportedReason && ready.ObservedGeneration == o.ObjectMeta.Generation {
+			return ready.Reason, ready.Message
+		}
+	}
+
+	return validReason, ""
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Oomer readiness", func() {
+
+	It("Should report invalid timing as not ready without requeueing", func() {
+		ctx := context.Background()
+		o := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid-timing", Namespace: "default", Finalizers: []string{oomerFinalizer}},
+			Spec: oomv1beta1.OomerSpec{
+				Replicas: 1,
+				Timing: &oomv1beta1.TimingSpec{
+					MinDelay: &metav1.Duration{Duration: time.Minute},
+					MaxDelay: &metav1.Duration{Duration: time.Second},
+				},
+			},
+		}
+		r := snapshotReconciler(o)
+
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(o)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+
+		invalid := &oomv1beta1.Oomer{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(o), invalid)).To(Succeed())
+		ready := meta.FindStatusCondition(invalid.Status.Conditions, oomv1beta1.ReadyCondition)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(invalidSpecReason))
+		Expect(ready.Message).To(ContainSubstring("minDelay"))
+		Expect(meta.IsStatusConditionTrue(invalid.Status.Conditions, oomv1beta1.InjectingCondition)).To(BeFalse())
+
+		By("not creating the allocators")
+		err = r.Get(ctx, client.ObjectKeyFromObject(o), &appsv1.Deployment{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("Should report the NodePressure mode as unsupported in namespaced mode", func() {
+		o := &oomv1beta1.Oomer{Spec: oomv1beta1.OomerSpec{Mode: oomv1beta1.NodePressureMode}}
+
+		_, message := (&OomerReconciler{}).validateSpec(o)
+		Expect(message).To(BeEmpty())
+
+		reason, message := (&OomerReconciler{Namespaced: true}).validateSpec(o)
+		Expect(reason).To(Equal(unsupportedReason))
+		Expect(message).To(ContainSubstring("--namespace"))
+	})
+})
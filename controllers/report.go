@@ -0,0 +1,171 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+)
+
+// oomKilledReason is the reason given for a container which was terminated by the OOM killer.
+const oomKilledReason = oomkill.Reason
+
+// reportName is the name of the OomReport generated for an Oomer, this is stable for
+// a single run so that a report is not generated twice.
+func reportName(o *oomv1beta1.Oomer) string {
+	return fmt.Sprintf("%s-%d", o.ObjectMeta.Name, o.Status.StartTime.Unix())
+}
+
+// buildReport summarises the affected pods, and the events observed for them, into
+// an OomReport for an Oomer which has started.
+func buildReport(o *oomv1beta1.Oomer, pods []corev1.Pod, events []corev1.Event, reason oomv1beta1.ReportReason, end time.Time) *oomv1beta1.OomReport {
+	report := &oomv1beta1.OomReport{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      reportName(o),
+			Namespace: o.ObjectMeta.Namespace,
+			Labels:    map[string]string{oomv1beta1.OomerLabel: o.ObjectMeta.Name},
+		},
+		Spec: oomv1beta1.OomReportSpec{
+			Oomer:     o.ObjectMeta.Name,
+			Reason:    reason,
+			StartTime: *o.Status.StartTime,
+			EndTime:   metav1.NewTime(end),
+			OomerSpec: *o.Spec.DeepCopy(),
+		},
+	}
+
+	// Events about the Oomer, and the Deployment or DaemonSet it created, share its name.
+	involved := map[string]bool{o.ObjectMeta.Name: true}
+	nodes := map[string]bool{}
+	var firstOOM *metav1.Time
+
+	for _, pod := range pods {
+		involved[pod.ObjectMeta.Name] = true
+		if pod.Spec.NodeName != "" {
+			nodes[pod.Spec.NodeName] = true
+		}
+
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.ContainerStatuses...), pod.Status.EphemeralContainerStatuses...)
+		for _, status := range statuses {
+			c := oomv1beta1.ContainerReport{
+				Pod:          pod.ObjectMeta.Name,
+				Container:    status.Name,
+				Node:         pod.Spec.NodeName,
+				RestartCount: status.RestartCount,
+				OOMKilledAt:  oomkill.KilledAt(status),
+			}
+			c.OOMKilled = c.OOMKilledAt != nil
+
+			if c.OOMKilled {
+				report.Spec.OOMKilledContainers++
+				if firstOOM == nil || c.OOMKilledAt.Before(firstOOM) {
+					firstOOM = c.OOMKilledAt
+				}
+			}
+
+			report.Spec.TotalRestarts += status.RestartCount
+			report.Spec.Containers = append(report.Spec.Containers, c)
+		}
+	}
+
+	if firstOOM != nil {
+		d := firstOOM.Sub(o.Status.StartTime.Time)
+		if d < 0 {
+			d = 0
+		}
+		report.Spec.TimeToFirstOOM = &metav1.Duration{Duration: d}
+	}
+
+	for node := range nodes {
+		report.Spec.Nodes = append(report.Spec.Nodes, node)
+	}
+	sort.Strings(report.Spec.Nodes)
+
+	for _, e := range events {
+		if !involved[e.InvolvedObject.Name] {
+			continue
+		}
+
+		lastTimestamp := e.LastTimestamp
+		if lastTimestamp.IsZero() {
+			lastTimestamp = metav1.NewTime(e.EventTime.Time)
+		}
+
+		report.Spec.Events = append(report.Spec.Events, oomv1beta1.EventReport{
+			Object:        e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name,
+			Type:          e.Type,
+			Reason:        e.Reason,
+			Message:       e.Message,
+			Count:         e.Count,
+			LastTimestamp: &lastTimestamp,
+		})
+	}
+	sort.SliceStable(report.Spec.Events, func(i, j int) bool {
+		return report.Spec.Events[i].LastTimestamp.Before(report.Spec.Events[j].LastTimestamp)
+	})
+
+	return report
+}
+
+// generateReport creates the OomReport for an Oomer, this must happen before the
+// resources it created are removed. Nothing is generated for an Oomer which never
+// started and an existing report for the same run is left as it is.
+func (r *OomerReconciler) generateReport(ctx context.Context, o *oomv1beta1.Oomer, reason oomv1beta1.ReportReason) error {
+	log := log.FromContext(ctx)
+
+	if o.Status.StartTime == nil {
+		return nil
+	}
+
+	var pods corev1.PodList
+	selector, err := oomkill.PodSelector(ctx, r, o)
+	if err != nil {
+		// The targeted workload may already be gone, this should not prevent the
+		// report, or the deletion of the Oomer.
+		log.Error(err, "unable to select pods for report")
+	} else if err := r.List(ctx, &pods, client.InNamespace(o.ObjectMeta.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return err
+	}
+
+	var events corev1.EventList
+	if err := r.List(ctx, &events, client.InNamespace(o.ObjectMeta.Namespace)); err != nil {
+		return err
+	}
+
+	report := buildReport(o, pods.Items, events.Items, reason, time.Now())
+	if err := r.Create(ctx, report); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		return err
+	}
+
+	log.Info("generated report", "report", report.ObjectMeta.Name, "oomKilled", report.Spec.OOMKilledContainers)
+
+	return nil
+}
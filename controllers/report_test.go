@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Oomer reports", func() {
+
+	start := time.Date(2023, time.January, 1, 12, 0, 0, 0, time.UTC)
+	startTime := metav1.NewTime(start)
+
+	o := &oomv1beta1.Oomer{
+		ObjectMeta: metav1.ObjectMeta{Name: "gameday", Namespace: "default"},
+		Spec:       oomv1beta1.OomerSpec{Replicas: 2},
+		Status:     oomv1beta1.OomerStatus{StartTime: &startTime},
+	}
+
+	oomKilled := func(at time.Time) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			ExitCode:   137,
+			Reason:     oomKilledReason,
+			FinishedAt: metav1.NewTime(at),
+		}}
+	}
+
+	pods := []corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "gameday-a"},
+			Spec:       corev1.PodSpec{NodeName: "node-b"},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:                 "oomer",
+				RestartCount:         3,
+				LastTerminationState: oomKilled(start.Add(2 * time.Minute)),
+			}}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "gameday-b"},
+			Spec:       corev1.PodSpec{NodeName: "node-a"},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "oomer",
+				RestartCount: 1,
+				State:        oomKilled(start.Add(time.Minute)),
+			}}},
+		},
+	}
+
+	events := []corev1.Event{
+		{
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "gameday-a"},
+			Type:           corev1.EventTypeWarning,
+			Reason:         "BackOff",
+			LastTimestamp:  metav1.NewTime(start.Add(3 * time.Minute)),
+		},
+		{
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "unrelated"},
+			Reason:         "Pulled",
+		},
+	}
+
+	It("Should summarise the affected pods", func() {
+		report := buildReport(o, pods, events, oomv1beta1.CompletedReason, start.Add(10*time.Minute))
+
+		Expect(report.ObjectMeta.Name).Should(Equal("gameday-1672574400"))
+		Expect(report.ObjectMeta.Labels).Should(HaveKeyWithValue(oomv1beta1.OomerLabel, "gameday"))
+		Expect(report.Spec.Reason).Should(Equal(oomv1beta1.CompletedReason))
+		Expect(report.Spec.OomerSpec.Replicas).Should(Equal(int32(2)))
+		Expect(report.Spec.OOMKilledContainers).Should(Equal(int32(2)))
+		Expect(report.Spec.TotalRestarts).Should(Equal(int32(4)))
+		Expect(report.Spec.TimeToFirstOOM.Duration).Should(Equal(time.Minute))
+		Expect(report.Spec.Nodes).Should(Equal([]string{"node-a", "node-b"}))
+		Expect(report.Spec.Containers).Should(HaveLen(2))
+		Expect(oomkill.Count(pods)).Should(Equal(report.Spec.OOMKilledContainers))
+
+		By("only including events for the affected objects")
+		Expect(report.Spec.Events).Should(HaveLen(1))
+		Expect(report.Spec.Events[0].Object).Should(Equal("Pod/gameday-a"))
+	})
+
+	It("Should not count containers which were not OOMKilled", func() {
+		running := []corev1.Pod{{
+			ObjectMeta: metav1.ObjectMeta{Name: "gameday-c"},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "oomer",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Reason:   "Error",
+				}},
+				LastTerminationState: oomKilled(start),
+			}}},
+		}}
+
+		report := buildReport(o, running, nil, oomv1beta1.DeletedReason, start)
+		Expect(report.Spec.OOMKilledContainers).Should(BeZero())
+		Expect(report.Spec.TimeToFirstOOM).Should(BeNil())
+	})
+
+})
+
+var _ = Describe("Oomer Operator reporting", func() {
+	const (
+		operatorName   = "test-report"
+		oomerNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	ctx := context.Background()
+
+	Context("When deleting the object", func() {
+		It("Should generate an immutable report", func() {
+			oom := &oomv1beta1.Oomer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      operatorName,
+					Namespace: oomerNamespace,
+				},
+				Spec: oomv1beta1.OomerSpec{Replicas: 1},
+			}
+			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+			lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
+			Eventually(func() bool {
+				o := &oomv1beta1.Oomer{}
+				if err := k8sClient.Get(ctx, lookupOomer, o); err != nil {
+					return false
+				}
+				return o.Status.StartTime != nil && len(o.ObjectMeta.Finalizers) > 0
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+
+			var reports oomv1beta1.OomReportList
+			Eventually(func() []oomv1beta1.OomReport {
+				if err := k8sClient.List(ctx, &reports, client.InNamespace(oomerNamespace), client.MatchingLabels{oomv1beta1.OomerLabel: operatorName}); err != nil {
+					return nil
+				}
+				return reports.Items
+			}, timeout, interval).Should(HaveLen(1))
+
+			report := reports.Items[0]
+			Expect(report.Spec.Reason).Should(Equal(oomv1beta1.DeletedReason))
+
+			By("rejecting changes to the report")
+			report.Spec.OOMKilledContainers = 10
+			Expect(k8sClient.Update(ctx, &report)).ShouldNot(Succeed())
+		})
+	})
+})
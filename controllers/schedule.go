@@ -0,0 +1,95 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// Reasons given for the conditions of an Oomer.
+const (
+	scheduledReason       = "Scheduled"
+	startedReason         = "Started"
+	scheduleElapsedReason = "ScheduleElapsed"
+	pausedReason          = "Paused"
+)
+
+// untilScheduledStart returns how long remains before an Oomer is scheduled to
+// start, this is zero when it has no start time or the start time has passed.
+func untilScheduledStart(s *oomv1beta1.ScheduleSpec, now time.Time) time.Duration {
+	if s == nil || s.StartAt == nil || !now.Before(s.StartAt.Time) {
+		return 0
+	}
+	return s.StartAt.Time.Sub(now)
+}
+
+// scheduleRemaining returns how long remains before the scheduled duration of an
+// Oomer has passed, measured from when injection started. This is zero or negative
+// once it has passed, the boolean is false when the Oomer runs indefinitely.
+func scheduleRemaining(o *oomv1beta1.Oomer, now time.Time) (time.Duration, bool) {
+	s := o.Spec.Schedule
+	if s == nil || s.Duration == nil || o.Status.StartTime == nil {
+		return 0, false
+	}
+	return o.Status.StartTime.Time.Add(s.Duration.Duration).Sub(now), true
+}
+
+// scheduledRequeue shortens a requeue so that an Oomer is reconciled again as soon
+// as its scheduled duration has passed, and so that abort criteria, pending checks
+// and the first OOM are polled.
+func scheduledRequeue(o *oomv1beta1.Oomer, after time.Duration, now time.Time) time.Duration {
+	if len(o.Spec.AbortWhen) > 0 && abortInterval < after {
+		after = abortInterval
+	}
+	if awaitingFirstOOM(o) && notifyInterval < after {
+		after = notifyInterval
+	}
+	if verificationPending(o) && verifyInterval < after {
+		after = verifyInterval
+	}
+	if wait := notifyRequeue(o, now); wait > 0 && wait < after {
+		after = wait
+	}
+	if remaining, ok := scheduleRemaining(o, now); ok && remaining > 0 && remaining < after {
+		return remaining
+	}
+	return after
+}
+
+// setCondition sets a condition on the status of an Oomer, returning whether it
+// changed so that the status is only updated when required.
+func setCondition(o *oomv1beta1.Oomer, conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	existing := meta.FindStatusCondition(o.Status.Conditions, conditionType)
+	if existing != nil && existing.Status == status && existing.Reason == reason &&
+		existing.Message == message && existing.ObservedGeneration == o.ObjectMeta.Generation {
+		return false
+	}
+
+	meta.SetStatusCondition(&o.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: o.ObjectMeta.Generation,
+	})
+	return true
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Oomer schedule", func() {
+
+	now := time.Date(2023, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	It("Should wait until the scheduled start time", func() {
+		startAt := metav1.NewTime(now.Add(time.Minute))
+		Expect(untilScheduledStart(&oomv1beta1.ScheduleSpec{StartAt: &startAt}, now)).Should(Equal(time.Minute))
+		Expect(untilScheduledStart(&oomv1beta1.ScheduleSpec{StartAt: &startAt}, now.Add(time.Hour))).Should(BeZero())
+		Expect(untilScheduledStart(nil, now)).Should(BeZero())
+	})
+
+	It("Should measure the scheduled duration from the start time", func() {
+		startTime := metav1.NewTime(now)
+		o := &oomv1beta1.Oomer{
+			Spec: oomv1beta1.OomerSpec{
+				Schedule: &oomv1beta1.ScheduleSpec{Duration: &metav1.Duration{Duration: 10 * time.Minute}},
+			},
+			Status: oomv1beta1.OomerStatus{StartTime: &startTime},
+		}
+
+		remaining, ok := scheduleRemaining(o, now.Add(4*time.Minute))
+		Expect(ok).To(BeTrue())
+		Expect(remaining).Should(Equal(6 * time.Minute))
+
+		By("requeueing when the duration passes rather than after the full interval")
+		Expect(scheduledRequeue(o, config.DefaultRequeueInterval, now.Add(8*time.Minute))).Should(Equal(2 * time.Minute))
+		Expect(scheduledRequeue(o, time.Minute, now.Add(8*time.Minute))).Should(Equal(time.Minute))
+
+		By("running indefinitely without a duration")
+		o.Spec.Schedule = nil
+		_, ok = scheduleRemaining(o, now)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("Should only report a changed condition once", func() {
+		o := &oomv1beta1.Oomer{}
+		Expect(setCondition(o, oomv1beta1.InjectingCondition, metav1.ConditionTrue, startedReason, "")).To(BeTrue())
+		Expect(setCondition(o, oomv1beta1.InjectingCondition, metav1.ConditionTrue, startedReason, "")).To(BeFalse())
+		Expect(setCondition(o, oomv1beta1.InjectingCondition, metav1.ConditionFalse, scheduleElapsedReason, "")).To(BeTrue())
+	})
+
+	It("Should complete a paused oomer once the scheduled duration passes", func() {
+		ctx := context.Background()
+		startTime := metav1.NewTime(time.Now().Add(-time.Hour))
+		o := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "paused", Namespace: "default", Finalizers: []string{oomerFinalizer}},
+			Spec: oomv1beta1.OomerSpec{
+				Paused:   true,
+				Schedule: &oomv1beta1.ScheduleSpec{Duration: &metav1.Duration{Duration: 10 * time.Minute}},
+			},
+			Status: oomv1beta1.OomerStatus{Phase: oomv1beta1.PausedPhase, StartTime: &startTime},
+		}
+		r := snapshotReconciler(o)
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(o)})
+		Expect(err).NotTo(HaveOccurred())
+
+		completed := &oomv1beta1.Oomer{}
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(o), completed)).To(Succeed())
+		Expect(meta.IsStatusConditionTrue(completed.Status.Conditions, oomv1beta1.CompletedCondition)).To(BeTrue())
+		Expect(completed.Status.Phase).To(Equal(oomv1beta1.CompletedPhase))
+
+		By("requeueing a paused oomer when the scheduled duration passes")
+		startTime = metav1.NewTime(time.Now())
+		o.Status = oomv1beta1.OomerStatus{Phase: oomv1beta1.PausedPhase, StartTime: &startTime}
+		r = snapshotReconciler(o)
+
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(o)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically("~", 10*time.Minute, time.Second))
+	})
+})
+
+var _ = Describe("Oomer Operator with a schedule", func() {
+	const (
+		operatorName   = "test-scheduled"
+		oomerNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	ctx := context.Background()
+
+	Context("When the scheduled duration passes", func() {
+		It("Should remove the deployment and complete", func() {
+			oom := &oomv1beta1.Oomer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      operatorName,
+					Namespace: oomerNamespace,
+				},
+				Spec: oomv1beta1.OomerSpec{
+					Replicas: 1,
+					Schedule: &oomv1beta1.ScheduleSpec{Duration: &metav1.Duration{Duration: 2 * time.Second}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+			lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
+			Eventually(func() bool {
+				o := &oomv1beta1.Oomer{}
+				if err := k8sClient.Get(ctx, lookupOomer, o); err != nil {
+					return false
+				}
+				return meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.CompletedCondition)
+			}, timeout, interval).Should(BeTrue())
+
+			completed := &oomv1beta1.Oomer{}
+			Expect(k8sClient.Get(ctx, lookupOomer, completed)).Should(Succeed())
+			Expect(completed.Status.Phase).Should(Equal(oomv1beta1.CompletedPhase))
+			Expect(completed.Status.History[0].Phase).Should(Equal(oomv1beta1.PendingPhase))
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, lookupOomer, &appsv1.Deployment{})
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+		})
+	})
+	Context("When the oomer is paused", func() {
+		It("Should remove the deployment until resumed", func() {
+			const pausedName = "test-paused"
+
+			oom := &oomv1beta1.Oomer{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      pausedName,
+					Namespace: oomerNamespace,
+				},
+				Spec: oomv1beta1.OomerSpec{Replicas: 1},
+			}
+			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+			lookupOomer := types.NamespacedName{Name: pausedName, Namespace: oomerNamespace}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, lookupOomer, &appsv1.Deployment{})
+			}, timeout, interval).Should(Succeed())
+
+			By("pausing the oomer")
+			Expect(k8sClient.Get(ctx, lookupOomer, oom)).Should(Succeed())
+			oom.Spec.Paused = true
+			Expect(k8sClient.Update(ctx, oom)).Should(Succeed())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, lookupOomer, &appsv1.Deployment{})
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			By("resuming the oomer")
+			Expect(k8sClient.Get(ctx, lookupOomer, oom)).Should(Succeed())
+			oom.Spec.Paused = false
+			Expect(k8sClient.Update(ctx, oom)).Should(Succeed())
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, lookupOomer, &appsv1.Deployment{})
+			}, timeout, interval).Should(Succeed())
+
+			Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+		})
+	})
+})
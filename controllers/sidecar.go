@@ -0,0 +1,67 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/allocator"
+)
+
+// observeSidecars counts the pods which have had a sidecar injected for an Oomer.
+// Injection itself happens at pod creation time within the pod webhook.
+func (r *OomerReconciler) observeSidecars(ctx context.Context, o *oomv1beta1.Oomer) error {
+	log := log.FromContext(ctx)
+
+	if o.Spec.Target == nil || o.Spec.Target.Selector == nil {
+		return fmt.Errorf("%s mode requires a target selector", o.Spec.Mode)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(o.Spec.Target.Selector)
+	if err != nil {
+		return err
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(o.ObjectMeta.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return err
+	}
+
+	injected := int32(0)
+	for i := range pods.Items {
+		if allocator.HasSidecar(&pods.Items[i], o) {
+			injected++
+		}
+	}
+
+	if o.Status.ObservedReplicas != injected {
+		o.Status.ObservedReplicas = injected
+		if err := r.applyStatus(ctx, o); err != nil {
+			log.Error(err, "unable to update oomer status observed replicas", "ObservedReplicas", injected)
+			return err
+		}
+	}
+
+	return nil
+}
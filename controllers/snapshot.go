@@ -0,0 +1,162 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+const (
+	// snapshotDeploymentKey is the key of a snapshot ConfigMap holding the name
+	// of the Deployment which was modified.
+	snapshotDeploymentKey = "deployment"
+
+	// snapshotResourcesKey is the key of a snapshot ConfigMap holding the original
+	// resources of each modified container.
+	snapshotResourcesKey = "resources"
+)
+
+// errSnapshotNameTaken is returned when the ConfigMap named for the snapshot of an
+// Oomer exists but was not created for it, so it must not be overwritten or deleted.
+var errSnapshotNameTaken = errors.New("snapshot name is taken")
+
+// targetSnapshot is the state of a targeted workload before it was modified by
+// an Oomer.
+type targetSnapshot struct {
+	// Deployment is the name of the modified Deployment, this is kept as the
+	// target of the Oomer may be changed before it is restored.
+	Deployment string
+
+	// Resources are the original resources of each modified container.
+	Resources map[string]corev1.ResourceRequirements
+}
+
+// snapshotName returns the name of the ConfigMap holding the snapshot of an Oomer.
+func snapshotName(o *oomv1beta1.Oomer) string {
+	return o.ObjectMeta.Name + "-snapshot"
+}
+
+// snapshotOf returns whether a ConfigMap holds the snapshot of an Oomer, these are
+// labelled with the name of the Oomer when saved.
+func snapshotOf(cm *corev1.ConfigMap, o *oomv1beta1.Oomer) bool {
+	return cm.ObjectMeta.Labels[oomv1beta1.OomerLabel] == o.ObjectMeta.Name
+}
+
+// getSnapshotConfigMap returns the ConfigMap holding the snapshot of an Oomer,
+// this is nil when it does not exist. errSnapshotNameTaken is returned when a
+// ConfigMap of the same name exists which is not a snapshot of the Oomer.
+func (r *OomerReconciler) getSnapshotConfigMap(ctx context.Context, o *oomv1beta1.Oomer) (*corev1.ConfigMap, error) {
+	cm := &corev1.ConfigMap{}
+	key := types.NamespacedName{Name: snapshotName(o), Namespace: o.ObjectMeta.Namespace}
+	if err := r.reader().Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !snapshotOf(cm, o) {
+		return nil, fmt.Errorf("%w: configmap %s is not a snapshot of oomer %s", errSnapshotNameTaken, cm.ObjectMeta.Name, o.ObjectMeta.Name)
+	}
+	return cm, nil
+}
+
+// reader returns the reader used for snapshots, these are read from the API
+// server when possible so that a snapshot which was just saved is never missed.
+func (r *OomerReconciler) reader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// getSnapshot returns the snapshot of the workload targeted by an Oomer, this
+// is nil when the Oomer has not modified a workload.
+func (r *OomerReconciler) getSnapshot(ctx context.Context, o *oomv1beta1.Oomer) (*targetSnapshot, error) {
+	cm, err := r.getSnapshotConfigMap(ctx, o)
+	if cm == nil || err != nil {
+		return nil, err
+	}
+
+	s := &targetSnapshot{Deployment: cm.Data[snapshotDeploymentKey]}
+	if err := json.Unmarshal([]byte(cm.Data[snapshotResourcesKey]), &s.Resources); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// saveSnapshot applies the snapshot of the workload targeted by an Oomer to a
+// ConfigMap labelled with the name of the Oomer. This must succeed before the
+// workload is modified, so that it can always be restored by the finalizer,
+// including after the operator has restarted. The ConfigMap has no owner
+// reference, as the garbage collector could then delete it before the finalizer
+// has restored the workload, it is deleted by restoreTarget instead.
+func (r *OomerReconciler) saveSnapshot(ctx context.Context, o *oomv1beta1.Oomer, s *targetSnapshot) error {
+	if _, err := r.getSnapshotConfigMap(ctx, o); err != nil {
+		return err
+	}
+
+	resources, err := json.Marshal(s.Resources)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      snapshotName(o),
+			Namespace: o.ObjectMeta.Namespace,
+			Labels:    map[string]string{oomv1beta1.OomerLabel: o.ObjectMeta.Name},
+		},
+		Data: map[string]string{
+			snapshotDeploymentKey: s.Deployment,
+			snapshotResourcesKey:  string(resources),
+		},
+	}
+
+	log.FromContext(ctx).Info("saving target snapshot", "configMap", cm.ObjectMeta.Name, "deployment", s.Deployment)
+	return r.apply(ctx, cm)
+}
+
+// deleteSnapshot deletes the snapshot of an Oomer once its target has been
+// restored. A ConfigMap which is not a snapshot of the Oomer is left in place.
+func (r *OomerReconciler) deleteSnapshot(ctx context.Context, o *oomv1beta1.Oomer) error {
+	cm, err := r.getSnapshotConfigMap(ctx, o)
+	if errors.Is(err, errSnapshotNameTaken) {
+		return nil
+	} else if cm == nil || err != nil {
+		return err
+	}
+
+	return client.IgnoreNotFound(r.Delete(ctx, cm, client.Preconditions{UID: &cm.ObjectMeta.UID}))
+}
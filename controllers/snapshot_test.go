@@ -0,0 +1,315 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// applyClient emulates server-side apply, which the fake client does not support,
+// by creating or replacing the applied object. Dry runs leave the object as applied.
+type applyClient struct {
+	client.Client
+}
+
+func (c applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	if len((&client.PatchOptions{}).ApplyOptions(opts).DryRun) > 0 {
+		return nil
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing); apierrors.IsNotFound(err) {
+		return c.Create(ctx, obj)
+	} else if err != nil {
+		return err
+	}
+
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return c.Update(ctx, obj)
+}
+
+func (c applyClient) Status() client.SubResourceWriter {
+	return applyStatusWriter{c.Client.Status(), c.Client}
+}
+
+// applyStatusWriter emulates server-side apply of the status subresource by
+// replacing the status of the existing object with the applied status.
+type applyStatusWriter struct {
+	client.SubResourceWriter
+	reader client.Reader
+}
+
+func (w applyStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	u, ok := obj.(*unstructured.Unstructured)
+	if patch.Type() != types.ApplyPatchType || !ok {
+		return w.SubResourceWriter.Patch(ctx, obj, patch, opts...)
+	}
+
+	existing := u.DeepCopy()
+	if err := w.reader.Get(ctx, client.ObjectKeyFromObject(u), existing); err != nil {
+		return err
+	}
+	existing.Object["status"] = u.Object["status"]
+	if err := w.SubResourceWriter.Update(ctx, existing); err != nil {
+		return err
+	}
+	u.SetResourceVersion(existing.GetResourceVersion())
+	return nil
+}
+
+// snapshotReconciler returns a reconciler which is able to save snapshots of the
+// given objects.
+func snapshotReconciler(objs ...client.Object) *OomerReconciler {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(oomv1beta1.AddToScheme(scheme))
+
+	return &OomerReconciler{
+		Client: applyClient{fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()},
+		Scheme: scheme,
+	}
+}
+
+var _ = Describe("Oomer snapshots", func() {
+
+	ctx := context.Background()
+	key := types.NamespacedName{Name: "web", Namespace: "default"}
+
+	var (
+		d *appsv1.Deployment
+		o *oomv1beta1.Oomer
+	)
+
+	BeforeEach(func() {
+		d = &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name: "app",
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+							},
+						}},
+					},
+				},
+			},
+		}
+
+		limit := resource.MustParse("16Mi")
+		o = &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "drill", Namespace: "default"},
+			Spec: oomv1beta1.OomerSpec{
+				Mode:   oomv1beta1.TargetMode,
+				Target: &oomv1beta1.TargetSpec{Deployment: "web", MemoryLimit: &limit},
+			},
+		}
+	})
+
+	limitOf := func(r *OomerReconciler) string {
+		current := &appsv1.Deployment{}
+		Expect(r.Get(ctx, key, current)).To(Succeed())
+		return current.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()
+	}
+
+	It("Should save a snapshot of the Oomer before modifying the target", func() {
+		r := snapshotReconciler(d)
+		Expect(r.applyTarget(ctx, o)).To(Succeed())
+		Expect(limitOf(r)).To(Equal("16Mi"))
+
+		cm := &corev1.ConfigMap{}
+		Expect(r.Get(ctx, types.NamespacedName{Name: "drill-snapshot", Namespace: "default"}, cm)).To(Succeed())
+		Expect(cm.Data).Should(HaveKeyWithValue(snapshotDeploymentKey, "web"))
+		Expect(cm.ObjectMeta.Labels).Should(HaveKeyWithValue(oomv1beta1.OomerLabel, "drill"))
+
+		By("not allowing the garbage collector to delete it before the target is restored")
+		Expect(cm.ObjectMeta.OwnerReferences).Should(BeEmpty())
+
+		By("keeping the original resources once the target has been modified")
+		Expect(r.applyTarget(ctx, o)).To(Succeed())
+		s, err := r.getSnapshot(ctx, o)
+		Expect(err).NotTo(HaveOccurred())
+		original := s.Resources["app"]
+		Expect(original.Limits.Memory().String()).To(Equal("256Mi"))
+	})
+
+	It("Should restore from the snapshot after a restart, even without the annotations", func() {
+		r := snapshotReconciler(d)
+		Expect(r.applyTarget(ctx, o)).To(Succeed())
+
+		current := &appsv1.Deployment{}
+		Expect(r.Get(ctx, key, current)).To(Succeed())
+		current.ObjectMeta.Annotations = nil
+		Expect(r.Update(ctx, current)).To(Succeed())
+
+		// A new reconciler holds nothing in memory, as after the operator restarts.
+		restarted := &OomerReconciler{Client: r.Client, Scheme: r.Scheme}
+		Expect(restarted.restoreTarget(ctx, o)).To(Succeed())
+		Expect(limitOf(restarted)).To(Equal("256Mi"))
+
+		s, err := restarted.getSnapshot(ctx, o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(BeNil())
+	})
+
+	It("Should restore the deployment in the snapshot after the target changes", func() {
+		other := d.DeepCopy()
+		other.ObjectMeta.Name = "other"
+		r := snapshotReconciler(d, other)
+		Expect(r.applyTarget(ctx, o)).To(Succeed())
+
+		o.Spec.Target.Deployment = "other"
+		Expect(r.applyTarget(ctx, o)).To(MatchError(ContainSubstring("holds a snapshot of deployment web")))
+
+		Expect(r.restoreTarget(ctx, o)).To(Succeed())
+		Expect(limitOf(r)).To(Equal("256Mi"))
+	})
+
+	It("Should not snapshot a modified deployment whose snapshot is missing", func() {
+		d.ObjectMeta.Annotations = map[string]string{targetedByAnnotation: "drill"}
+		r := snapshotReconciler(d)
+
+		Expect(r.applyTarget(ctx, o)).To(MatchError(ContainSubstring("snapshot is missing")))
+		Expect(limitOf(r)).To(Equal("256Mi"))
+
+		By("refusing to remove the Oomer without restoring the deployment")
+		Expect(r.restoreTarget(ctx, o)).To(MatchError(ContainSubstring("snapshot is missing")))
+	})
+
+	It("Should not overwrite or delete a ConfigMap which is not a snapshot", func() {
+		cm := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "drill-snapshot", Namespace: "default"},
+			Data:       map[string]string{"config": "mine"},
+		}
+		r := snapshotReconciler(d, cm)
+
+		Expect(r.applyTarget(ctx, o)).To(MatchError(ContainSubstring("is not a snapshot of oomer drill")))
+		Expect(limitOf(r)).To(Equal("256Mi"))
+
+		Expect(r.restoreTarget(ctx, o)).To(Succeed())
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(cm), cm)).To(Succeed())
+		Expect(cm.Data).To(Equal(map[string]string{"config": "mine"}))
+	})
+})
+
+var _ = Describe("Oomer Operator restarted mid-run", func() {
+	const (
+		operatorName   = "test-restart"
+		targetName     = "test-restart-app"
+		oomerNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	limit := resource.MustParse("16Mi")
+
+	ctx := context.Background()
+	oom := &oomv1beta1.Oomer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      operatorName,
+			Namespace: oomerNamespace,
+		},
+		Spec: oomv1beta1.OomerSpec{
+			Replicas: 0,
+			Mode:     oomv1beta1.TargetMode,
+			Target: &oomv1beta1.TargetSpec{
+				Deployment:  targetName,
+				MemoryLimit: &limit,
+			},
+		},
+	}
+
+	lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
+	lookupTarget := types.NamespacedName{Name: targetName, Namespace: oomerNamespace}
+	lookupSnapshot := types.NamespacedName{Name: operatorName + "-snapshot", Namespace: oomerNamespace}
+
+	It("Should restore the target when the Oomer is deleted while the manager is stopped", func() {
+
+		labels := map[string]string{"app": targetName}
+		target := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      targetName,
+				Namespace: oomerNamespace,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: labels},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Labels: labels},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:  "app",
+							Image: "example.com/app:v1",
+							Resources: corev1.ResourceRequirements{
+								Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+							},
+						}},
+					},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, target)).Should(Succeed())
+		Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+		d := &appsv1.Deployment{}
+		Eventually(func() string {
+			if err := k8sClient.Get(ctx, lookupTarget, d); err != nil {
+				return ""
+			}
+			return d.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()
+		}, timeout, interval).Should(Equal("16Mi"))
+		Expect(k8sClient.Get(ctx, lookupSnapshot, &corev1.ConfigMap{})).Should(Succeed())
+
+		By("stopping the manager mid-run")
+		stopManager()
+		DeferCleanup(func() {
+			if stopManager == nil {
+				startManager()
+			}
+		})
+
+		// The Deployment is reapplied while the operator is down, removing the
+		// annotations but not the lowered limit.
+		d.ObjectMeta.Annotations = nil
+		Expect(k8sClient.Update(ctx, d)).Should(Succeed())
+		Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+
+		Consistently(func() error {
+			return k8sClient.Get(ctx, lookupOomer, &oomv1beta1.Oomer{})
+		}, time.Second, interval).Should(Succeed())
+
+		By("restarting the manager")
+		startManager()
+
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, lookupOomer, &oomv1beta1.Oomer{})
+			return apierrors.IsNotFound(err)
+		}, timeout, interval).Should(BeTrue())
+
+		Expect(k8sClient.Get(ctx, lookupTarget, d)).Should(Succeed())
+		Expect(d.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()).Should(Equal("256Mi"))
+
+		err := k8sClient.Get(ctx, lookupSnapshot, &corev1.ConfigMap{})
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+	})
+})
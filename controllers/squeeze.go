@@ -0,0 +1,215 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// defaultSqueezeInterval is the time between steps when the Squeeze mode does not
+// provide an interval.
+const defaultSqueezeInterval = time.Minute
+
+// squeezeInterval returns the time between each step of a squeeze.
+func squeezeInterval(s *oomv1beta1.SqueezeSpec) time.Duration {
+	if s.Interval != nil && s.Interval.Duration > 0 {
+		return s.Interval.Duration
+	}
+	return defaultSqueezeInterval
+}
+
+// squeezedLimit returns the memory limit after a number of steps down from the
+// starting limit, this is never lowered beyond the floor. A starting limit which
+// is already at or below the floor is left unchanged.
+func squeezedLimit(start resource.Quantity, s *oomv1beta1.SqueezeSpec, steps int32) resource.Quantity {
+	if start.Cmp(s.Floor) <= 0 {
+		return start.DeepCopy()
+	}
+
+	value := start.Value() - int64(steps)*s.Step.Value()
+	if value <= s.Floor.Value() {
+		return s.Floor.DeepCopy()
+	}
+
+	return *resource.NewQuantity(value, start.Format)
+}
+
+// squeezeSteps returns the number of steps which the memory limit should have been
+// lowered by, and how long until the next step is due. No further steps are taken
+// once a targeted container has been OOMKilled since the Oomer started, making it
+// OOMing, or every limit has reached the floor, a zero duration is returned in
+// that case.
+func squeezeSteps(o *oomv1beta1.Oomer, now time.Time) (int32, time.Duration) {
+	status := o.Status.Squeeze
+	if status == nil {
+		status = &oomv1beta1.SqueezeStatus{}
+	}
+
+	if o.Status.Phase == oomv1beta1.OOMingPhase || squeezeFloorReached(status.Limits, o.Spec.Squeeze.Floor) {
+		return status.Steps, 0
+	}
+
+	interval := squeezeInterval(o.Spec.Squeeze)
+	if status.LastStepTime != nil {
+		if elapsed := now.Sub(status.LastStepTime.Time); elapsed < interval {
+			return status.Steps, interval - elapsed
+		}
+	}
+
+	return status.Steps + 1, interval
+}
+
+// squeezeFloorReached reports whether every limit is at or below the floor, this
+// is false before any limits have been applied.
+func squeezeFloorReached(limits map[string]resource.Quantity, floor resource.Quantity) bool {
+	if len(limits) == 0 {
+		return false
+	}
+
+	for _, limit := range limits {
+		if limit.Cmp(floor) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applySqueeze lowers the memory limit of the targeted containers by another step
+// once the interval has passed, recording their original resources on the Deployment
+// beforehand. The progress is recorded in the status of the Oomer. It returns how
+// long until the next step is due, which is zero once squeezing has stopped.
+func (r *OomerReconciler) applySqueeze(ctx context.Context, o *oomv1beta1.Oomer, now time.Time) (time.Duration, error) {
+	log := log.FromContext(ctx)
+
+	if o.Spec.Target == nil || o.Spec.Target.Deployment == "" || o.Spec.Squeeze == nil {
+		return 0, fmt.Errorf("squeeze mode requires a target deployment and squeeze")
+	}
+	if o.Spec.Squeeze.Step.Sign() <= 0 {
+		return 0, fmt.Errorf("squeeze step must be greater than zero")
+	}
+
+	steps, wait := squeezeSteps(o, now)
+
+	limits, err := r.squeezeTarget(ctx, o, steps)
+	if err != nil {
+		return 0, err
+	}
+
+	if !recordSqueeze(o, steps, limits, now) {
+		return wait, nil
+	}
+
+	if err := r.applyStatus(ctx, o); err != nil {
+		log.Error(err, "unable to update oomer squeeze status", "steps", steps)
+		return 0, err
+	}
+
+	return wait, nil
+}
+
+// squeezeTarget sets the memory limit of the targeted containers to their limit
+// after a number of steps, returning the limit of each container.
+func (r *OomerReconciler) squeezeTarget(ctx context.Context, o *oomv1beta1.Oomer, steps int32) (map[string]resource.Quantity, error) {
+	log := log.FromContext(ctx)
+
+	key := targetNamespacedName(o)
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+
+	var limits map[string]resource.Quantity
+	err := patchWithRetry(ctx, r.Client, d, func() (bool, error) {
+		names, err := selectContainers(&d.Spec.Template, o.Spec.Target)
+		if err != nil {
+			return false, err
+		}
+
+		original, update, err := r.claimTarget(ctx, d, o, names)
+		if err != nil {
+			return false, err
+		}
+
+		// Limits are always calculated from the original resources, so that a
+		// step is never applied twice.
+		limits = make(map[string]resource.Quantity, len(names))
+		for i := range d.Spec.Template.Spec.Containers {
+			c := &d.Spec.Template.Spec.Containers[i]
+			for _, name := range names {
+				if c.Name != name {
+					continue
+				}
+
+				start, ok := original[name].Limits[corev1.ResourceMemory]
+				if !ok {
+					if o.Spec.Target.MemoryLimit == nil {
+						return false, fmt.Errorf("container %s has no memory limit to squeeze from", name)
+					}
+					start = *o.Spec.Target.MemoryLimit
+				}
+
+				limit := squeezedLimit(start, o.Spec.Squeeze, steps)
+				limits[name] = limit
+				if setMemoryLimit(c, limit) {
+					update = true
+				}
+			}
+		}
+
+		if update {
+			log.Info("squeezing memory limit of target containers", "deployment", d.ObjectMeta.Name, "steps", steps, "limits", limits)
+		}
+
+		return update, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return limits, nil
+}
+
+// recordSqueeze records the steps taken, and the resulting limits, in the status
+// of an Oomer. It reports whether the status was changed.
+func recordSqueeze(o *oomv1beta1.Oomer, steps int32, limits map[string]resource.Quantity, now time.Time) bool {
+	status := o.Status.Squeeze
+	if status == nil {
+		status = &oomv1beta1.SqueezeStatus{}
+	}
+	if status.Steps == steps && apiequality.Semantic.DeepEqual(status.Limits, limits) {
+		return false
+	}
+
+	if status.Steps != steps {
+		lastStep := metav1.NewTime(now)
+		status.LastStepTime = &lastStep
+	}
+	status.Steps = steps
+	status.Limits = limits
+	o.Status.Squeeze = status
+
+	return true
+}
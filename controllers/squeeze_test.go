@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Oomer squeeze", func() {
+
+	ctx := context.Background()
+	now := time.Date(2023, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	squeeze := &oomv1beta1.SqueezeSpec{
+		Step:     resource.MustParse("64Mi"),
+		Floor:    resource.MustParse("96Mi"),
+		Interval: &metav1.Duration{Duration: time.Minute},
+	}
+
+	DescribeTable("squeezing a limit",
+		func(start string, steps int32, expected string) {
+			limit := squeezedLimit(resource.MustParse(start), squeeze, steps)
+			Expect(limit.String()).Should(Equal(expected))
+		},
+		Entry("leaves the limit before the first step", "256Mi", int32(0), "256Mi"),
+		Entry("lowers the limit by each step", "256Mi", int32(2), "128Mi"),
+		Entry("stops at the floor", "256Mi", int32(3), "96Mi"),
+		Entry("never raises a limit below the floor", "64Mi", int32(1), "64Mi"),
+	)
+
+	It("Should step once each interval until the floor is reached", func() {
+		o := &oomv1beta1.Oomer{Spec: oomv1beta1.OomerSpec{Squeeze: squeeze}}
+
+		steps, wait := squeezeSteps(o, now)
+		Expect(steps).To(Equal(int32(1)))
+		Expect(wait).To(Equal(time.Minute))
+		Expect(recordSqueeze(o, steps, map[string]resource.Quantity{"app": resource.MustParse("192Mi")}, now)).To(BeTrue())
+		Expect(recordSqueeze(o, steps, map[string]resource.Quantity{"app": resource.MustParse("192Mi")}, now)).To(BeFalse())
+
+		steps, wait = squeezeSteps(o, now.Add(20*time.Second))
+		Expect(steps).To(Equal(int32(1)))
+		Expect(wait).To(Equal(40 * time.Second))
+
+		steps, _ = squeezeSteps(o, now.Add(time.Minute))
+		Expect(steps).To(Equal(int32(2)))
+
+		By("holding the limit once the floor is reached")
+		o.Status.Squeeze.Limits = map[string]resource.Quantity{"app": resource.MustParse("96Mi")}
+		steps, wait = squeezeSteps(o, now.Add(time.Hour))
+		Expect(steps).To(Equal(int32(1)))
+		Expect(wait).To(BeZero())
+	})
+
+	It("Should hold the limit once the Oomer is OOMing", func() {
+		o := &oomv1beta1.Oomer{
+			Spec: oomv1beta1.OomerSpec{Squeeze: squeeze},
+			Status: oomv1beta1.OomerStatus{
+				Phase: oomv1beta1.OOMingPhase,
+				Squeeze: &oomv1beta1.SqueezeStatus{
+					Steps:        2,
+					LastStepTime: &metav1.Time{Time: now},
+					Limits:       map[string]resource.Quantity{"app": resource.MustParse("128Mi")},
+				},
+			},
+		}
+
+		steps, wait := squeezeSteps(o, now.Add(time.Hour))
+		Expect(steps).To(Equal(int32(2)))
+		Expect(wait).To(BeZero())
+	})
+
+	It("Should only stop squeezing for OOM kills since the Oomer started", func() {
+		labels := map[string]string{"app": "web"}
+		d := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+		}
+		killed := func(name string, at time.Time) *corev1.Pod {
+			return &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+					Name: "app",
+					LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+						Reason:     oomKilledReason,
+						FinishedAt: metav1.NewTime(at),
+					}},
+				}}},
+			}
+		}
+		o := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "drill", Namespace: "default"},
+			Spec: oomv1beta1.OomerSpec{
+				Mode:    oomv1beta1.SqueezeMode,
+				Target:  &oomv1beta1.TargetSpec{Deployment: "web"},
+				Squeeze: squeeze,
+			},
+			Status: oomv1beta1.OomerStatus{StartTime: &metav1.Time{Time: now}},
+		}
+
+		phase, _ := snapshotReconciler(d, killed("web-before", now.Add(-time.Hour))).injectingPhase(ctx, o)
+		Expect(phase).To(Equal(oomv1beta1.InjectingPhase))
+
+		phase, _ = snapshotReconciler(d, killed("web-after", now.Add(time.Minute))).injectingPhase(ctx, o)
+		Expect(phase).To(Equal(oomv1beta1.OOMingPhase))
+	})
+
+	It("Should squeeze from the original limits and restore them", func() {
+		d := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name: "app",
+								Resources: corev1.ResourceRequirements{
+									Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+									Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+								},
+							},
+							{Name: "sidecar"},
+						},
+					},
+				},
+			},
+		}
+		o := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "drill", Namespace: "default"},
+			Spec: oomv1beta1.OomerSpec{
+				Mode:    oomv1beta1.SqueezeMode,
+				Target:  &oomv1beta1.TargetSpec{Deployment: "web", ContainerSelector: &oomv1beta1.ContainerSelector{Names: []string{"app", "sidecar"}}},
+				Squeeze: squeeze,
+			},
+		}
+
+		r := snapshotReconciler(d)
+		key := types.NamespacedName{Name: "web", Namespace: "default"}
+
+		_, err := r.squeezeTarget(ctx, o, 1)
+		Expect(err).To(MatchError(ContainSubstring("no memory limit")))
+
+		start := resource.MustParse("512Mi")
+		o.Spec.Target.MemoryLimit = &start
+
+		for _, steps := range []int32{1, 2} {
+			_, err := r.squeezeTarget(ctx, o, steps)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		limits, err := r.squeezeTarget(ctx, o, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits).Should(HaveLen(2))
+		app, sidecar := limits["app"], limits["sidecar"]
+		Expect(app.String()).Should(Equal("128Mi"))
+		Expect(sidecar.String()).Should(Equal("384Mi"))
+
+		squeezed := &appsv1.Deployment{}
+		Expect(r.Get(ctx, key, squeezed)).To(Succeed())
+		Expect(squeezed.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()).Should(Equal("128Mi"))
+		Expect(squeezed.Spec.Template.Spec.Containers[0].Resources.Requests.Memory().String()).Should(Equal("128Mi"))
+		Expect(squeezed.ObjectMeta.Annotations).Should(HaveKeyWithValue(targetedByAnnotation, "drill"))
+
+		Expect(r.restoreTarget(ctx, o)).To(Succeed())
+
+		restored := &appsv1.Deployment{}
+		Expect(r.Get(ctx, key, restored)).To(Succeed())
+		Expect(restored.Spec.Template.Spec.Containers[0].Resources).Should(Equal(d.Spec.Template.Spec.Containers[0].Resources))
+		Expect(restored.Spec.Template.Spec.Containers[1].Resources.Limits).Should(BeEmpty())
+		Expect(restored.ObjectMeta.Annotations).ShouldNot(HaveKey(targetedByAnnotation))
+	})
+})
+
+var _ = Describe("Oomer Operator in Squeeze mode", func() {
+	const (
+		operatorName   = "test-squeeze"
+		targetName     = "test-squeeze-app"
+		oomerNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	ctx := context.Background()
+	oom := &oomv1beta1.Oomer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      operatorName,
+			Namespace: oomerNamespace,
+		},
+		Spec: oomv1beta1.OomerSpec{
+			Replicas: 0,
+			Mode:     oomv1beta1.SqueezeMode,
+			Target: &oomv1beta1.TargetSpec{
+				Deployment: targetName,
+			},
+			Squeeze: &oomv1beta1.SqueezeSpec{
+				Step:     resource.MustParse("64Mi"),
+				Floor:    resource.MustParse("128Mi"),
+				Interval: &metav1.Duration{Duration: time.Second},
+			},
+		},
+	}
+
+	lookupOomer := types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}
+	lookupTarget := types.NamespacedName{Name: targetName, Namespace: oomerNamespace}
+
+	Context("When creating the object", func() {
+		It("Should lower the memory limit in steps down to the floor", func() {
+
+			labels := map[string]string{"app": targetName}
+			target := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      targetName,
+					Namespace: oomerNamespace,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{
+								Name:  "app",
+								Image: "example.com/app:v1",
+								Resources: corev1.ResourceRequirements{
+									Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+								},
+							}},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, target)).Should(Succeed())
+			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+			d := &appsv1.Deployment{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, lookupTarget, d); err != nil {
+					return ""
+				}
+				return d.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()
+			}, timeout, interval).Should(Equal("128Mi"))
+
+			o := &oomv1beta1.Oomer{}
+			Expect(k8sClient.Get(ctx, lookupOomer, o)).Should(Succeed())
+			Expect(o.Status.Squeeze).ShouldNot(BeNil())
+			Expect(o.Status.Squeeze.Steps).Should(Equal(int32(2)))
+			limit := o.Status.Squeeze.Limits["app"]
+			Expect(limit.String()).Should(Equal("128Mi"))
+
+			By("holding the floor")
+			Consistently(func() int32 {
+				if err := k8sClient.Get(ctx, lookupOomer, o); err != nil || o.Status.Squeeze == nil {
+					return 0
+				}
+				return o.Status.Squeeze.Steps
+			}, 2*time.Second, interval).Should(Equal(int32(2)))
+		})
+	})
+
+	Context("When deleting the object", func() {
+		It("Should restore the original memory limit", func() {
+
+			Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, lookupOomer, &oomv1beta1.Oomer{})
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			d := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, lookupTarget, d)).Should(Succeed())
+			Expect(d.Spec.Template.Spec.Containers[0].Resources.Limits.Memory().String()).Should(Equal("256Mi"))
+			Expect(d.ObjectMeta.Annotations).ShouldNot(HaveKey(targetedByAnnotation))
+		})
+	})
+})
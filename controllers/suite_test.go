@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	oomv1alpha1 "github.com/jdockerty/oom-operator/api/v1alpha1"
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -44,6 +45,9 @@ var (
 	testEnv   *envtest.Environment
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	// stopManager stops the manager started by startManager, waiting for it to exit.
+	stopManager func()
 )
 
 func TestAPIs(t *testing.T) {
@@ -72,30 +76,54 @@ var _ = BeforeSuite(func() {
 	err = oomv1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = oomv1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	//+kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
 	Expect(err).NotTo(HaveOccurred())
 	Expect(k8sClient).NotTo(BeNil())
 
+	startManager()
+})
+
+// startManager starts a manager running the controllers, it can be stopped
+// and started again to test that no state is lost when the operator restarts.
+func startManager() {
 	k8sManager, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme: scheme.Scheme,
 	})
 	Expect(err).ToNot(HaveOccurred())
 
 	err = (&OomerReconciler{
+		Client:    k8sManager.GetClient(),
+		Scheme:    k8sManager.GetScheme(),
+		APIReader: k8sManager.GetAPIReader(),
+	}).SetupWithManager(k8sManager)
+	Expect(err).ToNot(HaveOccurred())
+
+	err = (&OomWatcherReconciler{
 		Client: k8sManager.GetClient(),
 		Scheme: k8sManager.GetScheme(),
 	}).SetupWithManager(k8sManager)
 	Expect(err).ToNot(HaveOccurred())
 
+	managerCtx, managerCancel := context.WithCancel(ctx)
+	done := make(chan struct{})
 	go func() {
 		defer GinkgoRecover()
-		err = k8sManager.Start(ctx)
+		defer close(done)
+		err := k8sManager.Start(managerCtx)
 		Expect(err).ToNot(HaveOccurred(), "failed to run manager")
 	}()
 
-})
+	stopManager = func() {
+		managerCancel()
+		<-done
+		stopManager = nil
+	}
+}
 
 var _ = AfterSuite(func() {
 	cancel()
@@ -0,0 +1,314 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+const (
+	// targetedByAnnotation is set on a targeted workload with the name of the Oomer
+	// which has modified it, preventing multiple Oomers from fighting over it.
+	targetedByAnnotation = "oomer.jdocklabs.co.uk/targeted-by"
+
+	// defaultContainerAnnotation is used by kubectl to pick the default container of a pod.
+	defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+)
+
+// selectContainers returns the names of the containers within a pod template which
+// are targeted.
+func selectContainers(template *corev1.PodTemplateSpec, t *oomv1beta1.TargetSpec) ([]string, error) {
+	containers := template.Spec.Containers
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("pod template has no containers")
+	}
+
+	var names []string
+
+	switch {
+	case t.ContainerName != "":
+		for _, c := range containers {
+			if c.Name == t.ContainerName {
+				names = append(names, c.Name)
+			}
+		}
+
+	case t.ContainerSelector != nil:
+		for _, c := range containers {
+			matched, err := matchContainer(c, t.ContainerSelector)
+			if err != nil {
+				return nil, err
+			}
+			if matched {
+				names = append(names, c.Name)
+			}
+		}
+
+	default:
+		name := containers[0].Name
+		if defaultContainer, ok := template.ObjectMeta.Annotations[defaultContainerAnnotation]; ok {
+			name = defaultContainer
+		}
+		for _, c := range containers {
+			if c.Name == name {
+				names = append(names, c.Name)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no containers match the target")
+	}
+
+	return names, nil
+}
+
+// matchContainer reports whether a container matches any field of the selector.
+func matchContainer(c corev1.Container, s *oomv1beta1.ContainerSelector) (bool, error) {
+	for _, name := range s.Names {
+		if c.Name == name {
+			return true, nil
+		}
+	}
+
+	if s.NamePattern != "" {
+		matched, err := path.Match(s.NamePattern, c.Name)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+
+	if s.ImagePattern != "" {
+		matched, err := path.Match(s.ImagePattern, c.Image)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+
+	return false, nil
+}
+
+// targetNamespacedName returns the namespaced name of the Deployment targeted by an Oomer.
+func targetNamespacedName(o *oomv1beta1.Oomer) types.NamespacedName {
+	return types.NamespacedName{
+		Name:      o.Spec.Target.Deployment,
+		Namespace: o.ObjectMeta.Namespace,
+	}
+}
+
+// applyTarget lowers the memory limit of the targeted containers, recording their
+// original resources on the Deployment beforehand.
+func (r *OomerReconciler) applyTarget(ctx context.Context, o *oomv1beta1.Oomer) error {
+	log := log.FromContext(ctx)
+
+	if o.Spec.Target == nil || o.Spec.Target.Deployment == "" || o.Spec.Target.MemoryLimit == nil {
+		return fmt.Errorf("target mode requires a target deployment and memory limit")
+	}
+
+	key := targetNamespacedName(o)
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+
+	// The Deployment belongs to someone else, so it is patched on the version which
+	// was read rather than overwriting any changes made since.
+	return patchWithRetry(ctx, r.Client, d, func() (bool, error) {
+		names, err := selectContainers(&d.Spec.Template, o.Spec.Target)
+		if err != nil {
+			return false, err
+		}
+
+		_, update, err := r.claimTarget(ctx, d, o, names)
+		if err != nil {
+			return false, err
+		}
+
+		limit := *o.Spec.Target.MemoryLimit
+		for i := range d.Spec.Template.Spec.Containers {
+			c := &d.Spec.Template.Spec.Containers[i]
+			for _, name := range names {
+				if c.Name == name && setMemoryLimit(c, limit) {
+					update = true
+				}
+			}
+		}
+
+		if update {
+			log.Info("lowering memory limit of target containers", "deployment", d.ObjectMeta.Name, "containers", names, "limit", limit.String())
+		}
+
+		return update, nil
+	})
+}
+
+// claimTarget annotates a Deployment as targeted by an Oomer, snapshotting the
+// original resources of the named containers before any of them are modified.
+// The original resources are returned, these are never the limits which the
+// Oomer has applied. It also reports whether the annotations were changed.
+func (r *OomerReconciler) claimTarget(ctx context.Context, d *appsv1.Deployment, o *oomv1beta1.Oomer, names []string) (map[string]corev1.ResourceRequirements, bool, error) {
+	owner, claimed := d.ObjectMeta.Annotations[targetedByAnnotation]
+	if claimed && owner != o.ObjectMeta.Name {
+		return nil, false, fmt.Errorf("deployment %s is already targeted by oomer %s", d.ObjectMeta.Name, owner)
+	}
+
+	snapshot, err := r.getSnapshot(ctx, o)
+	if err != nil {
+		return nil, false, err
+	}
+
+	save := false
+	switch {
+	case snapshot != nil && snapshot.Deployment != d.ObjectMeta.Name:
+		return nil, false, fmt.Errorf("oomer holds a snapshot of deployment %s, pause it to restore the deployment before changing the target", snapshot.Deployment)
+
+	case snapshot == nil && claimed:
+		// The Deployment has been modified, so its current resources are not the
+		// originals and must not be snapshotted.
+		return nil, false, fmt.Errorf("deployment %s was modified by oomer %s but its snapshot is missing", d.ObjectMeta.Name, owner)
+
+	case snapshot == nil:
+		snapshot = &targetSnapshot{Deployment: d.ObjectMeta.Name, Resources: make(map[string]corev1.ResourceRequirements, len(names))}
+		save = true
+	}
+
+	// Containers which are missing from the snapshot have never been modified,
+	// so their current resources are the originals.
+	for _, c := range d.Spec.Template.Spec.Containers {
+		for _, name := range names {
+			if _, ok := snapshot.Resources[name]; c.Name == name && !ok {
+				snapshot.Resources[name] = c.Resources
+				save = true
+			}
+		}
+	}
+
+	if save {
+		if err := r.saveSnapshot(ctx, o, snapshot); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if claimed {
+		return snapshot.Resources, false, nil
+	}
+
+	if d.ObjectMeta.Annotations == nil {
+		d.ObjectMeta.Annotations = make(map[string]string)
+	}
+	d.ObjectMeta.Annotations[targetedByAnnotation] = o.ObjectMeta.Name
+
+	return snapshot.Resources, true, nil
+}
+
+// setMemoryLimit sets the memory limit of a container, lowering the memory request
+// alongside it as a request cannot exceed its limit. It reports whether the
+// container was changed.
+func setMemoryLimit(c *corev1.Container, limit resource.Quantity) bool {
+	changed := false
+
+	if current, ok := c.Resources.Limits[corev1.ResourceMemory]; !ok || current.Cmp(limit) != 0 {
+		if c.Resources.Limits == nil {
+			c.Resources.Limits = make(corev1.ResourceList)
+		}
+		c.Resources.Limits[corev1.ResourceMemory] = limit
+		changed = true
+	}
+
+	if request, ok := c.Resources.Requests[corev1.ResourceMemory]; ok && request.Cmp(limit) > 0 {
+		c.Resources.Requests[corev1.ResourceMemory] = limit
+		changed = true
+	}
+
+	return changed
+}
+
+// restoreTarget returns the targeted containers to the resources in the snapshot
+// of the Oomer, deleting the snapshot once they have been restored. The target
+// may have been removed since it was modified, which is not treated as an error.
+func (r *OomerReconciler) restoreTarget(ctx context.Context, o *oomv1beta1.Oomer) error {
+	log := log.FromContext(ctx)
+
+	// A ConfigMap which is not a snapshot of the Oomer is ignored, a snapshot was
+	// never saved so the target has not been modified by it.
+	snapshot, err := r.getSnapshot(ctx, o)
+	if err != nil && !errors.Is(err, errSnapshotNameTaken) {
+		return err
+	}
+
+	var name string
+	switch {
+	case snapshot != nil:
+		name = snapshot.Deployment
+	case o.Spec.Target != nil:
+		name = o.Spec.Target.Deployment
+	}
+	if name == "" {
+		return nil
+	}
+
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: o.ObjectMeta.Namespace}}
+
+	restored := false
+	err = patchWithRetry(ctx, r.Client, d, func() (bool, error) {
+		owner, claimed := d.ObjectMeta.Annotations[targetedByAnnotation]
+		if claimed && owner != o.ObjectMeta.Name {
+			return false, nil
+		}
+
+		// The snapshot is restored even when the annotations have been removed,
+		// such as by the Deployment being reapplied, as the limits may remain.
+		if snapshot == nil {
+			if !claimed {
+				return false, nil
+			}
+			// The finalizer must not remove the Oomer while its changes remain.
+			return false, fmt.Errorf("deployment %s was modified by oomer %s but its snapshot is missing, restore its resources and remove the %s annotation", d.ObjectMeta.Name, owner, targetedByAnnotation)
+		}
+
+		for i := range d.Spec.Template.Spec.Containers {
+			c := &d.Spec.Template.Spec.Containers[i]
+			if resources, ok := snapshot.Resources[c.Name]; ok {
+				c.Resources = resources
+			}
+		}
+
+		delete(d.ObjectMeta.Annotations, targetedByAnnotation)
+
+		restored = true
+		return true, nil
+	})
+	if err := client.IgnoreNotFound(err); err != nil {
+		return err
+	}
+
+	if restored {
+		log.Info("target containers restored", "deployment", d.ObjectMeta.Name)
+	}
+
+	return r.deleteSnapshot(ctx, o)
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Oomer container targeting", func() {
+
+	template := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "app", Image: "example.com/app:v1"},
+				{Name: "istio-proxy", Image: "docker.io/istio/proxyv2:1.16"},
+				{Name: "log-shipper", Image: "cr.fluentbit.io/fluent/fluent-bit:2.0"},
+			},
+		},
+	}
+
+	DescribeTable("selecting containers",
+		func(t *oomv1beta1.TargetSpec, expected []string) {
+			names, err := selectContainers(template, t)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names).Should(Equal(expected))
+		},
+		Entry("defaults to the first container", &oomv1beta1.TargetSpec{}, []string{"app"}),
+		Entry("by container name", &oomv1beta1.TargetSpec{ContainerName: "log-shipper"}, []string{"log-shipper"}),
+		Entry("by selector names",
+			&oomv1beta1.TargetSpec{ContainerSelector: &oomv1beta1.ContainerSelector{Names: []string{"app", "log-shipper"}}},
+			[]string{"app", "log-shipper"}),
+		Entry("by selector name pattern",
+			&oomv1beta1.TargetSpec{ContainerSelector: &oomv1beta1.ContainerSelector{NamePattern: "istio-*"}},
+			[]string{"istio-proxy"}),
+		Entry("by selector image pattern",
+			&oomv1beta1.TargetSpec{ContainerSelector: &oomv1beta1.ContainerSelector{ImagePattern: "*/fluent/fluent-bit:*"}},
+			[]string{"log-shipper"}),
+	)
+
+	It("Should use the default container annotation", func() {
+		annotated := template.DeepCopy()
+		annotated.ObjectMeta.Annotations = map[string]string{defaultContainerAnnotation: "istio-proxy"}
+
+		names, err := selectContainers(annotated, &oomv1beta1.TargetSpec{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(names).Should(Equal([]string{"istio-proxy"}))
+	})
+
+	It("Should fail when no containers match", func() {
+		_, err := selectContainers(template, &oomv1beta1.TargetSpec{ContainerName: "missing"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should lower a memory request above the new limit", func() {
+		c := &corev1.Container{
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+			},
+		}
+
+		Expect(setMemoryLimit(c, resource.MustParse("64Mi"))).Should(BeTrue())
+		Expect(c.Resources.Limits.Memory().String()).Should(Equal("64Mi"))
+		Expect(c.Resources.Requests.Memory().String()).Should(Equal("64Mi"))
+
+		Expect(setMemoryLimit(c, resource.MustParse("64Mi"))).Should(BeFalse())
+	})
+})
+
+var _ = Describe("Oomer Operator in Target mode", func() {
+	const (
+		operatorName   = "test-target"
+		targetName     = "test-target-app"
+		oomerNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	var replicas int32 = 0
+	limit := resource.MustParse("16Mi")
+
+	ctx := context.Background()
+	oom := &oomv1beta1.Oomer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      operatorName,
+			Namespace: oomerNamespace,
+		},
+		Spec: oomv1beta1.OomerSpec{
+			Replicas: replicas,
+			Mode:     oomv1beta1.TargetMode,
+			Target: &oomv1beta1.TargetSpec{
+				Deployment:    targetName,
+				ContainerName: "sidecar",
+				MemoryLimit:   &limit,
+			},
+		},
+	}
+
+	lookupTarget := types.NamespacedName{Name: targetName, Namespace: oomerNamespace}
+
+	Context("When creating the object", func() {
+		It("Should lower the memory limit of only the targeted container", func() {
+
+			labels := map[string]string{"app": targetName}
+			target := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      targetName,
+					Namespace: oomerNamespace,
+				},
+				Spec: appsv1.DeploymentSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{
+								{Name: "app", Image: "example.com/app:v1"},
+								{
+									Name:  "sidecar",
+									Image: "example.com/sidecar:v1",
+									Resources: corev1.ResourceRequirements{
+										Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+									},
+								},
+							},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, target)).Should(Succeed())
+			Expect(k8sClient.Create(ctx, oom)).Should(Succeed())
+
+			d := &appsv1.Deployment{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, lookupTarget, d); err != nil {
+					return ""
+				}
+				return d.Spec.Template.Spec.Containers[1].Resources.Limits.Memory().String()
+			}, timeout, interval).Should(Equal("16Mi"))
+
+			Expect(d.Spec.Template.Spec.Containers[0].Resources.Limits).Should(BeEmpty())
+			Expect(d.ObjectMeta.Annotations).Should(HaveKeyWithValue(targetedByAnnotation, operatorName))
+		})
+	})
+
+	Context("When deleting the object", func() {
+		It("Should restore the original memory limit", func() {
+
+			Expect(k8sClient.Delete(ctx, oom)).Should(Succeed())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, types.NamespacedName{Name: operatorName, Namespace: oomerNamespace}, &oomv1beta1.Oomer{})
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+
+			d := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, lookupTarget, d)).Should(Succeed())
+			Expect(d.Spec.Template.Spec.Containers[1].Resources.Limits.Memory().String()).Should(Equal("256Mi"))
+			Expect(d.ObjectMeta.Annotations).ShouldNot(HaveKey(targetedByAnnotation))
+		})
+	})
+})
@@ -0,0 +1,272 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+const (
+	// defaultVerifyWithin is how long checks are given to pass when the Oomer
+	// does not specify it.
+	defaultVerifyWithin = 2 * time.Minute
+
+	// verifyInterval is how often checks are evaluated while they are pending.
+	verifyInterval = 10 * time.Second
+
+	// verifyTimeout bounds each request made by a check.
+	verifyTimeout = 10 * time.Second
+)
+
+// Reasons given for the verification conditions of an Oomer.
+const (
+	checksPendingReason = "ChecksPending"
+	checksPassedReason  = "ChecksPassed"
+	checksFailedReason  = "ChecksFailed"
+)
+
+// verificationPending returns whether an Oomer has checks which have neither
+// passed nor failed, these are polled until they do.
+func verificationPending(o *oomv1beta1.Oomer) bool {
+	return o.Spec.Verify != nil &&
+		!meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.VerifiedCondition) &&
+		!meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.VerificationFailedCondition)
+}
+
+// verify evaluates the checks of an Oomer and sets its verification conditions,
+// returning whether they changed. Checks which have not passed fail once the
+// allowed time has passed, or immediately when final is set as the Oomer has
+// stopped injecting.
+func (r *OomerReconciler) verify(ctx context.Context, o *oomv1beta1.Oomer, now time.Time, final bool) bool {
+	log := log.FromContext(ctx)
+
+	if !verificationPending(o) || o.Status.StartTime == nil {
+		return false
+	}
+
+	var failing []string
+	for _, check := range o.Spec.Verify.Checks {
+		passed, err := r.runCheck(ctx, o, check)
+		if err != nil {
+			log.Error(err, "unable to evaluate check", "check", check.Name)
+		}
+		if !passed {
+			failing = append(failing, check.Name)
+		}
+	}
+
+	if len(failing) == 0 {
+		log.Info("verification checks passed")
+		changed := setCondition(o, oomv1beta1.VerifiedCondition, metav1.ConditionTrue, checksPassedReason, "All checks passed")
+		return setCondition(o, oomv1beta1.VerificationFailedCondition, metav1.ConditionFalse, checksPassedReason, "All checks passed") || changed
+	}
+
+	within := defaultVerifyWithin
+	if o.Spec.Verify.Within != nil {
+		within = o.Spec.Verify.Within.Duration
+	}
+
+	message := "Checks have not passed: " + strings.Join(failing, ", ")
+	if final || !now.Before(o.Status.StartTime.Add(within)) {
+		log.Info("verification checks failed", "checks", failing)
+		changed := setCondition(o, oomv1beta1.VerifiedCondition, metav1.ConditionFalse, checksFailedReason, message)
+		return setCondition(o, oomv1beta1.VerificationFailedCondition, metav1.ConditionTrue, checksFailedReason, message) || changed
+	}
+
+	changed := setCondition(o, oomv1beta1.VerifiedCondition, metav1.ConditionFalse, checksPendingReason, message)
+	return setCondition(o, oomv1beta1.VerificationFailedCondition, metav1.ConditionFalse, checksPendingReason, message) || changed
+}
+
+// runCheck evaluates a single check, an error means that it could not be evaluated
+// and it has not passed.
+func (r *OomerReconciler) runCheck(ctx context.Context, o *oomv1beta1.Oomer, check oomv1beta1.VerifyCheck) (bool, error) {
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: verifyTimeout}
+	}
+
+	switch {
+	case check.Prometheus != nil:
+		return checkPrometheus(ctx, httpClient, check.Prometheus)
+	case check.HTTP != nil:
+		return checkHTTP(ctx, httpClient, check.HTTP)
+	case check.Event != nil:
+		return checkEvent(ctx, r, o, check.Event)
+	}
+
+	return false, fmt.Errorf("check %q does not specify a prometheus, http or event check", check.Name)
+}
+
+// prometheusResponse is the subset of a Prometheus instant query response which
+// is required to evaluate a check.
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// checkPrometheus passes when any sample returned by the query is greater than
+// the threshold of the check.
+func checkPrometheus(ctx context.Context, c *http.Client, check *oomv1beta1.PrometheusCheck) (bool, error) {
+	endpoint := strings.TrimSuffix(check.URL, "/") + "/api/v1/query?" + url.Values{"query": {check.Query}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var body prometheusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, err
+	}
+	if body.Status != "success" {
+		return false, fmt.Errorf("query failed with status %d: %s", resp.StatusCode, body.Error)
+	}
+
+	// Each sample is a pair of its timestamp and its value as a string.
+	var samples [][2]interface{}
+	switch body.Data.ResultType {
+	case "vector":
+		var vector []struct {
+			Value [2]interface{} `json:"value"`
+		}
+		if err := json.Unmarshal(body.Data.Result, &vector); err != nil {
+			return false, err
+		}
+		for _, v := range vector {
+			samples = append(samples, v.Value)
+		}
+	case "scalar":
+		var scalar [2]interface{}
+		if err := json.Unmarshal(body.Data.Result, &scalar); err != nil {
+			return false, err
+		}
+		samples = append(samples, scalar)
+	default:
+		return false, fmt.Errorf("unsupported result type %q", body.Data.ResultType)
+	}
+
+	for _, sample := range samples {
+		raw, ok := sample[1].(string)
+		if !ok {
+			return false, fmt.Errorf("unexpected sample value %v", sample[1])
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return false, err
+		}
+		if value > threshold(check) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// threshold returns the value which a sample must exceed for a Prometheus check to pass.
+func threshold(check *oomv1beta1.PrometheusCheck) float64 {
+	if check.GreaterThan == nil {
+		return 0
+	}
+	return check.GreaterThan.AsApproximateFloat64()
+}
+
+// checkHTTP passes when the endpoint responds with the expected status and body.
+func checkHTTP(ctx context.Context, c *http.Client, check *oomv1beta1.HTTPCheck) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, check.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	expected := http.StatusOK
+	if check.ExpectedStatus != 0 {
+		expected = int(check.ExpectedStatus)
+	}
+	if resp.StatusCode != expected {
+		return false, nil
+	}
+
+	if check.BodyContains == "" {
+		return true, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, err
+	}
+
+	return strings.Contains(string(body), check.BodyContains), nil
+}
+
+// checkEvent passes when an Event with the reason of the check was recorded in
+// the namespace of the Oomer since it started injecting.
+func checkEvent(ctx context.Context, c client.Reader, o *oomv1beta1.Oomer, check *oomv1beta1.EventCheck) (bool, error) {
+	var events corev1.EventList
+	if err := c.List(ctx, &events, client.InNamespace(o.ObjectMeta.Namespace)); err != nil {
+		return false, err
+	}
+
+	for _, e := range events.Items {
+		if e.Reason != check.Reason {
+			continue
+		}
+		if check.InvolvedObjectKind != "" && e.InvolvedObject.Kind != check.InvolvedObjectKind {
+			continue
+		}
+
+		lastTimestamp := e.LastTimestamp.Time
+		if lastTimestamp.IsZero() {
+			lastTimestamp = e.EventTime.Time
+		}
+		if lastTimestamp.IsZero() {
+			lastTimestamp = e.ObjectMeta.CreationTimestamp.Time
+		}
+		if !lastTimestamp.Before(o.Status.StartTime.Time) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// prometheusServer is a stand-in for the Prometheus query API, returning a vector
+// with a single sample of the given value.
+func prometheusServer(value string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		Expect(r.URL.Path).To(Equal("/api/v1/query"))
+		Expect(r.URL.Query().Get("query")).To(Equal("ALERTS{alertname=\"OOMKilled\"}"))
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1672574400,%q]}]}}`, value)
+	}))
+}
+
+var _ = Describe("Oomer verification", func() {
+
+	ctx := context.Background()
+	now := time.Now()
+	start := metav1.NewTime(now.Add(-time.Minute))
+
+	newOomer := func(checks ...oomv1beta1.VerifyCheck) *oomv1beta1.Oomer {
+		return &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "verified", Namespace: "default"},
+			Spec: oomv1beta1.OomerSpec{
+				Verify: &oomv1beta1.VerifySpec{Checks: checks},
+			},
+			Status: oomv1beta1.OomerStatus{StartTime: &start},
+		}
+	}
+
+	newReconciler := func(objs ...runtime.Object) *OomerReconciler {
+		return &OomerReconciler{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithRuntimeObjects(objs...).Build()}
+	}
+
+	It("Should pass a Prometheus check when a sample exceeds the threshold", func() {
+		server := prometheusServer("2")
+		defer server.Close()
+
+		check := &oomv1beta1.PrometheusCheck{URL: server.URL, Query: "ALERTS{alertname=\"OOMKilled\"}"}
+		Expect(checkPrometheus(ctx, server.Client(), check)).To(BeTrue())
+
+		threshold := resource.MustParse("2")
+		check.GreaterThan = &threshold
+		Expect(checkPrometheus(ctx, server.Client(), check)).To(BeFalse())
+
+		By("comparing against a fractional threshold")
+		threshold = resource.MustParse("1.95")
+		Expect(checkPrometheus(ctx, server.Client(), check)).To(BeTrue())
+	})
+
+	It("Should pass an HTTP check on the expected status and body", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "received: OOMKilled")
+		}))
+		defer server.Close()
+
+		Expect(checkHTTP(ctx, server.Client(), &oomv1beta1.HTTPCheck{URL: server.URL})).To(BeTrue())
+		Expect(checkHTTP(ctx, server.Client(), &oomv1beta1.HTTPCheck{URL: server.URL, BodyContains: "OOMKilled"})).To(BeTrue())
+		Expect(checkHTTP(ctx, server.Client(), &oomv1beta1.HTTPCheck{URL: server.URL, BodyContains: "BackOff"})).To(BeFalse())
+		Expect(checkHTTP(ctx, server.Client(), &oomv1beta1.HTTPCheck{URL: server.URL, ExpectedStatus: 204})).To(BeFalse())
+	})
+
+	It("Should only pass an Event check for events since injection started", func() {
+		o := newOomer()
+		event := func(name string, reason string, at time.Time) *corev1.Event {
+			return &corev1.Event{
+				ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+				InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "app"},
+				Reason:         reason,
+				LastTimestamp:  metav1.NewTime(at),
+			}
+		}
+
+		r := newReconciler(event("before", "OOMKilling", now.Add(-time.Hour)), event("other", "BackOff", now))
+		Expect(checkEvent(ctx, r, o, &oomv1beta1.EventCheck{Reason: "OOMKilling"})).To(BeFalse())
+
+		r = newReconciler(event("after", "OOMKilling", now))
+		Expect(checkEvent(ctx, r, o, &oomv1beta1.EventCheck{Reason: "OOMKilling"})).To(BeTrue())
+		Expect(checkEvent(ctx, r, o, &oomv1beta1.EventCheck{Reason: "OOMKilling", InvolvedObjectKind: "Node"})).To(BeFalse())
+	})
+
+	It("Should set Verified once every check passes", func() {
+		server := prometheusServer("1")
+		defer server.Close()
+
+		o := newOomer(oomv1beta1.VerifyCheck{
+			Name:       "alert",
+			Prometheus: &oomv1beta1.PrometheusCheck{URL: server.URL, Query: "ALERTS{alertname=\"OOMKilled\"}"},
+		})
+		r := newReconciler()
+		r.HTTPClient = server.Client()
+
+		Expect(verificationPending(o)).To(BeTrue())
+		Expect(r.verify(ctx, o, now, false)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.VerifiedCondition)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(o.Status.Conditions, oomv1beta1.VerificationFailedCondition)).To(BeTrue())
+
+		By("no longer evaluating the checks")
+		Expect(verificationPending(o)).To(BeFalse())
+		Expect(r.verify(ctx, o, now, false)).To(BeFalse())
+	})
+
+	It("Should set VerificationFailed once the allowed time passes", func() {
+		server := prometheusServer("0")
+		defer server.Close()
+
+		o := newOomer(oomv1beta1.VerifyCheck{
+			Name:       "alert",
+			Prometheus: &oomv1beta1.PrometheusCheck{URL: server.URL, Query: "ALERTS{alertname=\"OOMKilled\"}"},
+		})
+		r := newReconciler()
+		r.HTTPClient = server.Client()
+
+		By("waiting while the checks are pending")
+		Expect(r.verify(ctx, o, now, false)).To(BeTrue())
+		Expect(verificationPending(o)).To(BeTrue())
+		Expect(meta.FindStatusCondition(o.Status.Conditions, oomv1beta1.VerifiedCondition).Reason).To(Equal(checksPendingReason))
+		Expect(scheduledRequeue(o, config.DefaultRequeueInterval, now)).To(Equal(verifyInterval))
+
+		By("failing after the default of 2m")
+		Expect(r.verify(ctx, o, now.Add(time.Minute), false)).To(BeTrue())
+		failed := meta.FindStatusCondition(o.Status.Conditions, oomv1beta1.VerificationFailedCondition)
+		Expect(failed.Status).To(Equal(metav1.ConditionTrue))
+		Expect(failed.Message).To(ContainSubstring("alert"))
+		Expect(verificationPending(o)).To(BeFalse())
+	})
+
+	It("Should fail pending checks when the Oomer completes", func() {
+		o := newOomer(oomv1beta1.VerifyCheck{Name: "event", Event: &oomv1beta1.EventCheck{Reason: "OOMKilling"}})
+		o.Spec.Verify.Within = &metav1.Duration{Duration: time.Hour}
+
+		r := newReconciler()
+		Expect(r.verify(ctx, o, now, false)).To(BeTrue())
+		Expect(verificationPending(o)).To(BeTrue())
+
+		Expect(r.verify(ctx, o, now, true)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.VerificationFailedCondition)).To(BeTrue())
+	})
+})
@@ -19,20 +19,29 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	componentconfig "k8s.io/component-base/config/v1alpha1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	configv1alpha1 "github.com/jdockerty/oom-operator/api/config/v1alpha1"
 	jdocklabscoukv1alpha1 "github.com/jdockerty/oom-operator/api/v1alpha1"
+	jdocklabscoukv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
 	"github.com/jdockerty/oom-operator/controllers"
+	"github.com/jdockerty/oom-operator/pkg/config"
+	"github.com/jdockerty/oom-operator/webhooks"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,6 +54,8 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(jdocklabscoukv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(jdocklabscoukv1beta1.AddToScheme(scheme))
+	utilruntime.Must(configv1alpha1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -52,11 +63,22 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var enableOomWatcher bool
+	var configFile string
+	var namespaces string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableOomWatcher, "enable-oom-watcher", false,
+		"Enable the OomWatcher controller, which watches every pod in the cluster for OOMKilled containers.")
+	flag.StringVar(&configFile, "config", "",
+		"The OomOperatorConfig file to load, this replaces the metrics, probe and leader election flags. "+
+			"Changes to the file are reloaded while running.")
+	flag.StringVar(&namespaces, "namespace", "",
+		"Comma separated namespaces to watch, in addition to those in the config file. "+
+			"When set, only these namespaces are cached and reconciled, so a Role in each is enough rather than a ClusterRole.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,37 +87,113 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "36732118.jdocklabs.co.uk",
-		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
-		// when the Manager ends. This requires the binary to immediately end when the
-		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
-		// speeds up voluntary leader transitions as the new leader don't have to wait
-		// LeaseDuration time first.
-		//
-		// In the default scaffold provided, the program ends immediately after
-		// the manager stops, so would be fine to enable this option. However,
-		// if you are doing or is intended to do any operation such as perform cleanups
-		// after the manager stops then its usage might be unsafe.
-		// LeaderElectionReleaseOnCancel: true,
-	})
+	// Without a configuration file, the configuration is built from the flags.
+	operatorConfig := &configv1alpha1.OomOperatorConfig{}
+	if configFile != "" {
+		var err error
+		operatorConfig, err = config.Load(configFile, scheme)
+		if err != nil {
+			setupLog.Error(err, "unable to load config file")
+			os.Exit(1)
+		}
+	} else {
+		operatorConfig.Metrics.BindAddress = metricsAddr
+		operatorConfig.Health.HealthProbeBindAddress = probeAddr
+		operatorConfig.LeaderElection = &componentconfig.LeaderElectionConfiguration{LeaderElect: &enableLeaderElection}
+		config.SetDefaults(operatorConfig)
+	}
+
+	var watchNamespaces []string
+	if namespaces != "" {
+		watchNamespaces = strings.Split(namespaces, ",")
+	}
+	config.AddNamespaces(operatorConfig, watchNamespaces...)
+	if err := config.Validate(operatorConfig); err != nil {
+		setupLog.Error(err, "invalid config")
+		os.Exit(1)
+	}
+
+	options, err := ctrl.Options{Scheme: scheme}.AndFrom(operatorConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to apply config")
+		os.Exit(1)
+	}
+	switch len(operatorConfig.Namespaces) {
+	case 0:
+	case 1:
+		options.Namespace = operatorConfig.Namespaces[0]
+	default:
+		options.NewCache = cache.MultiNamespacedCacheBuilder(operatorConfig.Namespaces)
+	}
+	if len(operatorConfig.Namespaces) > 0 {
+		// Nodes are cluster scoped so they cannot be cached with a Role, reading them
+		// directly fails the NodePressure mode rather than waiting on a cache which
+		// never syncs.
+		options.ClientDisableCacheFor = append(options.ClientDisableCacheFor, &corev1.Node{})
+		setupLog.Info("watching namespaces", "namespaces", operatorConfig.Namespaces)
+	}
+	// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
+	// when the Manager ends. This requires the binary to immediately end when the
+	// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
+	// speeds up voluntary leader transitions as the new leader don't have to wait
+	// LeaseDuration time first.
+	//
+	// In the default scaffold provided, the program ends immediately after
+	// the manager stops, so would be fine to enable this option. However,
+	// if you are doing or is intended to do any operation such as perform cleanups
+	// after the manager stops then its usage might be unsafe.
+	// options.LeaderElectionReleaseOnCancel = true
+
+	restConfig := ctrl.GetConfigOrDie()
+	restConfig.QPS = float32(operatorConfig.Client.QPS)
+	restConfig.Burst = int(operatorConfig.Client.Burst)
+
+	mgr, err := ctrl.NewManager(restConfig, options)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	store := config.NewStore(operatorConfig)
+	if configFile != "" {
+		if err := mgr.Add(&config.Watcher{Path: configFile, Scheme: scheme, Store: store, Namespaces: watchNamespaces}); err != nil {
+			setupLog.Error(err, "unable to watch config file")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controllers.OomerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Scheme:     mgr.GetScheme(),
+		Config:     store,
+		APIReader:  mgr.GetAPIReader(),
+		Namespaced: namespaces != "",
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Oomer")
 		os.Exit(1)
 	}
+	if enableOomWatcher {
+		if err = (&controllers.OomWatcherReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "OomWatcher")
+			os.Exit(1)
+		}
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if *operatorConfig.Webhooks.Conversion {
+			if err = (&jdocklabscoukv1beta1.Oomer{}).SetupWebhookWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create webhook", "webhook", "Oomer")
+				os.Exit(1)
+			}
+		}
+		if *operatorConfig.Webhooks.PodInjector {
+			mgr.GetWebhookServer().Register(webhooks.PodInjectorPath, &webhook.Admission{
+				Handler: &webhooks.PodInjector{Client: mgr.GetClient(), Config: store},
+			})
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package allocator builds the allocator containers which an Oomer adds to pods,
+// it is shared by the controllers and the pod webhook.
+package allocator
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/config"
+)
+
+// TerminationMessagePath is where the allocator writes its termination message.
+const TerminationMessagePath = "/tmp/oomed-pod.log"
+
+// Image returns the image used for the allocators of an Oomer, this is the
+// configured default image when the Oomer does not specify one.
+func Image(o *oomv1beta1.Oomer, c *config.Store) string {
+	if o.Spec.Image != "" {
+		return o.Spec.Image
+	}
+	return c.Get().DefaultImage
+}
+
+// Env returns the environment variables which configure the timing and profile
+// of the allocator containers of an Oomer.
+func Env(o *oomv1beta1.Oomer) ([]corev1.EnvVar, error) {
+	env, err := timingEnv(o.Spec.Timing)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := profileEnv(o.Spec.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(env, profile...), nil
+}
+
+// ContainerName is the name of the allocator container which an Oomer adds to an
+// existing pod. Ephemeral containers cannot be removed once attached, so the name
+// is stable to avoid attaching more than one allocator to a pod. Container names
+// are DNS labels, so dots are replaced and names which are too long are truncated,
+// keeping them unique with a hash of the Oomer name.
+func ContainerName(o *oomv1beta1.Oomer) string {
+	name := "oomer-" + strings.ReplaceAll(o.ObjectMeta.Name, ".", "-")
+	if len(name) <= validation.DNS1123LabelMaxLength && !strings.Contains(o.ObjectMeta.Name, ".") {
+		return name
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(o.ObjectMeta.Name))
+	suffix := fmt.Sprintf("-%08x", h.Sum32())
+	if max := validation.DNS1123LabelMaxLength - len(suffix); len(name) > max {
+		name = strings.TrimRight(name[:max], "-")
+	}
+	return name + suffix
+}
+
+// Sidecar builds the allocator container which is injected into pods by an Oomer
+// in the Sidecar mode.
+func Sidecar(o *oomv1beta1.Oomer, image string) (corev1.Container, error) {
+	env, err := Env(o)
+	if err != nil {
+		return corev1.Container{}, err
+	}
+
+	c := corev1.Container{
+		Name:                   ContainerName(o),
+		Image:                  image,
+		Env:                    env,
+		TerminationMessagePath: TerminationMessagePath,
+	}
+
+	if o.Spec.Target != nil && o.Spec.Target.MemoryLimit != nil {
+		c.Resources.Limits = corev1.ResourceList{
+			corev1.ResourceMemory: *o.Spec.Target.MemoryLimit,
+		}
+	}
+
+	return c, nil
+}
+
+// HasSidecar reports whether a pod already contains the sidecar of an Oomer.
+func HasSidecar(pod *corev1.Pod, o *oomv1beta1.Oomer) bool {
+	name := ContainerName(o)
+	for _, c := range pod.Spec.Containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
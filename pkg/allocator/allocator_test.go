@@ -0,0 +1,66 @@
+package allocator
+
+import (
+	"strings"
+
+	configv1alpha1 "github.com/jdockerty/oom-operator/api/config/v1alpha1"
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+var _ = Describe("Allocator containers", func() {
+
+	It("Should use the configured default image", func() {
+		c := &configv1alpha1.OomOperatorConfig{DefaultImage: "registry.example.com/oomer:v1"}
+		config.SetDefaults(c)
+		store := config.NewStore(c)
+
+		o := &oomv1beta1.Oomer{}
+		Expect(Image(o, nil)).To(Equal(config.DefaultImage))
+		Expect(Image(o, store)).To(Equal("registry.example.com/oomer:v1"))
+
+		o.Spec.Image = "oomer:custom"
+		Expect(Image(o, store)).To(Equal("oomer:custom"))
+	})
+
+	It("Should keep the names of allocators valid container names", func() {
+		long := &oomv1beta1.Oomer{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 60)}}
+		longer := &oomv1beta1.Oomer{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 61)}}
+		dotted := &oomv1beta1.Oomer{ObjectMeta: metav1.ObjectMeta{Name: "web.drill"}}
+
+		for _, o := range []*oomv1beta1.Oomer{long, longer, dotted} {
+			Expect(validation.IsDNS1123Label(ContainerName(o))).Should(BeEmpty())
+		}
+		Expect(ContainerName(long)).ShouldNot(Equal(ContainerName(longer)))
+		Expect(ContainerName(dotted)).Should(HavePrefix("oomer-web-drill-"))
+	})
+
+	It("Should limit the sidecar to the target memory limit", func() {
+		limit := resource.MustParse("32Mi")
+		o := &oomv1beta1.Oomer{
+			ObjectMeta: metav1.ObjectMeta{Name: "drill"},
+			Spec: oomv1beta1.OomerSpec{
+				Mode:   oomv1beta1.SidecarMode,
+				Target: &oomv1beta1.TargetSpec{MemoryLimit: &limit},
+			},
+		}
+
+		c, err := Sidecar(o, "oomer")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Name).Should(Equal("oomer-drill"))
+		Expect(c.TerminationMessagePath).Should(Equal(TerminationMessagePath))
+		Expect(c.Resources.Limits).Should(HaveKeyWithValue(corev1.ResourceMemory, limit))
+
+		pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}}
+		Expect(HasSidecar(pod, o)).To(BeFalse())
+		pod.Spec.Containers = append(pod.Spec.Containers, c)
+		Expect(HasSidecar(pod, o)).To(BeTrue())
+	})
+})
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package allocator
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// Environment variables which configure the profile of the allocator container.
+const (
+	profileTypeEnv  = "OOMER_PROFILE"
+	leakRateEnv     = "OOMER_LEAK_RATE"
+	freeIntervalEnv = "OOMER_FREE_INTERVAL"
+	freePercentEnv  = "OOMER_FREE_PERCENT"
+	metricsPortEnv  = "OOMER_METRICS_PORT"
+
+	defaultMetricsPort int32 = 8080
+)
+
+// profileEnv converts the profile of an Oomer into the environment variables
+// passed to the allocator container, the allocator uses its defaults for any
+// which are unset.
+func profileEnv(p *oomv1beta1.ProfileSpec) ([]corev1.EnvVar, error) {
+	if p == nil || p.Type == "" {
+		return nil, nil
+	}
+
+	env := []corev1.EnvVar{{Name: profileTypeEnv, Value: string(p.Type)}}
+	if p.Type == oomv1beta1.InstantProfile {
+		return env, nil
+	}
+
+	if p.Rate != nil {
+		if p.Rate.Sign() <= 0 {
+			return nil, fmt.Errorf("profile rate must be positive, got %s", p.Rate.String())
+		}
+		env = append(env, corev1.EnvVar{Name: leakRateEnv, Value: strconv.FormatInt(p.Rate.Value(), 10)})
+	}
+	if p.Type == oomv1beta1.SawtoothProfile {
+		if p.FreeInterval != nil {
+			env = append(env, corev1.EnvVar{Name: freeIntervalEnv, Value: p.FreeInterval.Duration.String()})
+		}
+		if p.FreePercent != 0 {
+			env = append(env, corev1.EnvVar{Name: freePercentEnv, Value: strconv.Itoa(int(p.FreePercent))})
+		}
+	}
+	env = append(env, corev1.EnvVar{Name: metricsPortEnv, Value: strconv.Itoa(int(MetricsPort(p)))})
+
+	return env, nil
+}
+
+// MetricsPort returns the port which an allocator serves /metrics on, this is 0
+// when the profile of the allocator does not serve metrics.
+func MetricsPort(p *oomv1beta1.ProfileSpec) int32 {
+	if p == nil || p.Type == "" || p.Type == oomv1beta1.InstantProfile {
+		return 0
+	}
+	if p.MetricsPort != 0 {
+		return p.MetricsPort
+	}
+	return defaultMetricsPort
+}
+
+// ExposeMetrics sets the port which an allocator container serves /metrics on,
+// replacing the port from the profile, and names it so that it can be scraped.
+func ExposeMetrics(c *corev1.Container, port int32) {
+	env := make([]corev1.EnvVar, 0, len(c.Env))
+	for _, e := range c.Env {
+		if e.Name != metricsPortEnv {
+			env = append(env, e)
+		}
+	}
+	c.Env = append(env, corev1.EnvVar{Name: metricsPortEnv, Value: strconv.Itoa(int(port))})
+	c.Ports = []corev1.ContainerPort{{Name: "metrics", ContainerPort: port, Protocol: corev1.ProtocolTCP}}
+}
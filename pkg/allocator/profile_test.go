@@ -0,0 +1,65 @@
+package allocator
+
+import (
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Allocator profiles", func() {
+
+	It("Should not pass any environment for the default profile", func() {
+		env, err := profileEnv(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(env).Should(BeEmpty())
+
+		env, err = profileEnv(&oomv1beta1.ProfileSpec{Type: oomv1beta1.InstantProfile, MetricsPort: 9090})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(env).Should(Equal([]corev1.EnvVar{{Name: profileTypeEnv, Value: "instant"}}))
+	})
+
+	It("Should pass a sawtooth profile to the allocator environment", func() {
+		rate := resource.MustParse("2Mi")
+		env, err := profileEnv(&oomv1beta1.ProfileSpec{
+			Type:         oomv1beta1.SawtoothProfile,
+			Rate:         &rate,
+			FreeInterval: &metav1.Duration{Duration: 5 * time.Minute},
+			FreePercent:  30,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(env).Should(Equal([]corev1.EnvVar{
+			{Name: profileTypeEnv, Value: "sawtooth"},
+			{Name: leakRateEnv, Value: "2097152"},
+			{Name: freeIntervalEnv, Value: "5m0s"},
+			{Name: freePercentEnv, Value: "30"},
+			{Name: metricsPortEnv, Value: "8080"},
+		}))
+
+		rate = resource.MustParse("0")
+		_, err = profileEnv(&oomv1beta1.ProfileSpec{Type: oomv1beta1.LeakProfile, Rate: &rate})
+		Expect(err).To(MatchError(ContainSubstring("must be positive")))
+	})
+
+	It("Should serve the metrics of each container on its own port", func() {
+		o := &oomv1beta1.Oomer{Spec: oomv1beta1.OomerSpec{
+			Profile: &oomv1beta1.ProfileSpec{Type: oomv1beta1.LeakProfile, MetricsPort: 9000},
+		}}
+		env, err := Env(o)
+		Expect(err).NotTo(HaveOccurred())
+
+		containers := []corev1.Container{{Name: "a", Env: append([]corev1.EnvVar(nil), env...)}, {Name: "b", Env: append([]corev1.EnvVar(nil), env...)}}
+		for i := range containers {
+			ExposeMetrics(&containers[i], MetricsPort(o.Spec.Profile)+int32(i))
+		}
+
+		Expect(containers[1].Env).Should(ContainElement(corev1.EnvVar{Name: metricsPortEnv, Value: "9001"}))
+		Expect(containers[1].Env).ShouldNot(ContainElement(corev1.EnvVar{Name: metricsPortEnv, Value: "9000"}))
+		Expect(containers[1].Ports).Should(Equal([]corev1.ContainerPort{{Name: "metrics", ContainerPort: 9001, Protocol: corev1.ProtocolTCP}}))
+	})
+})
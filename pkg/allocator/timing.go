@@ -0,0 +1,77 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package allocator
+
+import (
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// Environment variables which configure the timing of the allocator container.
+const (
+	minDelayEnv    = "OOMER_MIN_DELAY"
+	maxDelayEnv    = "OOMER_MAX_DELAY"
+	intervalEnv    = "OOMER_INTERVAL"
+	probabilityEnv = "OOMER_PROBABILITY_PERCENT"
+	seedEnv        = "OOMER_SEED"
+	podNameEnv     = "OOMER_POD_NAME"
+)
+
+// timingEnv converts the timing of an Oomer into the environment variables passed
+// to the allocator container. Durations are passed in the Go duration format.
+func timingEnv(t *oomv1beta1.TimingSpec) ([]corev1.EnvVar, error) {
+	if t == nil {
+		return nil, nil
+	}
+
+	if t.MinDelay != nil && t.MaxDelay != nil && t.MinDelay.Duration > t.MaxDelay.Duration {
+		return nil, fmt.Errorf("timing minDelay %s is greater than maxDelay %s", t.MinDelay.Duration, t.MaxDelay.Duration)
+	}
+
+	env := []corev1.EnvVar{
+		{
+			// The pod name is combined with the seed by the allocator, otherwise
+			// every pod would follow the same sequence.
+			Name: podNameEnv,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "metadata.name"},
+			},
+		},
+	}
+
+	if t.MinDelay != nil {
+		env = append(env, corev1.EnvVar{Name: minDelayEnv, Value: t.MinDelay.Duration.String()})
+	}
+	if t.MaxDelay != nil {
+		env = append(env, corev1.EnvVar{Name: maxDelayEnv, Value: t.MaxDelay.Duration.String()})
+	}
+	if t.Interval != nil {
+		env = append(env, corev1.EnvVar{Name: intervalEnv, Value: t.Interval.Duration.String()})
+	}
+	if t.ProbabilityPercent != nil {
+		env = append(env, corev1.EnvVar{Name: probabilityEnv, Value: strconv.Itoa(int(*t.ProbabilityPercent))})
+	}
+	if t.Seed != nil {
+		env = append(env, corev1.EnvVar{Name: seedEnv, Value: strconv.FormatInt(*t.Seed, 10)})
+	}
+
+	return env, nil
+}
@@ -0,0 +1,55 @@
+package allocator
+
+import (
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Allocator timing", func() {
+
+	It("Should not pass any environment without timing", func() {
+		env, err := timingEnv(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(env).Should(BeEmpty())
+	})
+
+	It("Should pass the timing to the allocator environment", func() {
+		var probability int32 = 25
+		var seed int64 = 42
+
+		env, err := timingEnv(&oomv1beta1.TimingSpec{
+			MinDelay:           &metav1.Duration{Duration: 10 * time.Second},
+			MaxDelay:           &metav1.Duration{Duration: time.Minute},
+			Interval:           &metav1.Duration{Duration: 5 * time.Second},
+			ProbabilityPercent: &probability,
+			Seed:               &seed,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(env).Should(ContainElements(
+			corev1.EnvVar{Name: minDelayEnv, Value: "10s"},
+			corev1.EnvVar{Name: maxDelayEnv, Value: "1m0s"},
+			corev1.EnvVar{Name: intervalEnv, Value: "5s"},
+			corev1.EnvVar{Name: probabilityEnv, Value: "25"},
+			corev1.EnvVar{Name: seedEnv, Value: "42"},
+		))
+
+		By("exposing the pod name so that each pod has its own sequence")
+		Expect(env[0].Name).Should(Equal(podNameEnv))
+		Expect(env[0].ValueFrom.FieldRef.FieldPath).Should(Equal("metadata.name"))
+	})
+
+	It("Should reject a minimum delay greater than the maximum", func() {
+		_, err := timingEnv(&oomv1beta1.TimingSpec{
+			MinDelay: &metav1.Duration{Duration: time.Minute},
+			MaxDelay: &metav1.Duration{Duration: time.Second},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// OomerBuilder builds an Oomer, such as
+//
+//	NewOomer("leak").InNamespace("drills").WithReplicas(3).WithMode(oomv1beta1.DeploymentMode).Build()
+type OomerBuilder struct {
+	o oomv1beta1.Oomer
+}
+
+// NewOomer starts building an Oomer with the given name, which has a single
+// replica in the Deployment mode as the API server defaults it to.
+func NewOomer(name string) *OomerBuilder {
+	return &OomerBuilder{o: oomv1beta1.Oomer{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: oomv1beta1.OomerSpec{
+			Replicas: 1,
+			Mode:     oomv1beta1.DeploymentMode,
+		},
+	}}
+}
+
+// InNamespace sets the namespace of the Oomer.
+func (b *OomerBuilder) InNamespace(namespace string) *OomerBuilder {
+	b.o.ObjectMeta.Namespace = namespace
+	return b
+}
+
+// WithReplicas sets the number of allocators.
+func (b *OomerBuilder) WithReplicas(replicas int32) *OomerBuilder {
+	b.o.Spec.Replicas = replicas
+	return b
+}
+
+// WithMode sets how OOM conditions are injected.
+func (b *OomerBuilder) WithMode(mode oomv1beta1.OomerMode) *OomerBuilder {
+	b.o.Spec.Mode = mode
+	return b
+}
+
+// WithImage sets the allocator image.
+func (b *OomerBuilder) WithImage(image string) *OomerBuilder {
+	b.o.Spec.Image = image
+	return b
+}
+
+// WithPodLabels sets the labels of the pods created by the Oomer.
+func (b *OomerBuilder) WithPodLabels(labels map[string]string) *OomerBuilder {
+	b.o.Spec.Selector = &metav1.LabelSelector{MatchLabels: labels}
+	return b
+}
+
+// WithTarget sets the existing workload used in the Target, Squeeze, Ephemeral
+// and Sidecar modes.
+func (b *OomerBuilder) WithTarget(target oomv1beta1.TargetSpec) *OomerBuilder {
+	b.o.Spec.Target = &target
+	return b
+}
+
+// WithNodePressure sets the allocators of the NodePressure mode.
+func (b *OomerBuilder) WithNodePressure(np oomv1beta1.NodePressureSpec) *OomerBuilder {
+	b.o.Spec.NodePressure = &np
+	return b
+}
+
+// WithMultiContainer sets the pods of the MultiContainer mode.
+func (b *OomerBuilder) WithMultiContainer(mc oomv1beta1.MultiContainerSpec) *OomerBuilder {
+	b.o.Spec.MultiContainer = &mc
+	return b
+}
+
+// WithSqueeze sets the steps taken in the Squeeze mode.
+func (b *OomerBuilder) WithSqueeze(s oomv1beta1.SqueezeSpec) *OomerBuilder {
+	b.o.Spec.Squeeze = &s
+	return b
+}
+
+// WithPattern varies the number of replicas over time.
+func (b *OomerBuilder) WithPattern(p oomv1beta1.PatternSpec) *OomerBuilder {
+	b.o.Spec.Pattern = &p
+	return b
+}
+
+// WithTiming randomises when each allocator triggers an OOM.
+func (b *OomerBuilder) WithTiming(t oomv1beta1.TimingSpec) *OomerBuilder {
+	b.o.Spec.Timing = &t
+	return b
+}
+
+// WithProfile sets how each allocator consumes memory.
+func (b *OomerBuilder) WithProfile(p oomv1beta1.ProfileSpec) *OomerBuilder {
+	b.o.Spec.Profile = &p
+	return b
+}
+
+// StartingAt delays injection until the given time.
+func (b *OomerBuilder) StartingAt(t time.Time) *OomerBuilder {
+	if b.o.Spec.Schedule == nil {
+		b.o.Spec.Schedule = &oomv1beta1.ScheduleSpec{}
+	}
+	b.o.Spec.Schedule.StartAt = &metav1.Time{Time: t}
+	return b
+}
+
+// RunningFor stops injection once the duration has passed.
+func (b *OomerBuilder) RunningFor(d time.Duration) *OomerBuilder {
+	if b.o.Spec.Schedule == nil {
+		b.o.Spec.Schedule = &oomv1beta1.ScheduleSpec{}
+	}
+	b.o.Spec.Schedule.Duration = &metav1.Duration{Duration: d}
+	return b
+}
+
+// WithVerify sets the signals which are expected to fire during the run.
+func (b *OomerBuilder) WithVerify(v oomv1beta1.VerifySpec) *OomerBuilder {
+	b.o.Spec.Verify = &v
+	return b
+}
+
+// WithNotify sets where notifications of the run are sent.
+func (b *OomerBuilder) WithNotify(n oomv1beta1.NotifySpec) *OomerBuilder {
+	b.o.Spec.Notify = &n
+	return b
+}
+
+// AbortWhen adds a criterion which aborts the run once met.
+func (b *OomerBuilder) AbortWhen(criterion oomv1beta1.AbortCriterion) *OomerBuilder {
+	b.o.Spec.AbortWhen = append(b.o.Spec.AbortWhen, criterion)
+	return b
+}
+
+// Paused creates the Oomer without starting injection.
+func (b *OomerBuilder) Paused() *OomerBuilder {
+	b.o.Spec.Paused = true
+	return b
+}
+
+// Build returns the Oomer, the builder may continue to be used afterwards.
+func (b *OomerBuilder) Build() *oomv1beta1.Oomer {
+	return b.o.DeepCopy()
+}
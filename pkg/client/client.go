@@ -0,0 +1,70 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client manages Oomers and their reports from Go, such as from the
+// integration tests of a workload. It is a thin typed wrapper of a
+// controller-runtime client, so Scheme can also be used to build a cache for
+// watching Oomers.
+package client
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// Scheme contains the Kubernetes types and the types of the operator.
+var Scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(Scheme))
+	utilruntime.Must(oomv1beta1.AddToScheme(Scheme))
+}
+
+// Client manages Oomers and their reports.
+type Client struct {
+	client.Client
+}
+
+// New returns a Client for the cluster of the given configuration, which can be
+// loaded with ctrl.GetConfig.
+func New(config *rest.Config) (*Client, error) {
+	c, err := client.New(config, client.Options{Scheme: Scheme})
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: c}, nil
+}
+
+// NewFromClient wraps an existing controller-runtime client, whose scheme must
+// contain the types of the operator.
+func NewFromClient(c client.Client) *Client {
+	return &Client{Client: c}
+}
+
+// Oomers returns a client for the Oomers of a namespace.
+func (c *Client) Oomers(namespace string) *OomerClient {
+	return &OomerClient{c: c.Client, namespace: namespace}
+}
+
+// OomReports returns a client for the reports of a namespace.
+func (c *Client) OomReports(namespace string) *OomReportClient {
+	return &OomReportClient{c: c.Client, namespace: namespace}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+var _ = Describe("Oomer builder", func() {
+
+	It("Should default to a single replica in the Deployment mode", func() {
+		o := NewOomer("leak").Build()
+		Expect(o.ObjectMeta.Name).To(Equal("leak"))
+		Expect(o.Spec.Replicas).To(Equal(int32(1)))
+		Expect(o.Spec.Mode).To(Equal(oomv1beta1.DeploymentMode))
+	})
+
+	It("Should build an Oomer from each option", func() {
+		limit := resource.MustParse("64Mi")
+		start := time.Date(2023, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+		b := NewOomer("drill").
+			InNamespace("drills").
+			WithReplicas(3).
+			WithMode(oomv1beta1.TargetMode).
+			WithTarget(oomv1beta1.TargetSpec{Deployment: "web", MemoryLimit: &limit}).
+			WithProfile(oomv1beta1.ProfileSpec{Type: oomv1beta1.LeakProfile}).
+			StartingAt(start).
+			RunningFor(time.Hour).
+			Paused()
+		o := b.Build()
+
+		Expect(o.ObjectMeta.Namespace).To(Equal("drills"))
+		Expect(o.Spec.Replicas).To(Equal(int32(3)))
+		Expect(o.Spec.Mode).To(Equal(oomv1beta1.TargetMode))
+		Expect(o.Spec.Target.Deployment).To(Equal("web"))
+		Expect(o.Spec.Profile.Type).To(Equal(oomv1beta1.LeakProfile))
+		Expect(o.Spec.Schedule.StartAt.Time).To(Equal(start))
+		Expect(o.Spec.Schedule.Duration.Duration).To(Equal(time.Hour))
+		Expect(o.Spec.Paused).To(BeTrue())
+
+		By("not sharing the built Oomer with the builder")
+		o.Spec.Target.Deployment = "other"
+		Expect(b.Build().Spec.Target.Deployment).To(Equal("web"))
+	})
+})
+
+var _ = Describe("Client", func() {
+
+	ctx := context.Background()
+
+	var c *Client
+
+	BeforeEach(func() {
+		c = NewFromClient(fake.NewClientBuilder().WithScheme(Scheme).Build())
+	})
+
+	It("Should create, update, pause and delete Oomers", func() {
+		oomers := c.Oomers("drills")
+		Expect(oomers.Create(ctx, NewOomer("leak").Build())).To(Succeed())
+
+		o, err := oomers.Get(ctx, "leak")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(o.ObjectMeta.Namespace).To(Equal("drills"))
+
+		o, err = oomers.Update(ctx, "leak", func(o *oomv1beta1.Oomer) { o.Spec.Replicas = 5 })
+		Expect(err).NotTo(HaveOccurred())
+		Expect(o.Spec.Replicas).To(Equal(int32(5)))
+
+		Expect(oomers.SetPaused(ctx, "leak", true)).To(Succeed())
+		o, err = oomers.Get(ctx, "leak")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(o.Spec.Paused).To(BeTrue())
+		Expect(o.Spec.Replicas).To(Equal(int32(5)))
+
+		Expect(c.Oomers("").List(ctx)).To(HaveLen(1))
+		Expect(c.Oomers("other").List(ctx)).To(BeEmpty())
+
+		Expect(oomers.Delete(ctx, "leak")).To(Succeed())
+		Expect(oomers.Delete(ctx, "leak")).To(Succeed())
+		_, err = oomers.Get(ctx, "leak")
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("Should find the latest report of an Oomer", func() {
+		report := func(name, oomer string, start time.Time) *oomv1beta1.OomReport {
+			return &oomv1beta1.OomReport{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "drills",
+					Labels:    map[string]string{oomv1beta1.OomerLabel: oomer},
+				},
+				Spec: oomv1beta1.OomReportSpec{Oomer: oomer, StartTime: metav1.NewTime(start)},
+			}
+		}
+
+		start := time.Date(2023, time.January, 1, 9, 0, 0, 0, time.UTC)
+		for _, r := range []*oomv1beta1.OomReport{
+			report("leak-1", "leak", start),
+			report("leak-2", "leak", start.Add(time.Hour)),
+			report("other-1", "other", start.Add(2*time.Hour)),
+		} {
+			Expect(c.Create(ctx, r)).To(Succeed())
+		}
+
+		reports := c.OomReports("drills")
+		Expect(reports.ForOomer(ctx, "leak")).To(HaveLen(2))
+
+		latest, err := reports.Latest(ctx, "leak")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(latest.ObjectMeta.Name).To(Equal("leak-2"))
+
+		_, err = reports.Latest(ctx, "missing")
+		Expect(err).To(MatchError(ContainSubstring("no reports found")))
+	})
+})
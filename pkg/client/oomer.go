@@ -0,0 +1,98 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// OomerClient manages the Oomers of a namespace.
+type OomerClient struct {
+	c         client.Client
+	namespace string
+}
+
+// Get returns the named Oomer.
+func (oc *OomerClient) Get(ctx context.Context, name string) (*oomv1beta1.Oomer, error) {
+	o := &oomv1beta1.Oomer{}
+	if err := oc.c.Get(ctx, types.NamespacedName{Name: name, Namespace: oc.namespace}, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// List returns the Oomers of the namespace, every namespace is used when the
+// namespace of the client is empty.
+func (oc *OomerClient) List(ctx context.Context, opts ...client.ListOption) ([]oomv1beta1.Oomer, error) {
+	var oomers oomv1beta1.OomerList
+	if err := oc.c.List(ctx, &oomers, append([]client.ListOption{client.InNamespace(oc.namespace)}, opts...)...); err != nil {
+		return nil, err
+	}
+	return oomers.Items, nil
+}
+
+// Create creates an Oomer, in the namespace of the client when it has none.
+func (oc *OomerClient) Create(ctx context.Context, o *oomv1beta1.Oomer) error {
+	if o.ObjectMeta.Namespace == "" {
+		o.ObjectMeta.Namespace = oc.namespace
+	}
+	return oc.c.Create(ctx, o)
+}
+
+// Update changes the spec of an existing Oomer through mutate, retrying when the
+// Oomer was changed concurrently. It returns the updated Oomer.
+func (oc *OomerClient) Update(ctx context.Context, name string, mutate func(*oomv1beta1.Oomer)) (*oomv1beta1.Oomer, error) {
+	o := &oomv1beta1.Oomer{}
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if err := oc.c.Get(ctx, types.NamespacedName{Name: name, Namespace: oc.namespace}, o); err != nil {
+			return err
+		}
+		mutate(o)
+		return oc.c.Update(ctx, o)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// SetPaused pauses, or resumes, injection for the named Oomer.
+func (oc *OomerClient) SetPaused(ctx context.Context, name string, paused bool) error {
+	o, err := oc.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(o.DeepCopy())
+	o.Spec.Paused = paused
+	return oc.c.Patch(ctx, o, patch)
+}
+
+// Delete deletes the named Oomer, the operator generates a report of the run as
+// it is removed. An Oomer which does not exist is ignored.
+func (oc *OomerClient) Delete(ctx context.Context, name string) error {
+	o := &oomv1beta1.Oomer{}
+	o.ObjectMeta.Name = name
+	o.ObjectMeta.Namespace = oc.namespace
+	return client.IgnoreNotFound(oc.c.Delete(ctx, o))
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// OomReportClient reads the reports of a namespace.
+type OomReportClient struct {
+	c         client.Client
+	namespace string
+}
+
+// Get returns the named report.
+func (rc *OomReportClient) Get(ctx context.Context, name string) (*oomv1beta1.OomReport, error) {
+	r := &oomv1beta1.OomReport{}
+	if err := rc.c.Get(ctx, types.NamespacedName{Name: name, Namespace: rc.namespace}, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// List returns the reports of the namespace, every namespace is used when the
+// namespace of the client is empty.
+func (rc *OomReportClient) List(ctx context.Context, opts ...client.ListOption) ([]oomv1beta1.OomReport, error) {
+	var reports oomv1beta1.OomReportList
+	if err := rc.c.List(ctx, &reports, append([]client.ListOption{client.InNamespace(rc.namespace)}, opts...)...); err != nil {
+		return nil, err
+	}
+	return reports.Items, nil
+}
+
+// ForOomer returns the reports of the named Oomer, one is generated each time it
+// completes, aborts or is deleted.
+func (rc *OomReportClient) ForOomer(ctx context.Context, oomer string) ([]oomv1beta1.OomReport, error) {
+	return rc.List(ctx, client.MatchingLabels{oomv1beta1.OomerLabel: oomer})
+}
+
+// Latest returns the most recently started report of the named Oomer.
+func (rc *OomReportClient) Latest(ctx context.Context, oomer string) (*oomv1beta1.OomReport, error) {
+	reports, err := rc.ForOomer(ctx, oomer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reports) == 0 {
+		return nil, fmt.Errorf("no reports found for %q", oomer)
+	}
+
+	latest := &reports[0]
+	for i := range reports {
+		if latest.Spec.StartTime.Before(&reports[i].Spec.StartTime) {
+			latest = &reports[i]
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/oomkill"
+)
+
+// DefaultPollInterval is how often an Oomer is read while waiting for it.
+const DefaultPollInterval = 2 * time.Second
+
+// Snapshot is the state of an Oomer observed while waiting for it.
+type Snapshot struct {
+	Oomer *oomv1beta1.Oomer `json:"oomer"`
+
+	// OOMKilledContainers is the number of containers affected by the Oomer whose
+	// most recent termination was caused by the OOM killer. A container which is
+	// OOMKilled repeatedly is counted once.
+	OOMKilledContainers int32 `json:"oomKilledContainers"`
+}
+
+// Condition reports whether a snapshot is in the state being waited for. An error
+// stops waiting, such as when the state can no longer be reached.
+type Condition func(s *Snapshot) (bool, error)
+
+// ContainersOOMKilledAtLeast is met once at least n containers affected by the
+// Oomer were last terminated by the OOM killer. This counts containers rather than
+// OOM kills, a container stops being counted once it terminates for another reason.
+func ContainersOOMKilledAtLeast(n int32) Condition {
+	return func(s *Snapshot) (bool, error) {
+		return s.OOMKilledContainers >= n, nil
+	}
+}
+
+// Completed is met once the scheduled duration of the Oomer has passed, it fails
+// when the Oomer is aborted as it will never complete.
+func Completed(s *Snapshot) (bool, error) {
+	if c := meta.FindStatusCondition(s.Oomer.Status.Conditions, oomv1beta1.AbortedCondition); c != nil && c.Status == metav1.ConditionTrue {
+		return false, fmt.Errorf("oomer was aborted: %s", c.Message)
+	}
+	return meta.IsStatusConditionTrue(s.Oomer.Status.Conditions, oomv1beta1.CompletedCondition), nil
+}
+
+// InPhase is met once the Oomer is in any of the given phases.
+func InPhase(phases ...oomv1beta1.OomerPhase) Condition {
+	return func(s *Snapshot) (bool, error) {
+		for _, p := range phases {
+			if s.Oomer.Status.Phase == p {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// Snapshot returns the current state of the named Oomer.
+func (oc *OomerClient) Snapshot(ctx context.Context, name string) (*Snapshot, error) {
+	o, err := oc.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return oc.snapshot(ctx, o)
+}
+
+// snapshot returns the state of an Oomer along with its OOMKilled containers.
+func (oc *OomerClient) snapshot(ctx context.Context, o *oomv1beta1.Oomer) (*Snapshot, error) {
+	selector, err := oomkill.PodSelector(ctx, oc.c, o)
+	if err != nil {
+		return nil, err
+	}
+
+	var pods corev1.PodList
+	if err := oc.c.List(ctx, &pods, client.InNamespace(o.ObjectMeta.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{Oomer: o, OOMKilledContainers: oomkill.Count(pods.Items)}, nil
+}
+
+// finished reports whether an Oomer is in a phase which it never leaves.
+func finished(o *oomv1beta1.Oomer) bool {
+	switch o.Status.Phase {
+	case oomv1beta1.CompletedPhase, oomv1beta1.AbortedPhase, oomv1beta1.FailedPhase:
+		return true
+	}
+	return false
+}
+
+// Wait blocks until the named Oomer meets the condition, reading it every interval.
+// It stops with an error once the timeout passes, unless the timeout is 0, when the
+// condition fails, or when the Oomer is deleted or finishes without meeting it.
+// Other errors from reading the Oomer and its pods are retried until the timeout,
+// the last of them is included in the error. The last snapshot is returned in
+// every case where one was read.
+func (oc *OomerClient) Wait(ctx context.Context, name string, timeout, interval time.Duration, condition Condition) (*Snapshot, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	var (
+		last    *Snapshot
+		lastErr error
+	)
+	err := wait.PollImmediateUntilWithContext(ctx, interval, func(ctx context.Context) (bool, error) {
+		o, err := oc.Get(ctx, name)
+		if apierrors.IsNotFound(err) {
+			return false, err
+		} else if err != nil {
+			lastErr = err
+			return false, nil
+		}
+
+		s, err := oc.snapshot(ctx, o)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		last, lastErr = s, nil
+
+		if met, err := condition(s); met || err != nil {
+			return met, err
+		}
+		if finished(o) {
+			return false, fmt.Errorf("oomer finished in phase %s without meeting the condition", o.Status.Phase)
+		}
+		return false, nil
+	})
+	if err != nil {
+		if lastErr != nil {
+			err = fmt.Errorf("%w, last error: %v", err, lastErr)
+		}
+		return last, fmt.Errorf("waiting for oomer %s: %w", name, err)
+	}
+
+	return last, nil
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// flakyClient fails the first reads, as an API server which is briefly unavailable.
+type flakyClient struct {
+	client.Client
+	failures int
+}
+
+func (c *flakyClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if c.failures > 0 {
+		c.failures--
+		return apierrors.NewServiceUnavailable("etcd is unavailable")
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+var _ = Describe("Waiting for an Oomer", func() {
+
+	ctx := context.Background()
+
+	var c *Client
+
+	oomKilledPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "drills", Labels: map[string]string{"app": "oomer", oomv1beta1.OomerLabel: "leak"}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "oomer",
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 137,
+					Reason:   "OOMKilled",
+				}},
+			}}},
+		}
+	}
+
+	BeforeEach(func() {
+		c = NewFromClient(fake.NewClientBuilder().WithScheme(Scheme).Build())
+		Expect(c.Oomers("drills").Create(ctx, NewOomer("leak").WithReplicas(3).Build())).To(Succeed())
+	})
+
+	It("Should wait until enough containers are OOMKilled", func() {
+		Expect(c.Create(ctx, oomKilledPod("leak-1"))).To(Succeed())
+
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(50 * time.Millisecond)
+			Expect(c.Create(ctx, oomKilledPod("leak-2"))).To(Succeed())
+		}()
+
+		s, err := c.Oomers("drills").Wait(ctx, "leak", time.Second, 10*time.Millisecond, ContainersOOMKilledAtLeast(2))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.OOMKilledContainers).To(Equal(int32(2)))
+		Expect(s.Oomer.ObjectMeta.Name).To(Equal("leak"))
+	})
+
+	It("Should return the last snapshot when the timeout passes", func() {
+		s, err := c.Oomers("drills").Wait(ctx, "leak", 50*time.Millisecond, 10*time.Millisecond, ContainersOOMKilledAtLeast(1))
+		Expect(err).To(MatchError(wait.ErrWaitTimeout))
+		Expect(s.OOMKilledContainers).To(BeZero())
+	})
+
+	It("Should wait for completion, failing once aborted", func() {
+		o, err := c.Oomers("drills").Get(ctx, "leak")
+		Expect(err).NotTo(HaveOccurred())
+
+		s := &Snapshot{Oomer: o}
+		Expect(Completed(s)).To(BeFalse())
+
+		meta.SetStatusCondition(&o.Status.Conditions, metav1.Condition{Type: oomv1beta1.CompletedCondition, Status: metav1.ConditionTrue, Reason: "ScheduleElapsed"})
+		Expect(Completed(s)).To(BeTrue())
+
+		meta.SetStatusCondition(&o.Status.Conditions, metav1.Condition{Type: oomv1beta1.AbortedCondition, Status: metav1.ConditionTrue, Reason: "Aborted", Message: "too many restarts"})
+		_, err = Completed(s)
+		Expect(err).To(MatchError(ContainSubstring("too many restarts")))
+
+		o.Status.Phase = oomv1beta1.AbortedPhase
+		Expect(InPhase(oomv1beta1.CompletedPhase, oomv1beta1.AbortedPhase)(s)).To(BeTrue())
+	})
+
+	It("Should retry transient errors until the timeout", func() {
+		flaky := &flakyClient{Client: c.Client, failures: 2}
+		Expect(c.Create(ctx, oomKilledPod("leak-1"))).To(Succeed())
+
+		s, err := NewFromClient(flaky).Oomers("drills").Wait(ctx, "leak", time.Second, 10*time.Millisecond, ContainersOOMKilledAtLeast(1))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.OOMKilledContainers).To(Equal(int32(1)))
+
+		By("including the last error once the timeout passes")
+		flaky.failures = 1000
+		_, err = NewFromClient(flaky).Oomers("drills").Wait(ctx, "leak", 50*time.Millisecond, 10*time.Millisecond, Completed)
+		Expect(err).To(MatchError(wait.ErrWaitTimeout))
+		Expect(err).To(MatchError(ContainSubstring("etcd is unavailable")))
+	})
+
+	It("Should stop once the Oomer finishes without meeting the condition", func() {
+		o, err := c.Oomers("drills").Get(ctx, "leak")
+		Expect(err).NotTo(HaveOccurred())
+		o.Status.Phase = oomv1beta1.CompletedPhase
+		Expect(c.Status().Update(ctx, o)).To(Succeed())
+
+		_, err = c.Oomers("drills").Wait(ctx, "leak", time.Second, 10*time.Millisecond, ContainersOOMKilledAtLeast(1))
+		Expect(err).To(MatchError(ContainSubstring("finished in phase Completed")))
+	})
+
+	It("Should stop when the Oomer does not exist", func() {
+		_, err := c.Oomers("drills").Wait(ctx, "missing", time.Second, 10*time.Millisecond, Completed)
+		Expect(err).To(MatchError(ContainSubstring("not found")))
+	})
+})
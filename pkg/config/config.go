@@ -0,0 +1,292 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the configuration file of the operator and holds the
+// fields which may be reloaded while it runs.
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	componentconfig "k8s.io/component-base/config/v1alpha1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	configv1alpha1 "github.com/jdockerty/oom-operator/api/config/v1alpha1"
+)
+
+const (
+	// DefaultImage is the allocator image used when neither the Oomer nor the
+	// configuration specify one. It ignores the OOMER_* environment variables,
+	// which require an image built from cmd/allocator.
+	DefaultImage = "jdockerty/oomer:v0.0.1"
+
+	// DefaultRequeueInterval is how often an Oomer is reconciled when nothing
+	// else triggers a reconcile.
+	DefaultRequeueInterval = 5 * time.Minute
+
+	// DefaultLeaderElectionID is the name of the resource used for leader election.
+	DefaultLeaderElectionID = "36732118.jdocklabs.co.uk"
+
+	// DefaultWebhookPort is the port which the webhook server listens on.
+	DefaultWebhookPort = 9443
+
+	// The defaults of the rate limiter match those used by controller-runtime.
+	DefaultBaseDelay = 5 * time.Millisecond
+	DefaultMaxDelay  = 1000 * time.Second
+	DefaultQPS       = 10
+	DefaultBurst     = 100
+
+	// The defaults of the client match those used by controller-runtime.
+	DefaultClientQPS   = 20
+	DefaultClientBurst = 30
+)
+
+// SetDefaults sets the defaults of any unset fields of the configuration.
+func SetDefaults(c *configv1alpha1.OomOperatorConfig) {
+	if c.DefaultImage == "" {
+		c.DefaultImage = DefaultImage
+	}
+	if c.RequeueInterval == nil {
+		c.RequeueInterval = &metav1.Duration{Duration: DefaultRequeueInterval}
+	}
+
+	if c.LeaderElection == nil {
+		c.LeaderElection = &componentconfig.LeaderElectionConfiguration{}
+	}
+	if c.LeaderElection.ResourceName == "" {
+		c.LeaderElection.ResourceName = DefaultLeaderElectionID
+	}
+	if c.Webhook.Port == nil {
+		port := DefaultWebhookPort
+		c.Webhook.Port = &port
+	}
+
+	if c.Client.QPS == 0 {
+		c.Client.QPS = DefaultClientQPS
+	}
+	if c.Client.Burst == 0 {
+		c.Client.Burst = DefaultClientBurst
+	}
+
+	limiter := &c.OomerController.RateLimiter
+	if limiter.BaseDelay == nil {
+		limiter.BaseDelay = &metav1.Duration{Duration: DefaultBaseDelay}
+	}
+	if limiter.MaxDelay == nil {
+		limiter.MaxDelay = &metav1.Duration{Duration: DefaultMaxDelay}
+	}
+	if limiter.QPS == 0 {
+		limiter.QPS = DefaultQPS
+	}
+	if limiter.Burst == 0 {
+		limiter.Burst = DefaultBurst
+	}
+
+	enabled := true
+	if c.Webhooks.Conversion == nil {
+		c.Webhooks.Conversion = &enabled
+	}
+	if c.Webhooks.PodInjector == nil {
+		c.Webhooks.PodInjector = &enabled
+	}
+}
+
+// AddNamespaces adds to the namespaces which are watched, such as those given on
+// the command line. Empty and repeated namespaces are ignored.
+func AddNamespaces(c *configv1alpha1.OomOperatorConfig, namespaces ...string) {
+	for _, ns := range namespaces {
+		ns = strings.TrimSpace(ns)
+		if ns == "" || contains(c.Namespaces, ns) {
+			continue
+		}
+		c.Namespaces = append(c.Namespaces, ns)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate returns an error describing every invalid field of the configuration.
+func Validate(c *configv1alpha1.OomOperatorConfig) error {
+	var errs field.ErrorList
+
+	if c.RequeueInterval != nil && c.RequeueInterval.Duration <= 0 {
+		errs = append(errs, field.Invalid(field.NewPath("requeueInterval"), c.RequeueInterval.Duration.String(), "must be greater than zero"))
+	}
+
+	clientPath := field.NewPath("client")
+	if c.Client.QPS < 0 {
+		errs = append(errs, field.Invalid(clientPath.Child("qps"), c.Client.QPS, "must not be negative"))
+	}
+	if c.Client.Burst < 0 {
+		errs = append(errs, field.Invalid(clientPath.Child("burst"), c.Client.Burst, "must not be negative"))
+	}
+
+	namespacesPath := field.NewPath("namespaces")
+	for i, ns := range c.Namespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			errs = append(errs, field.Invalid(namespacesPath.Index(i), ns, msg))
+		}
+	}
+	if len(c.Namespaces) > 0 && c.CacheNamespace != "" {
+		errs = append(errs, field.Forbidden(field.NewPath("cacheNamespace"), "cannot be set with namespaces"))
+	}
+
+	budgetPath := field.NewPath("budget")
+	if c.Budget.MaxInjecting < 0 {
+		errs = append(errs, field.Invalid(budgetPath.Child("maxInjecting"), c.Budget.MaxInjecting, "must not be negative"))
+	}
+	if c.Budget.MaxReplicas < 0 {
+		errs = append(errs, field.Invalid(budgetPath.Child("maxReplicas"), c.Budget.MaxReplicas, "must not be negative"))
+	}
+
+	if port := c.Webhook.Port; port != nil && (*port < 1 || *port > 65535) {
+		errs = append(errs, field.Invalid(field.NewPath("webhook", "port"), *port, "must be between 1 and 65535"))
+	}
+
+	controllerPath := field.NewPath("oomerController")
+	if c.OomerController.MaxConcurrentReconciles < 0 {
+		errs = append(errs, field.Invalid(controllerPath.Child("maxConcurrentReconciles"), c.OomerController.MaxConcurrentReconciles, "must not be negative"))
+	}
+	limiter, limiterPath := c.OomerController.RateLimiter, controllerPath.Child("rateLimiter")
+	if limiter.BaseDelay != nil && limiter.BaseDelay.Duration <= 0 {
+		errs = append(errs, field.Invalid(limiterPath.Child("baseDelay"), limiter.BaseDelay.Duration.String(), "must be greater than zero"))
+	}
+	if limiter.BaseDelay != nil && limiter.MaxDelay != nil && limiter.MaxDelay.Duration < limiter.BaseDelay.Duration {
+		errs = append(errs, field.Invalid(limiterPath.Child("maxDelay"), limiter.MaxDelay.Duration.String(), "must not be less than baseDelay"))
+	}
+	if limiter.QPS < 0 {
+		errs = append(errs, field.Invalid(limiterPath.Child("qps"), limiter.QPS, "must not be negative"))
+	}
+	if limiter.Burst < 0 {
+		errs = append(errs, field.Invalid(limiterPath.Child("burst"), limiter.Burst, "must not be negative"))
+	}
+
+	if c.Controller != nil {
+		concurrencyPath := field.NewPath("controller", "groupKindConcurrency")
+		for groupKind, concurrency := range c.Controller.GroupKindConcurrency {
+			if concurrency < 1 {
+				errs = append(errs, field.Invalid(concurrencyPath.Key(groupKind), concurrency, "must be at least 1"))
+			}
+		}
+	}
+
+	return errs.ToAggregate()
+}
+
+// Load reads, defaults and validates the configuration file at the given path.
+// The scheme must contain the configuration types.
+func Load(path string, scheme *runtime.Scheme) (*configv1alpha1.OomOperatorConfig, error) {
+	// A file which is being rewritten may briefly be empty, which would otherwise
+	// load as the default configuration.
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load %s: %w", path, err)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil, fmt.Errorf("unable to load %s: the file is empty", path)
+	}
+
+	c := &configv1alpha1.OomOperatorConfig{}
+
+	loader := ctrl.ConfigFile().AtPath(path).OfKind(c)
+	if err := loader.InjectScheme(scheme); err != nil {
+		return nil, err
+	}
+	if _, err := loader.Complete(); err != nil {
+		return nil, fmt.Errorf("unable to load %s: %w", path, err)
+	}
+
+	SetDefaults(c)
+	if err := Validate(c); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// Store holds the configuration of a running operator. The fields which are
+// reloaded are read from it on each reconcile, so it is safe for concurrent use.
+type Store struct {
+	mu     sync.RWMutex
+	config *configv1alpha1.OomOperatorConfig
+}
+
+// NewStore returns a Store holding the given configuration.
+func NewStore(c *configv1alpha1.OomOperatorConfig) *Store {
+	return &Store{config: c.DeepCopy()}
+}
+
+// Get returns a copy of the current configuration. A nil Store returns the
+// default configuration.
+func (s *Store) Get() *configv1alpha1.OomOperatorConfig {
+	if s == nil {
+		c := &configv1alpha1.OomOperatorConfig{}
+		SetDefaults(c)
+		return c
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.DeepCopy()
+}
+
+// Reload applies the fields of a new configuration which do not require a
+// restart. The paths of any other fields which changed are returned, these keep
+// their current values until the operator is restarted.
+func (s *Store) Reload(c *configv1alpha1.OomOperatorConfig) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var restart []string
+	if !equality.Semantic.DeepEqual(s.config.ControllerManagerConfigurationSpec, c.ControllerManagerConfigurationSpec) {
+		restart = append(restart, "controller manager configuration")
+	}
+	if s.config.Client != c.Client {
+		restart = append(restart, "client")
+	}
+	if !equality.Semantic.DeepEqual(s.config.Namespaces, c.Namespaces) {
+		restart = append(restart, "namespaces")
+	}
+	if !equality.Semantic.DeepEqual(s.config.Webhooks, c.Webhooks) {
+		restart = append(restart, "webhooks")
+	}
+	if !equality.Semantic.DeepEqual(s.config.OomerController, c.OomerController) {
+		restart = append(restart, "oomerController")
+	}
+
+	s.config.DefaultImage = c.DefaultImage
+	s.config.RequeueInterval = c.RequeueInterval.DeepCopy()
+	s.config.Budget = c.Budget
+
+	return restart
+}
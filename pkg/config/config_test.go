@@ -0,0 +1,190 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+
+	configv1alpha1 "github.com/jdockerty/oom-operator/api/config/v1alpha1"
+)
+
+const validConfig = `apiVersion: config.jdocklabs.co.uk/v1alpha1
+kind: OomOperatorConfig
+health:
+  healthProbeBindAddress: :8081
+metrics:
+  bindAddress: 127.0.0.1:8080
+leaderElection:
+  leaderElect: true
+controller:
+  groupKindConcurrency:
+    Oomer.jdocklabs.co.uk: 4
+defaultImage: registry.example.com/oomer:v1
+requeueInterval: 1m
+namespaces:
+- chaos
+budget:
+  maxInjecting: 2
+  maxReplicas: 10
+webhooks:
+  podInjector: false
+client:
+  qps: 40
+oomerController:
+  maxConcurrentReconciles: 8
+  rateLimiter:
+    qps: 50
+`
+
+var _ = Describe("Operator config", func() {
+
+	scheme := runtime.NewScheme()
+	utilruntime.Must(configv1alpha1.AddToScheme(scheme))
+
+	var dir string
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	write := func(content string) string {
+		path := filepath.Join(dir, "config.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		return path
+	}
+
+	It("Should load and default a config file", func() {
+		c, err := Load(write(validConfig), scheme)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.DefaultImage).To(Equal("registry.example.com/oomer:v1"))
+		Expect(c.RequeueInterval.Duration).To(Equal(time.Minute))
+		Expect(c.Namespaces).To(ConsistOf("chaos"))
+		Expect(c.Budget).To(Equal(configv1alpha1.BudgetConfig{MaxInjecting: 2, MaxReplicas: 10}))
+		Expect(c.Controller.GroupKindConcurrency).To(HaveKeyWithValue("Oomer.jdocklabs.co.uk", 4))
+		Expect(*c.LeaderElection.LeaderElect).To(BeTrue())
+
+		By("defaulting the unset fields")
+		Expect(c.LeaderElection.ResourceName).To(Equal(DefaultLeaderElectionID))
+		Expect(*c.Webhook.Port).To(Equal(DefaultWebhookPort))
+		Expect(*c.Webhooks.Conversion).To(BeTrue())
+		Expect(*c.Webhooks.PodInjector).To(BeFalse())
+		Expect(c.Client.QPS).To(Equal(int32(40)))
+		Expect(c.Client.Burst).To(Equal(int32(DefaultClientBurst)))
+		Expect(c.OomerController.MaxConcurrentReconciles).To(Equal(8))
+		Expect(c.OomerController.RateLimiter.QPS).To(Equal(int32(50)))
+		Expect(c.OomerController.RateLimiter.Burst).To(Equal(int32(DefaultBurst)))
+		Expect(c.OomerController.RateLimiter.BaseDelay.Duration).To(Equal(DefaultBaseDelay))
+	})
+
+	It("Should reject an invalid config file", func() {
+		_, err := Load(write(`apiVersion: config.jdocklabs.co.uk/v1alpha1
+kind: OomOperatorConfig
+requeueInterval: 0s
+namespaces:
+- Not_A_Namespace
+budget:
+  maxInjecting: -1
+client:
+  burst: -1
+oomerController:
+  maxConcurrentReconciles: -1
+  rateLimiter:
+    baseDelay: 1m
+    maxDelay: 1s
+`), scheme)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("requeueInterval"))
+		Expect(err.Error()).To(ContainSubstring("namespaces[0]"))
+		Expect(err.Error()).To(ContainSubstring("budget.maxInjecting"))
+		Expect(err.Error()).To(ContainSubstring("client.burst"))
+		Expect(err.Error()).To(ContainSubstring("oomerController.maxConcurrentReconciles"))
+		Expect(err.Error()).To(ContainSubstring("oomerController.rateLimiter.maxDelay"))
+
+		_, err = Load(filepath.Join(dir, "missing.yaml"), scheme)
+		Expect(err).To(HaveOccurred())
+
+		By("rejecting a file which is being rewritten")
+		_, err = Load(write("\n"), scheme)
+		Expect(err).To(MatchError(ContainSubstring("the file is empty")))
+	})
+
+	It("Should only reload the fields which do not require a restart", func() {
+		c, err := Load(write(validConfig), scheme)
+		Expect(err).NotTo(HaveOccurred())
+		store := NewStore(c)
+
+		changed := c.DeepCopy()
+		changed.DefaultImage = "registry.example.com/oomer:v2"
+		changed.RequeueInterval = &metav1.Duration{Duration: time.Hour}
+		changed.Budget.MaxInjecting = 5
+		changed.Namespaces = []string{"chaos", "staging"}
+		changed.OomerController.MaxConcurrentReconciles = 16
+		changed.Client.QPS = 100
+
+		Expect(store.Reload(changed)).To(ConsistOf("client", "namespaces", "oomerController"))
+		current := store.Get()
+		Expect(current.DefaultImage).To(Equal("registry.example.com/oomer:v2"))
+		Expect(current.RequeueInterval.Duration).To(Equal(time.Hour))
+		Expect(current.Budget.MaxInjecting).To(Equal(int32(5)))
+		Expect(current.Namespaces).To(ConsistOf("chaos"))
+	})
+
+	It("Should use the defaults without a store", func() {
+		var store *Store
+		Expect(store.Get().DefaultImage).To(Equal(DefaultImage))
+		Expect(store.Get().RequeueInterval.Duration).To(Equal(DefaultRequeueInterval))
+	})
+
+	It("Should add namespaces once", func() {
+		c := &configv1alpha1.OomOperatorConfig{Namespaces: []string{"chaos"}}
+		AddNamespaces(c, "team-a", "", " chaos ", "team-b", "team-a")
+		Expect(c.Namespaces).To(Equal([]string{"chaos", "team-a", "team-b"}))
+	})
+
+	It("Should reload the file when it changes", func() {
+		path := write(validConfig)
+		c, err := Load(path, scheme)
+		Expect(err).NotTo(HaveOccurred())
+		AddNamespaces(c, "team-a")
+		store := NewStore(c)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		done := make(chan error)
+		go func() {
+			done <- (&Watcher{Path: path, Scheme: scheme, Store: store, Namespaces: []string{"team-a"}}).Start(ctx)
+		}()
+
+		// The watch is established asynchronously, so the file is rewritten until
+		// the change is observed.
+		Eventually(func() string {
+			write(`apiVersion: config.jdocklabs.co.uk/v1alpha1
+kind: OomOperatorConfig
+defaultImage: registry.example.com/oomer:v2
+`)
+			return store.Get().DefaultImage
+		}).Should(Equal("registry.example.com/oomer:v2"))
+		Expect(store.Get().Namespaces).To(ContainElement("team-a"))
+
+		By("keeping the current config when the file is invalid")
+		write(`apiVersion: config.jdocklabs.co.uk/v1alpha1
+kind: OomOperatorConfig
+defaultImage: registry.example.com/oomer:v3
+requeueInterval: -1m
+`)
+		Consistently(func() string {
+			return store.Get().DefaultImage
+		}, "200ms").Should(Equal("registry.example.com/oomer:v2"))
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
@@ -0,0 +1,106 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Watcher reloads the configuration file into a Store when it changes. It is
+// added to the manager as a runnable, which runs on every replica.
+type Watcher struct {
+	// Path of the configuration file.
+	Path string
+
+	// Scheme contains the configuration types.
+	Scheme *runtime.Scheme
+
+	// Store receives the reloaded configuration.
+	Store *Store
+
+	// Namespaces are added to each reloaded configuration, these are given on the
+	// command line rather than in the file.
+	Namespaces []string
+}
+
+// NeedLeaderElection is false, as every replica must use the same configuration.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start watches the configuration file until the context is done. The directory
+// is watched rather than the file, as a mounted ConfigMap is updated by replacing
+// a symlink.
+func (w *Watcher) Start(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("config").WithValues("path", w.Path)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(w.Path)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Error(err, "error watching configuration")
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// A mounted ConfigMap is updated through the ..data symlink.
+			if event.Op == fsnotify.Chmod ||
+				(filepath.Clean(event.Name) != filepath.Clean(w.Path) && !strings.HasPrefix(filepath.Base(event.Name), "..")) {
+				continue
+			}
+			w.reload(ctx)
+		}
+	}
+}
+
+// reload applies the configuration file to the Store, an invalid file is logged
+// and the current configuration is kept.
+func (w *Watcher) reload(ctx context.Context) {
+	log := log.FromContext(ctx).WithName("config").WithValues("path", w.Path)
+
+	c, err := Load(w.Path, w.Scheme)
+	if err != nil {
+		log.Error(err, "unable to reload configuration, keeping the current configuration")
+		return
+	}
+	AddNamespaces(c, w.Namespaces...)
+
+	if restart := w.Store.Reload(c); len(restart) > 0 {
+		log.Info("configuration changed which requires a restart to apply", "fields", restart)
+	}
+	log.Info("reloaded configuration")
+}
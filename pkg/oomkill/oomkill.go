@@ -0,0 +1,150 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oomkill finds the pods affected by an Oomer and counts the containers
+// within them which were OOMKilled. It is shared by the operator and its clients,
+// so it must not depend on the controllers.
+package oomkill
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+)
+
+// Reason is the reason given for a container which was terminated by the OOM killer.
+const Reason = "OOMKilled"
+
+// PodSelector returns the selector for the pods affected by an Oomer, these are the
+// pods of an existing workload when one is targeted.
+func PodSelector(ctx context.Context, c client.Reader, o *oomv1beta1.Oomer) (labels.Selector, error) {
+	switch o.Spec.Mode {
+	case oomv1beta1.TargetMode, oomv1beta1.SqueezeMode, oomv1beta1.EphemeralMode, oomv1beta1.SidecarMode:
+		return TargetPodSelector(ctx, c, o)
+	}
+
+	return createdPodSelector(ctx, c, o)
+}
+
+// createdPodSelector returns the selector for the pods created by an Oomer. This is
+// the selector of its Deployment, or DaemonSet, as the selector is immutable and
+// one created before the default labels changed keeps the labels it was created
+// with. PodLabels are used once the workload has been removed.
+func createdPodSelector(ctx context.Context, c client.Reader, o *oomv1beta1.Oomer) (labels.Selector, error) {
+	var workload client.Object = &appsv1.Deployment{}
+	if o.Spec.Mode == oomv1beta1.NodePressureMode {
+		workload = &appsv1.DaemonSet{}
+	}
+
+	key := types.NamespacedName{Name: o.ObjectMeta.Name, Namespace: o.ObjectMeta.Namespace}
+	if err := c.Get(ctx, key, workload); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		return labels.SelectorFromSet(PodLabels(o)), nil
+	}
+
+	var selector *metav1.LabelSelector
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		selector = w.Spec.Selector
+	case *appsv1.DaemonSet:
+		selector = w.Spec.Selector
+	}
+
+	if selector == nil || !metav1.IsControlledBy(workload, o) {
+		return labels.SelectorFromSet(PodLabels(o)), nil
+	}
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// PodLabels returns the labels of the pods created by an Oomer. By default these
+// include the name of the Oomer, so that the pods of Oomers sharing a namespace
+// are told apart.
+func PodLabels(o *oomv1beta1.Oomer) map[string]string {
+	if o.Spec.Selector != nil && o.Spec.Selector.MatchLabels != nil {
+		return o.Spec.Selector.MatchLabels
+	}
+	return map[string]string{"app": "oomer", oomv1beta1.OomerLabel: o.ObjectMeta.Name}
+}
+
+// TargetPodSelector returns the selector used to find the pods targeted by an Oomer,
+// this is the selector of the referenced Deployment when provided.
+func TargetPodSelector(ctx context.Context, c client.Reader, o *oomv1beta1.Oomer) (labels.Selector, error) {
+	t := o.Spec.Target
+	if t == nil || (t.Deployment == "" && t.Selector == nil) {
+		return nil, fmt.Errorf("%s mode requires a target deployment or selector", o.Spec.Mode)
+	}
+
+	selector := t.Selector
+	if t.Deployment != "" {
+		d := &appsv1.Deployment{}
+		if err := c.Get(ctx, types.NamespacedName{Name: t.Deployment, Namespace: o.ObjectMeta.Namespace}, d); err != nil {
+			return nil, err
+		}
+		selector = d.Spec.Selector
+	}
+
+	return metav1.LabelSelectorAsSelector(selector)
+}
+
+// KilledAt returns when a container was last OOMKilled, this is nil when its
+// most recent termination was not caused by the OOM killer.
+func KilledAt(status corev1.ContainerStatus) *metav1.Time {
+	for _, state := range []corev1.ContainerState{status.State, status.LastTerminationState} {
+		if t := state.Terminated; t != nil {
+			if t.Reason != Reason {
+				return nil
+			}
+			finishedAt := t.FinishedAt
+			return &finishedAt
+		}
+	}
+	return nil
+}
+
+// Count returns the number of containers, across the given pods, whose most
+// recent termination was caused by the OOM killer.
+func Count(pods []corev1.Pod) int32 {
+	return CountSince(pods, time.Time{})
+}
+
+// CountSince returns the number of containers, across the given pods, whose most
+// recent termination was caused by the OOM killer at or after the given time. This
+// ignores the OOM kills of an existing workload from before it was targeted.
+func CountSince(pods []corev1.Pod, since time.Time) int32 {
+	var count int32
+	for _, pod := range pods {
+		for _, statuses := range [][]corev1.ContainerStatus{pod.Status.ContainerStatuses, pod.Status.EphemeralContainerStatuses} {
+			for _, status := range statuses {
+				if killedAt := KilledAt(status); killedAt != nil && !killedAt.Time.Before(since) {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,119 @@
+package oomkill
+
+import (
+	"context"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("OOM kills", func() {
+
+	ctx := context.Background()
+
+	o := &oomv1beta1.Oomer{ObjectMeta: metav1.ObjectMeta{Name: "gameday", Namespace: "default", UID: "gameday-uid"}}
+
+	It("Should not select the pods of other Oomers in the namespace", func() {
+		other := o.DeepCopy()
+		other.ObjectMeta.Name = "fire-drill"
+
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+		selector, err := PodSelector(ctx, c, o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.Matches(labels.Set(PodLabels(o)))).Should(BeTrue())
+		Expect(selector.Matches(labels.Set(PodLabels(other)))).Should(BeFalse())
+	})
+
+	It("Should select pods through the selector of an existing deployment", func() {
+		legacy := map[string]string{"app": "oomer"}
+		d := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "gameday",
+				Namespace:       "default",
+				OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(o, oomv1beta1.GroupVersion.WithKind("Oomer"))},
+			},
+			Spec: appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: legacy}},
+		}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(d).Build()
+
+		selector, err := PodSelector(ctx, c, o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.Matches(labels.Set(legacy))).Should(BeTrue())
+
+		By("ignoring a deployment which the Oomer does not control")
+		d.ObjectMeta.OwnerReferences = nil
+		c = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(d).Build()
+
+		selector, err = PodSelector(ctx, c, o)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.Matches(labels.Set(legacy))).Should(BeFalse())
+	})
+
+	It("Should select the pods of a targeted deployment", func() {
+		d := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		}
+		c := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(d).Build()
+
+		target := o.DeepCopy()
+		target.Spec.Mode = oomv1beta1.TargetMode
+		target.Spec.Target = &oomv1beta1.TargetSpec{Deployment: "web"}
+
+		selector, err := PodSelector(ctx, c, target)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.String()).Should(Equal("app=web"))
+
+		target.Spec.Target = nil
+		_, err = PodSelector(ctx, c, target)
+		Expect(err).To(MatchError(ContainSubstring("requires a target deployment or selector")))
+	})
+
+	It("Should only count containers whose last termination was an OOM kill", func() {
+		oomKilled := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: Reason}}
+		errored := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}}
+
+		pods := []corev1.Pod{{
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "killed", LastTerminationState: oomKilled},
+					{Name: "recovered", State: errored, LastTerminationState: oomKilled},
+					{Name: "running"},
+				},
+				EphemeralContainerStatuses: []corev1.ContainerStatus{{Name: "oomer-gameday", State: oomKilled}},
+			},
+		}}
+
+		Expect(Count(pods)).To(Equal(int32(2)))
+		Expect(KilledAt(pods[0].Status.ContainerStatuses[1])).To(BeNil())
+	})
+
+	It("Should only count OOM kills since the given time", func() {
+		start := time.Now()
+		killed := func(at time.Time) corev1.ContainerStatus {
+			return corev1.ContainerStatus{LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Reason:     Reason,
+				FinishedAt: metav1.NewTime(at),
+			}}}
+		}
+
+		pods := []corev1.Pod{{
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{
+				killed(start.Add(-time.Hour)),
+				killed(start.Add(time.Minute)),
+			}},
+		}}
+
+		Expect(CountSince(pods, start)).To(Equal(int32(1)))
+		Expect(Count(pods)).To(Equal(int32(2)))
+	})
+})
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 Jack Dockerty.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	"github.com/jdockerty/oom-operator/pkg/allocator"
+	"github.com/jdockerty/oom-operator/pkg/config"
+)
+
+// PodInjectorPath is the path which the PodInjector is served from.
+const PodInjectorPath = "/mutate-v1-pod"
+
+//+kubebuilder:webhook:path=/mutate-v1-pod,mutating=true,failurePolicy=ignore,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod.oomer.jdocklabs.co.uk,admissionReviewVersions=v1
+
+// PodInjector injects an allocator sidecar into pods which are labelled with
+// the InjectLabel and match the target selector of an active Oomer in the
+// Sidecar mode.
+type PodInjector struct {
+	Client client.Client
+
+	// Config holds the configuration of the operator, the defaults are used when
+	// this is nil.
+	Config *config.Store
+
+	decoder *admission.Decoder
+}
+
+// Handle injects the allocator sidecar into an admitted pod.
+func (p *PodInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	log := log.FromContext(ctx)
+
+	pod := &corev1.Pod{}
+	if err := p.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// Other pods are excluded by the object selector of the webhook, this guards
+	// against a configuration without it.
+	if pod.ObjectMeta.Labels[oomv1beta1.InjectLabel] != "true" {
+		return admission.Allowed("pod has not opted into injection")
+	}
+
+	oomer, err := p.matchOomer(ctx, req.Namespace, pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if oomer == nil {
+		return admission.Allowed("no active oomer matches the pod")
+	}
+
+	if allocator.HasSidecar(pod, oomer) {
+		return admission.Allowed("pod already has an allocator sidecar")
+	}
+
+	sidecar, err := allocator.Sidecar(oomer, allocator.Image(oomer, p.Config))
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	pod.Spec.Containers = append(pod.Spec.Containers, sidecar)
+
+	marshaledPod, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	log.Info("injecting allocator sidecar", "oomer", oomer.ObjectMeta.Name, "namespace", req.Namespace)
+
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaledPod)
+}
+
+// matchOomer returns the first active Oomer, in the Sidecar mode, whose target
+// selector matches the pod. Only Oomers which the controller has started injecting
+// are active, so paused, completed and aborted Oomers, and those waiting for their
+// scheduled start or the injection budget, are not. When no Oomer matches, nil is
+// returned.
+func (p *PodInjector) matchOomer(ctx context.Context, namespace string, pod *corev1.Pod) (*oomv1beta1.Oomer, error) {
+	var oomers oomv1beta1.OomerList
+	if err := p.Client.List(ctx, &oomers, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	for i := range oomers.Items {
+		o := &oomers.Items[i]
+
+		if o.Spec.Mode != oomv1beta1.SidecarMode || o.Spec.Paused || !o.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		if meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.CompletedCondition) ||
+			meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.AbortedCondition) ||
+			!meta.IsStatusConditionTrue(o.Status.Conditions, oomv1beta1.InjectingCondition) {
+			continue
+		}
+
+		// The condition may not yet reflect a start time which was moved later.
+		if s := o.Spec.Schedule; s != nil && s.StartAt != nil && time.Now().Before(s.StartAt.Time) {
+			continue
+		}
+
+		if o.Spec.Target == nil || o.Spec.Target.Selector == nil {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(o.Spec.Target.Selector)
+		if err != nil {
+			return nil, err
+		}
+
+		if selector.Matches(labels.Set(pod.ObjectMeta.Labels)) {
+			return o, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// InjectDecoder injects the decoder.
+func (p *PodInjector) InjectDecoder(d *admission.Decoder) error {
+	p.decoder = d
+	return nil
+}
@@ -0,0 +1,171 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	oomv1beta1 "github.com/jdockerty/oom-operator/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var _ = Describe("Pod injector", func() {
+	const namespace = "default"
+
+	ctx := context.Background()
+	limit := resource.MustParse("32Mi")
+
+	oomer := &oomv1beta1.Oomer{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sidecar",
+			Namespace: namespace,
+		},
+		Spec: oomv1beta1.OomerSpec{
+			Mode: oomv1beta1.SidecarMode,
+			Target: &oomv1beta1.TargetSpec{
+				Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "chaos"}},
+				MemoryLimit: &limit,
+			},
+		},
+		Status: oomv1beta1.OomerStatus{
+			Conditions: []metav1.Condition{{
+				Type:   oomv1beta1.InjectingCondition,
+				Status: metav1.ConditionTrue,
+				Reason: "Started",
+			}},
+		},
+	}
+
+	var injector *PodInjector
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(oomv1beta1.AddToScheme(scheme)).To(Succeed())
+
+		decoder, err := admission.NewDecoder(scheme)
+		Expect(err).NotTo(HaveOccurred())
+
+		injector = &PodInjector{
+			Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(oomer.DeepCopy()).Build(),
+		}
+		Expect(injector.InjectDecoder(decoder)).To(Succeed())
+	})
+
+	admit := func(pod *corev1.Pod) admission.Response {
+		raw, err := json.Marshal(pod)
+		Expect(err).NotTo(HaveOccurred())
+
+		return injector.Handle(ctx, admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{
+				Namespace: namespace,
+				Object:    runtime.RawExtension{Raw: raw},
+			},
+		})
+	}
+
+	pod := func(labels map[string]string, inject bool) *corev1.Pod {
+		if inject {
+			labels[oomv1beta1.InjectLabel] = "true"
+		}
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "app",
+				Namespace: namespace,
+				Labels:    labels,
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: "example.com/app:v1"}},
+			},
+		}
+	}
+
+	It("Should inject a sidecar into labelled pods matching an oomer", func() {
+		resp := admit(pod(map[string]string{"app": "chaos"}, true))
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(HaveLen(1))
+		Expect(resp.Patches[0].Path).To(Equal("/spec/containers/1"))
+
+		container, ok := resp.Patches[0].Value.(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(container["name"]).To(Equal("oomer-sidecar"))
+	})
+
+	It("Should not modify pods without the inject label", func() {
+		resp := admit(pod(map[string]string{"app": "chaos"}, false))
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+
+	It("Should not modify pods which do not match an oomer", func() {
+		resp := admit(pod(map[string]string{"app": "other"}, true))
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+
+	It("Should not modify pods while the oomer is paused", func() {
+		paused := &oomv1beta1.Oomer{}
+		Expect(injector.Client.Get(ctx, client.ObjectKeyFromObject(oomer), paused)).To(Succeed())
+		paused.Spec.Paused = true
+		Expect(injector.Client.Update(ctx, paused)).To(Succeed())
+
+		resp := admit(pod(map[string]string{"app": "chaos"}, true))
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+
+	It("Should not modify pods once the oomer is aborted", func() {
+		aborted := &oomv1beta1.Oomer{}
+		Expect(injector.Client.Get(ctx, client.ObjectKeyFromObject(oomer), aborted)).To(Succeed())
+		meta.SetStatusCondition(&aborted.Status.Conditions, metav1.Condition{
+			Type:   oomv1beta1.AbortedCondition,
+			Status: metav1.ConditionTrue,
+			Reason: "Aborted",
+		})
+		Expect(injector.Client.Status().Update(ctx, aborted)).To(Succeed())
+
+		resp := admit(pod(map[string]string{"app": "chaos"}, true))
+
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+
+	It("Should not modify pods before the oomer starts injecting", func() {
+		scheduled := &oomv1beta1.Oomer{}
+		Expect(injector.Client.Get(ctx, client.ObjectKeyFromObject(oomer), scheduled)).To(Succeed())
+		scheduled.Spec.Schedule = &oomv1beta1.ScheduleSpec{StartAt: &metav1.Time{Time: time.Now().Add(time.Hour)}}
+		Expect(injector.Client.Update(ctx, scheduled)).To(Succeed())
+
+		resp := admit(pod(map[string]string{"app": "chaos"}, true))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+
+		meta.SetStatusCondition(&scheduled.Status.Conditions, metav1.Condition{
+			Type:   oomv1beta1.InjectingCondition,
+			Status: metav1.ConditionFalse,
+			Reason: "BudgetExceeded",
+		})
+		scheduled.Spec.Schedule = nil
+		Expect(injector.Client.Update(ctx, scheduled)).To(Succeed())
+
+		resp = admit(pod(map[string]string{"app": "chaos"}, true))
+		Expect(resp.Allowed).To(BeTrue())
+		Expect(resp.Patches).To(BeEmpty())
+	})
+})